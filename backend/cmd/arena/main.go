@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+	"time"
+)
+
+// arena plays a match between two engine configurations and reports the
+// result, the Elo difference and, optionally, an SPRT verdict
+func main() {
+	engine1 := flag.String("engine1", "", "first engine: easy, medium, hard, level:N, rating:N, nn:FILE[@N] or ext:COMMAND")
+	engine2 := flag.String("engine2", "", "second engine, in the same format")
+	games := flag.Int("games", 100, "maximum number of games")
+	concurrency := flag.Int("concurrency", 1, "games played in parallel")
+	openingsFile := flag.String("openings", "", "file with one opening per line (default: built-in suite)")
+	recordsFile := flag.String("records", "", "file to append game records to")
+	moveTime := flag.Duration("movetime", time.Second, "time per move for external engines")
+	seed := flag.Int64("seed", 1, "random seed for engine noise")
+	sprt := flag.Bool("sprt", false, "stop early with a sequential probability ratio test")
+	elo0 := flag.Float64("elo0", 0, "SPRT null hypothesis Elo difference")
+	elo1 := flag.Float64("elo1", 10, "SPRT alternative hypothesis Elo difference")
+	alpha := flag.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := flag.Float64("beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+
+	spec1, err := arena.ParseEngineSpec(*engine1, *moveTime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	spec2, err := arena.ParseEngineSpec(*engine2, *moveTime)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config := arena.Config{
+		Engine1:     spec1,
+		Engine2:     spec2,
+		Games:       *games,
+		Concurrency: *concurrency,
+		Seed:        *seed,
+	}
+
+	if *openingsFile != "" {
+		file, err := os.Open(*openingsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Openings, err = arena.LoadOpenings(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *sprt {
+		config.SPRT = &arena.SPRT{Elo0: *elo0, Elo1: *elo1, Alpha: *alpha, Beta: *beta}
+	}
+
+	var records *os.File
+	if *recordsFile != "" {
+		records, err = os.OpenFile(*recordsFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer records.Close()
+	}
+
+	config.OnGame = func(record *game.Record, result arena.Result) {
+		if records != nil {
+			if err := game.WriteRecords(records, record); err != nil {
+				log.Printf("Failed to write game record: %v", err)
+			}
+		}
+		fmt.Printf("\r%s", summary(config, result))
+	}
+
+	// Ctrl-C stops the match and still prints the result so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("%s vs %s\n", spec1.Name, spec2.Name)
+	result, err := arena.Run(ctx, config)
+	fmt.Printf("\r%s\n", summary(config, result))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+
+	if config.SPRT != nil {
+		switch config.SPRT.Decide(result) {
+		case "H1":
+			fmt.Printf("SPRT: H1 accepted, %s is at least %.0f Elo stronger\n", spec1.Name, *elo1)
+		case "H0":
+			fmt.Printf("SPRT: H0 accepted, %s is not %.0f Elo stronger\n", spec1.Name, *elo1)
+		default:
+			fmt.Println("SPRT: inconclusive")
+		}
+	}
+}
+
+// summary formats the running result of a match
+func summary(config arena.Config, result arena.Result) string {
+	elo, margin := result.Elo()
+	line := fmt.Sprintf("Games %d  W/D/L %d/%d/%d  Score %.1f%%  Elo %+.1f ± %.1f",
+		result.Games(), result.Wins, result.Draws, result.Losses, result.Score()*100, elo, margin)
+
+	if config.SPRT != nil {
+		lower, upper := config.SPRT.Bounds()
+		line += fmt.Sprintf("  LLR %.2f [%.2f, %.2f]", result.LLR(config.SPRT.Elo0, config.SPRT.Elo1), lower, upper)
+	}
+	return line
+}
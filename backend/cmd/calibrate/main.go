@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"t-9/internal/ai"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+)
+
+// minGames is the fewest games played between two levels, so a lucky start
+// cannot end a pair
+const minGames = 100
+
+// calibrate measures the rating of every AI strength level by playing
+// matches between neighbouring levels. Each pair plays until the 95% margin
+// of its Elo difference is within -margin, or -games have been played, so
+// cheap low levels are measured closely and the slow top ones as well as time
+// allows. Levels measured out of order are pooled so the published ratings
+// never fall. They are pasted into the table in internal/ai/strength.go, and
+// the output of the run is kept in ratings.txt next to this file.
+func main() {
+	games := flag.Int("games", 2000, "most games per pair of neighbouring levels")
+	margin := flag.Float64("margin", 20, "95% margin in Elo at which a pair stops")
+	openingPlies := flag.Int("opening", 2, "random opening moves played before the engines take over")
+	anchor := flag.Int("anchor", 400, "rating assigned to level 1")
+	seed := flag.Int64("seed", 1, "random seed for openings and blunders")
+	flag.Parse()
+
+	rng := rand.New(rand.NewSource(*seed))
+	ratings := []float64{float64(*anchor)}
+
+	for level := ai.MinLevel; level < ai.MaxLevel; level++ {
+		weaker := ai.StrengthForLevel(level)
+		stronger := ai.StrengthForLevel(level + 1)
+
+		var result arena.Result
+		for i := 0; i < *games; i++ {
+			opening := ai.RandomOpening(rng, *openingPlies)
+
+			// Alternate colours so neither level always moves first
+			strongerPlays := game.X
+			if i%2 == 1 {
+				strongerPlays = game.O
+			}
+
+			x := ai.NewSeededLeveledAIPlayer(stronger, game.X, rng.Int63())
+			o := ai.NewSeededLeveledAIPlayer(weaker, game.O, rng.Int63())
+			if strongerPlays == game.O {
+				x = ai.NewSeededLeveledAIPlayer(weaker, game.X, rng.Int63())
+				o = ai.NewSeededLeveledAIPlayer(stronger, game.O, rng.Int63())
+			}
+
+			finished, err := ai.PlayGame(context.Background(), x, o, opening)
+			if err != nil {
+				log.Fatalf("level %d vs %d: %v", level+1, level, err)
+			}
+
+			switch finished.GameWon {
+			case strongerPlays:
+				result.Wins++
+			case game.Empty:
+				result.Draws++
+			default:
+				result.Losses++
+			}
+
+			// Stop after an even number of games, so both levels had each
+			// colour equally often
+			if _, spread := result.Elo(); i%2 == 1 && i+1 >= minGames && spread <= *margin {
+				break
+			}
+		}
+
+		elo, spread := result.Elo()
+		ratings = append(ratings, ratings[len(ratings)-1]+elo)
+		fmt.Printf("level %2d scored %5.1f/%-3d against level %d: %+4.0f ± %2.0f Elo\n",
+			level+1, result.Score()*float64(result.Games()), result.Games(), level, elo, spread)
+	}
+
+	published := poolAdjacentViolators(ratings)
+	for i := range ratings {
+		fmt.Printf("level %2d: measured %5.0f  published %5.0f\n", i+ai.MinLevel, ratings[i], published[i])
+	}
+}
+
+// poolAdjacentViolators returns the closest non-decreasing sequence to
+// values by averaging neighbouring values that are out of order
+func poolAdjacentViolators(values []float64) []float64 {
+	type block struct {
+		sum   float64
+		count int
+	}
+
+	var blocks []block
+	for _, value := range values {
+		blocks = append(blocks, block{sum: value, count: 1})
+		for len(blocks) > 1 {
+			last := blocks[len(blocks)-1]
+			previous := blocks[len(blocks)-2]
+			if previous.sum/float64(previous.count) <= last.sum/float64(last.count) {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{sum: previous.sum + last.sum, count: previous.count + last.count})
+		}
+	}
+
+	pooled := make([]float64, 0, len(values))
+	for _, b := range blocks {
+		for i := 0; i < b.count; i++ {
+			pooled = append(pooled, b.sum/float64(b.count))
+		}
+	}
+	return pooled
+}
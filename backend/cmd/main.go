@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"t-9/internal/ai"
 	"t-9/internal/api"
 	"t-9/internal/config"
 	"t-9/internal/logging"
+	"t-9/internal/service"
 	"t-9/internal/ws"
 )
 
@@ -22,12 +24,31 @@ func main() {
 		"env":     getEnv("ENVIRONMENT", "development"),
 	})
 
+	// Load tuned evaluation weights
+	if cfg.AI.WeightsFile != "" {
+		weights, err := ai.LoadWeightsFile(cfg.AI.WeightsFile)
+		if err != nil {
+			logger.Error("Failed to load evaluation weights", err, map[string]interface{}{
+				"file": cfg.AI.WeightsFile,
+			})
+			log.Fatal("Failed to load evaluation weights:", err)
+		}
+		ai.SetDefaultWeights(weights)
+		logger.Info("Loaded evaluation weights", map[string]interface{}{
+			"file": cfg.AI.WeightsFile,
+			"name": weights.Name,
+		})
+	}
+
+	// Create the game service shared by REST and WebSocket
+	games := service.New()
+
 	// Create WebSocket hub
-	hub := ws.NewHub()
+	hub := ws.NewHub(games)
 	go hub.Run()
 
 	// Setup routes with WebSocket support
-	r := api.SetupRoutes(hub)
+	r := api.SetupRoutes(hub, games)
 	
 	// Start server with configuration
 	serverAddr := fmt.Sprintf(":%s", cfg.Server.Port)
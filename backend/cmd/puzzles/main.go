@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+	"t-9/internal/puzzle"
+)
+
+// puzzles mines tactical puzzles from game records and self-play games and
+// adds them to the puzzle file the server serves (PUZZLE_FILE). Puzzles
+// already in the file keep their ratings.
+func main() {
+	recordFiles := flag.String("records", "", "comma-separated game record files to mine")
+	selfPlay := flag.Int("selfplay", 0, "self-play games to play and mine")
+	selfPlayEngine := flag.String("engine", "level:6", "engine used for self-play, in cmd/arena format")
+	concurrency := flag.Int("concurrency", 1, "self-play games played in parallel")
+	seed := flag.Int64("seed", 1, "random seed")
+	out := flag.String("out", "puzzles.json", "puzzle file to add to")
+	minMoves := flag.Int("min-moves", 2, "fewest solver moves in a puzzle")
+	maxMoves := flag.Int("max-moves", 3, "most solver moves in a puzzle")
+	skipPlies := flag.Int("skip", 10, "opening plies of each game to ignore")
+	flag.Parse()
+
+	if *minMoves < 1 || *maxMoves < *minMoves {
+		log.Fatal("need 1 <= -min-moves <= -max-moves")
+	}
+
+	// Ctrl-C stops mining and keeps the puzzles found so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	store, err := puzzle.LoadStore(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records, err := game.ReadRecordFiles(splitList(*recordFiles))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sources := make([]string, len(records))
+	for i := range records {
+		sources[i] = "records"
+	}
+
+	if *selfPlay > 0 {
+		engine, err := arena.ParseEngineSpec(*selfPlayEngine, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		games, err := arena.SelfPlay(ctx, engine, *selfPlay, *concurrency, *seed, func(record *game.Record, played int) {
+			fmt.Printf("\rSelf-play games %d/%d", played, *selfPlay)
+		})
+		fmt.Println()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatal(err)
+		}
+		for range games {
+			sources = append(sources, "self-play "+engine.Name)
+		}
+		records = append(records, games...)
+	}
+	if len(records) == 0 {
+		log.Fatal("no games to mine: use -records or -selfplay")
+	}
+
+	config := puzzle.MineConfig{MinMoves: *minMoves, MaxMoves: *maxMoves, SkipPlies: *skipPlies}
+	found, added := 0, 0
+	for i, record := range records {
+		puzzles, err := puzzle.MineRecord(ctx, record, sources[i], config)
+		if errors.Is(err, context.Canceled) {
+			fmt.Println()
+			break
+		}
+		if err != nil {
+			log.Printf("game %d: %v", i+1, err)
+		}
+		found += len(puzzles)
+		added += store.Add(puzzles...)
+		fmt.Printf("\rMined games %d/%d: %d puzzles", i+1, len(records), found)
+	}
+	fmt.Println()
+
+	if err := store.Save(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d new puzzles, %d in %s\n", added, store.Len(), *out)
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"t-9/internal/game"
+	"t-9/internal/render"
+)
+
+// replay draws games as animated GIFs, a frame per move, for sharing. Games
+// come from record files or a move list; with several games each is written
+// to its own file, numbered after -out.
+func main() {
+	recordFiles := flag.String("records", "", "comma-separated game record files")
+	moves := flag.String("moves", "", "moves of a single game, such as \"44 40 04\", instead of -records")
+	index := flag.Int("game", 0, "number of the game in the records to draw, from 1; all unless given")
+	out := flag.String("out", "replay.gif", "GIF file to write")
+	size := flag.Int("size", render.DefaultSize, "width and height in pixels")
+	theme := flag.String("theme", "light", "theme: "+strings.Join(render.ThemeNames(), ", "))
+	delay := flag.Duration("delay", render.DefaultDelay, "time each move is shown")
+	finalDelay := flag.Duration("final-delay", render.DefaultFinalDelay, "time the final position is shown before looping")
+	banner := flag.Bool("banner", true, "show a banner over the final position")
+	bannerText := flag.String("banner-text", "", "text of the banner, the result unless given")
+	flag.Parse()
+
+	options := render.ReplayOptions{
+		Options:    render.Options{Size: *size, Theme: *theme},
+		Delay:      *delay,
+		FinalDelay: *finalDelay,
+		NoBanner:   !*banner,
+		BannerText: *bannerText,
+	}
+	if err := options.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	records, err := readGames(*recordFiles, *moves)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *index != 0 {
+		if *index < 1 || *index > len(records) {
+			log.Fatalf("-game must be between 1 and %d", len(records))
+		}
+		records = records[*index-1 : *index]
+	}
+
+	for i, record := range records {
+		state, err := finalState(record)
+		if err != nil {
+			log.Fatalf("game %d: %v", i+1, err)
+		}
+		path := *out
+		if len(records) > 1 {
+			path = numbered(*out, i+1)
+		}
+		if err := writeGIF(path, state, options); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: %d moves, %s\n", path, len(state.MoveHistory), game.ResultString(state))
+	}
+}
+
+// readGames reads the games to draw from record files or a move list
+func readGames(recordFiles, moves string) ([]*game.Record, error) {
+	if moves != "" {
+		parsed, err := game.ParseMoves(moves)
+		if err != nil {
+			return nil, err
+		}
+		return []*game.Record{{Tags: map[string]string{}, Moves: parsed}}, nil
+	}
+	records, err := game.ReadRecordFiles(splitList(recordFiles))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no games to draw: use -records or -moves")
+	}
+	return records, nil
+}
+
+// finalState replays a record. A game whose moves stop before its recorded
+// result ended by resignation.
+func finalState(record *game.Record) (*game.GameState, error) {
+	state, err := record.Replay()
+	if err != nil {
+		return nil, err
+	}
+	if !state.GameOver {
+		switch record.Tags["Result"] {
+		case game.ResultXWins:
+			err = state.Resign(game.O)
+		case game.ResultOWins:
+			err = state.Resign(game.X)
+		}
+	}
+	return state, err
+}
+
+func writeGIF(path string, state *game.GameState, options render.ReplayOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := render.GIF(file, state, options); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// numbered inserts a game number before a path's extension
+func numbered(path string, n int) string {
+	if dot := strings.LastIndex(path, "."); dot > strings.LastIndex(path, "/") {
+		return fmt.Sprintf("%s-%d%s", path[:dot], n, path[dot:])
+	}
+	return fmt.Sprintf("%s-%d", path, n)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
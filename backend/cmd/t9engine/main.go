@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"t-9/internal/nn"
+	"t-9/internal/protocol"
+)
+
+// t9engine runs the built-in AI as a protocol engine on stdin and stdout, so
+// it can be used by any program that drives engines over the protocol.
+// "t9engine bench [depth]" searches a fixed position suite and prints the
+// total node count, which changes only when the search or evaluation does.
+func main() {
+	options := protocol.DefaultEngineOptions()
+	depth := flag.Int("depth", options.Depth, "search depth when go has no depth")
+	moveTime := flag.Duration("movetime", options.MoveTime, "search time when go has no limits")
+	weights := flag.String("weights", "", "neural network weights file to evaluate with")
+	evalWeights := flag.String("eval-weights", "", "handwritten evaluation weights file, see cmd/tune")
+	flag.Parse()
+
+	if *evalWeights != "" {
+		tuned, err := ai.LoadWeightsFile(*evalWeights)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ai.SetDefaultWeights(tuned)
+	}
+
+	if flag.Arg(0) == "bench" {
+		runBench(flag.Arg(1))
+		return
+	}
+
+	if *weights != "" {
+		network, err := nn.LoadFile(*weights)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Evaluator = network
+		options.Name += " NN"
+	}
+
+	options.Depth = *depth
+	options.MoveTime = *moveTime
+
+	if err := protocol.RunEngine(os.Stdin, os.Stdout, options); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runBench runs the bench suite and prints its statistics
+func runBench(depthArg string) {
+	depth := ai.DefaultBenchDepth
+	if depthArg != "" {
+		var err error
+		if depth, err = strconv.Atoi(depthArg); err != nil || depth < 1 {
+			log.Fatalf("invalid bench depth %q", depthArg)
+		}
+	}
+
+	position := 0
+	total := ai.Bench(depth, func(result ai.BenchResult) {
+		position++
+		fmt.Printf("Position %2d: nodes %9d  nps %8d  seldepth %2d  ttHitRate %.3f  bestmove %s\n",
+			position, result.Stats.Nodes, result.Stats.NodesPerSecond, result.Stats.SelDepth,
+			result.Stats.TTHitRate, game.FormatMove(result.Move))
+	})
+
+	fmt.Printf("Depth %d  time %d ms  nps %d  branching %.2f  cutoffs %d (%d on first move)  ttHitRate %.3f\n",
+		total.Depth, total.TimeMs, total.NodesPerSecond, total.BranchingFactor,
+		total.Cutoffs, total.FirstMoveCutoffs, total.TTHitRate)
+	fmt.Printf("Bench: %d nodes\n", total.Nodes)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+	"t-9/internal/nn"
+)
+
+// train fits a neural network evaluator to game records on the CPU. Records
+// come from files written by cmd/arena, from self-play games played here, or
+// both. A tenth of the games is held out to report validation loss.
+func main() {
+	recordFiles := flag.String("records", "", "comma-separated game record files to learn from")
+	selfPlay := flag.Int("selfplay", 0, "self-play games to generate before training")
+	selfPlayEngine := flag.String("engine", "level:8", "engine used for self-play, in cmd/arena format")
+	concurrency := flag.Int("concurrency", 1, "self-play games played in parallel")
+	saveRecords := flag.String("save-records", "", "file to append self-play records to")
+	weights := flag.String("weights", "", "file to write the trained weights to")
+	initial := flag.String("init", "", "weights file to continue training from")
+	hidden := flag.Int("hidden", 64, "hidden layer size of a new network")
+	epochs := flag.Int("epochs", 10, "passes over the training data")
+	learningRate := flag.Float64("lr", 0.005, "learning rate")
+	policyWeight := flag.Float64("policy", 0.5, "weight of the policy loss")
+	augment := flag.Bool("augment", true, "add every position under all eight board symmetries")
+	seed := flag.Int64("seed", 1, "random seed")
+	flag.Parse()
+
+	if *weights == "" {
+		log.Fatal("-weights is required")
+	}
+	rng := rand.New(rand.NewSource(*seed))
+
+	records, err := game.ReadRecordFiles(splitList(*recordFiles))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *selfPlay > 0 {
+		games, err := playSelfPlay(*selfPlayEngine, *selfPlay, *concurrency, *seed, *saveRecords)
+		if err != nil {
+			log.Fatal(err)
+		}
+		records = append(records, games...)
+	}
+	if len(records) == 0 {
+		log.Fatal("no games to learn from: use -records or -selfplay")
+	}
+
+	// Hold out whole games so positions from one game are not on both sides
+	rng.Shuffle(len(records), func(i, j int) {
+		records[i], records[j] = records[j], records[i]
+	})
+	holdout := len(records) / 10
+	validation, err := samples(records[:holdout], false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	training, err := samples(records[holdout:], *augment)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d games, %d training and %d validation positions\n", len(records), len(training), len(validation))
+
+	network := nn.NewNetwork(*hidden, rng)
+	if *initial != "" {
+		network, err = nn.LoadFile(*initial)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	network.Train(training, nn.TrainConfig{
+		Epochs:       *epochs,
+		LearningRate: *learningRate,
+		PolicyWeight: *policyWeight,
+		Rng:          rng,
+		OnEpoch: func(epoch int, loss float64) {
+			fmt.Printf("epoch %2d: training loss %.4f  validation loss %.4f\n",
+				epoch, loss, network.Loss(validation, *policyWeight))
+		},
+	})
+
+	if err := network.SaveFile(*weights); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Weights written to %s\n", *weights)
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// playSelfPlay plays an engine against itself, optionally saving the records
+func playSelfPlay(spec string, games, concurrency int, seed int64, saveTo string) ([]*game.Record, error) {
+	engine, err := arena.ParseEngineSpec(spec, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var out *os.File
+	if saveTo != "" {
+		out, err = os.OpenFile(saveTo, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer out.Close()
+	}
+
+	records, err := arena.SelfPlay(context.Background(), engine, games, concurrency, seed, func(record *game.Record, played int) {
+		if out != nil {
+			if err := game.WriteRecords(out, record); err != nil {
+				log.Printf("Failed to write game record: %v", err)
+			}
+		}
+		fmt.Printf("\rSelf-play games %d/%d", played, games)
+	})
+	fmt.Println()
+	return records, err
+}
+
+// samples turns game records into training samples
+func samples(records []*game.Record, augment bool) ([]nn.Sample, error) {
+	var all []nn.Sample
+	for _, record := range records {
+		recordSamples, err := nn.SamplesFromRecord(record, augment)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, recordSamples...)
+	}
+	return all, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"t-9/internal/ai"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+	"t-9/internal/tuning"
+)
+
+// tune fits the handwritten evaluation weights and writes them to a file
+// that the server (AI_WEIGHTS_FILE), cmd/arena (weights:FILE) and
+// cmd/t9engine (-eval-weights) can load. Texel tuning fits recorded game
+// results; SPSA plays perturbed weight sets against each other.
+func main() {
+	method := flag.String("method", "texel", "tuning method: texel or spsa")
+	initial := flag.String("init", "", "weights file to start from (default: built-in weights)")
+	out := flag.String("out", "", "file to write the tuned weights to")
+	name := flag.String("name", "tuned", "name stored in the weights file")
+	iterations := flag.Int("iterations", 20, "tuning iterations")
+	seed := flag.Int64("seed", 1, "random seed")
+	concurrency := flag.Int("concurrency", 1, "games played in parallel")
+
+	// Texel
+	recordFiles := flag.String("records", "", "texel: comma-separated game record files")
+	selfPlay := flag.Int("selfplay", 0, "texel: self-play games to add to the records")
+	selfPlayEngine := flag.String("engine", "level:8", "texel: engine used for self-play, in cmd/arena format")
+	skipPlies := flag.Int("skip", 4, "texel: opening plies of each game to ignore")
+	step := flag.Float64("step", 0.1, "texel: relative change tried for each weight")
+
+	// SPSA
+	games := flag.Int("games", 32, "spsa: games per iteration")
+	level := flag.Int("level", 6, "spsa: strength level the weights play at")
+	a := flag.Float64("a", 0.02, "spsa: step size")
+	c := flag.Float64("c", 0.1, "spsa: relative perturbation size")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("-out is required")
+	}
+
+	weights := ai.DefaultWeights()
+	if *initial != "" {
+		var err error
+		if weights, err = ai.LoadWeightsFile(*initial); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var err error
+	switch *method {
+	case "texel":
+		weights, err = runTexel(weights, *recordFiles, *selfPlay, *selfPlayEngine, *concurrency, *seed, *skipPlies, *iterations, *step)
+	case "spsa":
+		weights, err = tuning.SPSA(context.Background(), weights, tuning.SPSAConfig{
+			Iterations:  *iterations,
+			Games:       *games,
+			A:           *a,
+			C:           *c,
+			Strength:    ai.StrengthForLevel(*level),
+			Concurrency: *concurrency,
+			Seed:        *seed,
+			OnIteration: func(iteration int, score float64, weights ai.Weights) {
+				fmt.Printf("iteration %3d: plus scored %+.2f against minus\n", iteration, score)
+			},
+		})
+	default:
+		log.Fatalf("unknown method %q", *method)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	weights.Name = *name
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := weights.Save(file); err != nil {
+		log.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Weights written to %s\n", *out)
+}
+
+// runTexel loads or plays games and tunes the weights to their results
+func runTexel(weights ai.Weights, recordFiles string, selfPlay int, spec string, concurrency int, seed int64, skipPlies, iterations int, step float64) (ai.Weights, error) {
+	var paths []string
+	for _, path := range strings.Split(recordFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	records, err := game.ReadRecordFiles(paths)
+	if err != nil {
+		return weights, err
+	}
+
+	if selfPlay > 0 {
+		engine, err := arena.ParseEngineSpec(spec, 0)
+		if err != nil {
+			return weights, err
+		}
+		games, err := arena.SelfPlay(context.Background(), engine, selfPlay, concurrency, seed, func(record *game.Record, played int) {
+			fmt.Printf("\rSelf-play games %d/%d", played, selfPlay)
+		})
+		fmt.Println()
+		if err != nil {
+			return weights, err
+		}
+		records = append(records, games...)
+	}
+
+	positions, err := tuning.PositionsFromRecords(records, skipPlies)
+	if err != nil {
+		return weights, err
+	}
+	if len(positions) == 0 {
+		return weights, fmt.Errorf("no positions to tune on: use -records or -selfplay")
+	}
+
+	scale := tuning.FitScale(positions, weights)
+	fmt.Printf("%d positions from %d games, scale %.0f, starting error %.5f\n",
+		len(positions), len(records), scale, tuning.MeanSquaredError(positions, weights, scale))
+
+	weights, _ = tuning.Texel(positions, weights, scale, tuning.TexelConfig{
+		Iterations: iterations,
+		Step:       step,
+		MinStep:    step / 64,
+		OnIteration: func(iteration int, err float64, weights ai.Weights) {
+			fmt.Printf("iteration %3d: error %.5f\n", iteration, err)
+		},
+	})
+	return weights, nil
+}
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"t-9/internal/game"
@@ -18,9 +19,34 @@ const (
 
 // AIPlayer represents an AI opponent
 type AIPlayer struct {
-	difficulty Difficulty
-	player     game.Player
-	rng        *rand.Rand
+	difficulty  Difficulty
+	strength    *Strength
+	evaluator   Evaluator
+	weights     Weights
+	personality Personality
+	player      game.Player
+	rng         *rand.Rand
+
+	// seeded players replace time limits with node limits, so the same seed
+	// always gives the same moves
+	seeded bool
+
+	// Search limits for the current move
+	ctx       context.Context
+	deadline  time.Time
+	nodeLimit int
+	nodes     int
+	aborted   bool
+
+	// Transposition table, made on the first store. Only entries of the
+	// current search generation are used.
+	tt           []ttEntry
+	ttGeneration uint32
+
+	// Statistics of the last search
+	stats     SearchStats
+	statsEnd  time.Time
+	rootDepth int
 }
 
 // NewAIPlayer creates a new AI player
@@ -28,29 +54,66 @@ func NewAIPlayer(difficulty Difficulty, player game.Player) *AIPlayer {
 	return &AIPlayer{
 		difficulty: difficulty,
 		player:     player,
+		weights:    defaultWeights,
 		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
+// NewSeededAIPlayer creates an AI player with a fixed random seed, so its
+// games can be replayed
+func NewSeededAIPlayer(difficulty Difficulty, player game.Player, seed int64) *AIPlayer {
+	aiPlayer := NewAIPlayer(difficulty, player)
+	aiPlayer.rng = rand.New(rand.NewSource(seed))
+	aiPlayer.seeded = true
+	return aiPlayer
+}
+
 // GetBestMove returns the best move for the current game state
 func (ai *AIPlayer) GetBestMove(gameState *game.GameState) game.Move {
+	move, _ := ai.GetBestMoveContext(context.Background(), gameState)
+	return move
+}
+
+// GetBestMoveContext returns the best move for the current game state. The
+// search stops soon after ctx is cancelled and the context's error is
+// returned. An empty move without an error means there are no valid moves.
+func (ai *AIPlayer) GetBestMoveContext(ctx context.Context, gameState *game.GameState) (game.Move, error) {
+	if err := ctx.Err(); err != nil {
+		return game.Move{}, err
+	}
+
 	// First check if there are any valid moves
 	moves := ai.getValidMoves(gameState)
 	if len(moves) == 0 {
 		// Return empty move if no valid moves
-		return game.Move{}
+		return game.Move{}, nil
 	}
 
-	switch ai.difficulty {
-	case Easy:
-		return ai.getRandomMove(gameState)
-	case Medium:
-		return ai.getMediumMove(gameState)
-	case Hard:
-		return ai.getHardMove(gameState)
-	default:
-		return ai.getRandomMove(gameState)
+	ai.ctx = ctx
+	defer func() { ai.ctx = nil }()
+	ai.startStats()
+	defer ai.finishStats()
+
+	var move game.Move
+	if ai.strength != nil {
+		move = ai.getLeveledMove(gameState, moves)
+	} else {
+		switch ai.difficulty {
+		case Easy:
+			move = ai.getRandomMove(gameState)
+		case Medium:
+			move = ai.getMediumMove(gameState)
+		case Hard:
+			move = ai.getHardMove(gameState)
+		default:
+			move = ai.getRandomMove(gameState)
+		}
 	}
+
+	if err := ctx.Err(); err != nil {
+		return game.Move{}, err
+	}
+	return move, nil
 }
 
 // getRandomMove returns a random valid move
@@ -72,6 +135,7 @@ func (ai *AIPlayer) getMediumMove(gameState *game.GameState) game.Move {
 	// Use minimax but with limited depth for medium difficulty
 	// 80% chance of strategic play, 20% chance of good-but-not-perfect move
 	if ai.rng.Float64() < 0.8 {
+		ai.rootDepth, ai.stats.Depth = 4, 4
 		return ai.minimax(gameState, 4, math.Inf(-1), math.Inf(1), true).move
 	} else {
 		return ai.getStrategicMove(gameState, moves)
@@ -90,6 +154,7 @@ func (ai *AIPlayer) getHardMove(gameState *game.GameState) game.Move {
 	}
 	
 	// Use deeper search for hard difficulty
+	ai.rootDepth, ai.stats.Depth = 10, 10
 	return ai.minimax(gameState, 10, math.Inf(-1), math.Inf(1), true).move
 }
 
@@ -101,6 +166,12 @@ type MinimaxResult struct {
 
 // minimax implements the minimax algorithm with alpha-beta pruning
 func (ai *AIPlayer) minimax(gameState *game.GameState, depth int, alpha, beta float64, maximizing bool) MinimaxResult {
+	ai.nodes++
+	ai.countNode(ai.rootDepth - depth)
+	if ai.searchExpired() {
+		return MinimaxResult{}
+	}
+
 	if depth == 0 || gameState.GameOver {
 		return MinimaxResult{
 			score: ai.evaluatePosition(gameState),
@@ -116,14 +187,25 @@ func (ai *AIPlayer) minimax(gameState *game.GameState, depth int, alpha, beta fl
 		}
 	}
 
+	key := positionKey(gameState)
+	entry, settled := ai.probeTT(key, depth, alpha, beta)
+	if settled {
+		return MinimaxResult{score: entry.score, move: moveFromCell(entry.move, gameState.CurrentPlayer)}
+	}
+	hashMoveFirst(moves, entry)
+	windowAlpha, windowBeta := alpha, beta
+	cutoff := false
+
 	bestMove := moves[0]
+	ai.countInterior()
 
 	if maximizing {
 		maxScore := math.Inf(-1)
-		for _, move := range moves {
+		for i, move := range moves {
+			ai.stats.children++
 			// Create copy of game state
 			newState := ai.copyGameState(gameState)
-			newState.MakeMove(move)
+			newState.PlayMove(move)
 			
 			result := ai.minimax(newState, depth-1, alpha, beta, false)
 			
@@ -134,16 +216,20 @@ func (ai *AIPlayer) minimax(gameState *game.GameState, depth int, alpha, beta fl
 			
 			alpha = math.Max(alpha, result.score)
 			if beta <= alpha {
+				ai.countCutoff(i + 1)
+				cutoff = true
 				break // Alpha-beta pruning
 			}
 		}
+		ai.storeTT(key, depth, maxScore, searchBound(true, cutoff, maxScore, windowAlpha, windowBeta), bestMove, nil)
 		return MinimaxResult{score: maxScore, move: bestMove}
 	} else {
 		minScore := math.Inf(1)
-		for _, move := range moves {
+		for i, move := range moves {
+			ai.stats.children++
 			// Create copy of game state
 			newState := ai.copyGameState(gameState)
-			newState.MakeMove(move)
+			newState.PlayMove(move)
 			
 			result := ai.minimax(newState, depth-1, alpha, beta, true)
 			
@@ -154,9 +240,12 @@ func (ai *AIPlayer) minimax(gameState *game.GameState, depth int, alpha, beta fl
 			
 			beta = math.Min(beta, result.score)
 			if beta <= alpha {
+				ai.countCutoff(i + 1)
+				cutoff = true
 				break // Alpha-beta pruning
 			}
 		}
+		ai.storeTT(key, depth, minScore, searchBound(false, cutoff, minScore, windowAlpha, windowBeta), bestMove, nil)
 		return MinimaxResult{score: minScore, move: bestMove}
 	}
 }
@@ -187,7 +276,7 @@ func (ai *AIPlayer) evaluateMove(gameState *game.GameState, move game.Move) floa
 
 	// Create a copy and make the move
 	newState := ai.copyGameState(gameState)
-	newState.MakeMove(move)
+	newState.PlayMove(move)
 
 	// ULTIMATE WIN - highest priority
 	if newState.GameWon == ai.player {
@@ -202,24 +291,24 @@ func (ai *AIPlayer) evaluateMove(gameState *game.GameState, move game.Move) floa
 		Player:          opponent,
 	}
 	if testState.IsValidMove(opponentMove) == nil {
-		testState.MakeMove(opponentMove)
+		testState.PlayMove(opponentMove)
 		if testState.GameWon == opponent {
-			score += 5000 // Block opponent ultimate win
+			score += ai.weights.MoveBlockWin // Block opponent ultimate win
 		}
 	}
 
 	// WIN SMALL BOARD - very important
 	if newState.BigBoardWins[move.BigBoardIndex] == ai.player && 
 	   gameState.BigBoardWins[move.BigBoardIndex] == game.Empty {
-		score += 1000
+		score += ai.weights.MoveWinBoard
 	}
 
 	// BLOCK OPPONENT SMALL BOARD WIN - very important
 	if testState.IsValidMove(opponentMove) == nil {
-		testState.MakeMove(opponentMove)
+		testState.PlayMove(opponentMove)
 		if testState.BigBoardWins[move.BigBoardIndex] == opponent &&
 		   gameState.BigBoardWins[move.BigBoardIndex] == game.Empty {
-			score += 800
+			score += ai.weights.MoveBlockBoard
 		}
 	}
 
@@ -229,11 +318,11 @@ func (ai *AIPlayer) evaluateMove(gameState *game.GameState, move game.Move) floa
 		// Evaluate where we're sending the opponent
 		if newState.BigBoardWins[nextBoard] != game.Empty || ai.isSmallBoardFull(newState, nextBoard) {
 			// Sending opponent to completed board = anarchy mode = VERY good for us!
-			score += 800
+			score += ai.weights.MoveSendToWonBoard
 		} else {
 			// CRITICAL: Analyze the board we're sending opponent to
 			sendingScore := ai.evaluateSendingTarget(gameState, newState, nextBoard, opponent)
-			score += sendingScore
+			score += sendingScore * ai.weights.MoveSendingTarget
 		}
 	}
 
@@ -251,13 +340,18 @@ func (ai *AIPlayer) evaluateMove(gameState *game.GameState, move game.Move) floa
 	if move.BigBoardIndex == 4 { // Center big board
 		// Only bonus if we're not helping opponent
 		if !ai.isMoveDangerous(gameState, move, opponent) {
-			score += 80
+			score += ai.weights.MoveCenterBoard
 		}
 	}
 
+	// CAUTION - only cautious styles avoid every risky send
+	if ai.weights.MoveDangerous != 0 && ai.isMoveDangerous(gameState, move, opponent) {
+		score -= ai.weights.MoveDangerous
+	}
+
 	// MULTIPLE THREAT CREATION
 	threatsCreated := ai.countThreatsCreated(newState, move.BigBoardIndex)
-	score += float64(threatsCreated) * 30
+	score += float64(threatsCreated) * ai.weights.MoveThreat
 
 	// FORK OPPORTUNITIES (multiple ways to win)
 	forkValue := ai.evaluateForkOpportunities(newState, move.BigBoardIndex)
@@ -265,15 +359,15 @@ func (ai *AIPlayer) evaluateMove(gameState *game.GameState, move game.Move) floa
 
 	// ADVANCED CHAIN THINKING - plan 2-3 moves ahead
 	chainValue := ai.evaluateChainStrategy(gameState, newState, move, opponent)
-	score += chainValue
+	score += chainValue * ai.weights.MoveChain
 
 	// BOARD SACRIFICE STRATEGY - sometimes losing a board is good
 	sacrificeValue := ai.evaluateSacrificeStrategy(gameState, newState, move, opponent)
-	score += sacrificeValue
+	score += sacrificeValue * ai.weights.MoveSacrifice
 
 	// TEMPO CONTROL - prefer moves that give us options
 	if newState.ActiveBoard == -1 { // Created anarchy mode
-		score += 150
+		score += ai.weights.MoveAnarchy
 	}
 
 	// ENDGAME STRATEGY - different logic when few moves left
@@ -304,36 +398,10 @@ func (ai *AIPlayer) evaluateSmallBoardAdvantage(state *game.GameState, boardInde
 	return float64(opponentCount - aiCount)
 }
 
-// countThreatsCreated counts how many new winning threats this move creates
+// countThreatsCreated counts the lines of a small board where the AI has two
+// in a row and the third cell empty
 func (ai *AIPlayer) countThreatsCreated(state *game.GameState, boardIndex int) int {
-	board := &state.BigBoard[boardIndex]
-	threats := 0
-	
-	lines := [][]int{
-		{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, // rows
-		{0, 3, 6}, {1, 4, 7}, {2, 5, 8}, // columns
-		{0, 4, 8}, {2, 4, 6}, // diagonals
-	}
-	
-	for _, line := range lines {
-		aiCount := 0
-		emptyCount := 0
-		
-		for _, pos := range line {
-			if board[pos] == ai.player {
-				aiCount++
-			} else if board[pos] == game.Empty {
-				emptyCount++
-			}
-		}
-		
-		// Two in a row with one empty = threat
-		if aiCount == 2 && emptyCount == 1 {
-			threats++
-		}
-	}
-	
-	return threats
+	return int(smallBoard(&state.BigBoard[boardIndex]).threatLines[ai.player])
 }
 
 // evaluateForkOpportunities looks for fork setups (multiple winning threats)
@@ -342,7 +410,7 @@ func (ai *AIPlayer) evaluateForkOpportunities(state *game.GameState, boardIndex
 	// In a full implementation, you'd check for positions that create multiple threats
 	threats := ai.countThreatsCreated(state, boardIndex)
 	if threats >= 2 {
-		return 100 // Fork opportunity!
+		return ai.weights.MoveFork // Fork opportunity!
 	}
 	return 0
 }
@@ -414,30 +482,30 @@ func (ai *AIPlayer) evaluateSendingTarget(originalState, newState *game.GameStat
 	// SCORING:
 	// BAD: Sending opponent where they have more pieces or threats
 	if opponentPieces > aiPieces {
-		score -= 300 // They dominate this board
+		score -= ai.weights.SendOpponentAhead // They dominate this board
 	}
 	
 	if opponentThreats > 0 {
-		score -= 400 * float64(opponentThreats) // They can threaten to win it
+		score -= ai.weights.SendOpponentThreat * float64(opponentThreats) // They can threaten to win it
 	}
 	
 	// GOOD: Sending them where we dominate
 	if aiPieces > opponentPieces && aiThreats > 0 {
-		score += 200 // We control this board
+		score += ai.weights.SendOwnBoard // We control this board
 	}
 	
 	// STRATEGIC: Board position value
 	if targetBoard == 4 { // Center board
 		if opponentPieces == 0 && aiPieces > 0 {
-			score += 150 // Good, we control center
+			score += ai.weights.SendCenterOwned // Good, we control center
 		} else if opponentPieces > aiPieces {
-			score -= 200 // Bad, giving them center advantage
+			score -= ai.weights.SendCenterLost // Bad, giving them center advantage
 		}
 	}
 	
 	// TACTICAL: Check if they can immediately win the small board
 	if ai.canOpponentWinBoard(originalState, targetBoard, opponent) {
-		score -= 600 // NEVER send them where they can win immediately!
+		score -= ai.weights.SendOpponentCanWin // NEVER send them where they can win immediately!
 	}
 	
 	return score
@@ -445,33 +513,7 @@ func (ai *AIPlayer) evaluateSendingTarget(originalState, newState *game.GameStat
 
 // canOpponentWinBoard checks if opponent can win a small board on their next move
 func (ai *AIPlayer) canOpponentWinBoard(state *game.GameState, boardIndex int, opponent game.Player) bool {
-	board := &state.BigBoard[boardIndex]
-	
-	lines := [][]int{
-		{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, // rows
-		{0, 3, 6}, {1, 4, 7}, {2, 5, 8}, // columns
-		{0, 4, 8}, {2, 4, 6}, // diagonals
-	}
-	
-	for _, line := range lines {
-		oppCount := 0
-		emptyCount := 0
-		
-		for _, pos := range line {
-			if board[pos] == opponent {
-				oppCount++
-			} else if board[pos] == game.Empty {
-				emptyCount++
-			}
-		}
-		
-		// Two in a row with one empty = can win
-		if oppCount == 2 && emptyCount == 1 {
-			return true
-		}
-	}
-	
-	return false
+	return smallBoard(&state.BigBoard[boardIndex]).threats[opponent] != 0
 }
 
 // evaluateCenterMove - is center actually good here?
@@ -513,18 +555,18 @@ func (ai *AIPlayer) evaluateCenterMove(state *game.GameState, move game.Move, op
 	}
 	
 	if threats >= 2 {
-		score += 100 // Fork opportunity
+		score += ai.weights.CellCenterFork // Fork opportunity
 	} else if threats == 1 {
-		score += 40 // Single threat
+		score += ai.weights.CellCenterThreat // Single threat
 	} else {
-		score += 10 // Just positional
+		score += ai.weights.CellCenter // Just positional
 	}
 	
 	// Penalty if we're sending opponent to a good board for them
 	nextBoard := move.SmallBoardIndex
 	if nextBoard == 4 { // Sending them to center board
 		if ai.evaluateSmallBoardAdvantage(state, 4, opponent) > 0 {
-			score -= 60 // They have advantage in center board
+			score -= ai.weights.CellCenterToCenter // They have advantage in center board
 		}
 	}
 	
@@ -541,9 +583,9 @@ func (ai *AIPlayer) evaluateNonCenterMove(state *game.GameState, move game.Move,
 		if move.SmallBoardIndex == corner {
 			// Corner is good if it blocks opponent or creates threats
 			if ai.doesMoveBlockOpponent(state, move, opponent) {
-				score += 40
+				score += ai.weights.CellCornerBlock
 			} else {
-				score += 15 // Basic corner value
+				score += ai.weights.CellCorner // Basic corner value
 			}
 		}
 	}
@@ -553,9 +595,9 @@ func (ai *AIPlayer) evaluateNonCenterMove(state *game.GameState, move game.Move,
 	for _, edge := range edges {
 		if move.SmallBoardIndex == edge {
 			if ai.doesMoveBlockOpponent(state, move, opponent) {
-				score += 35
+				score += ai.weights.CellEdgeBlock
 			} else {
-				score += 8 // Basic edge value
+				score += ai.weights.CellEdge // Basic edge value
 			}
 		}
 	}
@@ -772,32 +814,32 @@ func (ai *AIPlayer) evaluateChainStrategy(originalState, newState *game.GameStat
 	
 	// If opponent goes to completed board, they get anarchy
 	if newState.BigBoardWins[nextBoard] != game.Empty || ai.isSmallBoardFull(newState, nextBoard) {
-		return -50 // Giving them too much freedom is bad
+		return -ai.weights.ChainFreeMove // Giving them too much freedom is bad
 	}
 	
 	// Simulate opponent's likely responses
 	opponentMoves := ai.getValidMovesForPlayer(newState, nextBoard, opponent)
 	if len(opponentMoves) == 0 {
-		return 100 // Trapped them!
+		return ai.weights.ChainTrapped // Trapped them!
 	}
 	
-	worstCase := 1000.0
+	worstCase := math.Inf(1)
 	for _, oppMove := range opponentMoves {
 		// Where would we be sent after their move?
 		ourNextBoard := oppMove.SmallBoardIndex
 		
 		// Evaluate how good that board is for us
 		chainState := ai.copyGameState(newState)
-		chainState.MakeMove(oppMove)
+		chainState.PlayMove(oppMove)
 		
 		var chainScore float64
 		if chainState.BigBoardWins[ourNextBoard] != game.Empty || ai.isSmallBoardFull(chainState, ourNextBoard) {
 			// We'd get anarchy - good!
-			chainScore = 80.0
+			chainScore = ai.weights.ChainFreeReply
 		} else {
 			// How good is that board for us?
 			ourAdvantage := -ai.evaluateSmallBoardAdvantage(chainState, ourNextBoard, opponent)
-			chainScore = ourAdvantage * 20
+			chainScore = ourAdvantage * ai.weights.ChainAdvantage
 		}
 		
 		if chainScore < worstCase {
@@ -805,7 +847,7 @@ func (ai *AIPlayer) evaluateChainStrategy(originalState, newState *game.GameStat
 		}
 	}
 	
-	score += worstCase * 0.3 // Weight the chain thinking
+	score += worstCase
 	
 	return score
 }
@@ -819,9 +861,9 @@ func (ai *AIPlayer) evaluateSacrificeStrategy(originalState, newState *game.Game
 		// This looks bad, but might be strategic...
 		
 		// Is it a corner/edge board? (Less valuable)
-		boardValue := 100.0
+		gain := ai.weights.SacrificeGain
 		if move.BigBoardIndex == 4 {
-			boardValue = 200.0 // Center is more valuable
+			gain = ai.weights.SacrificeCenterGain // Center is more valuable
 		}
 		
 		// What do we gain by sacrificing?
@@ -830,14 +872,14 @@ func (ai *AIPlayer) evaluateSacrificeStrategy(originalState, newState *game.Game
 		if nextBoard >= 0 && nextBoard < 9 {
 			// If we send them somewhere bad for them, sacrifice might be worth it
 			sendingAdvantage := ai.evaluateSendingTarget(originalState, newState, nextBoard, opponent)
-			if sendingAdvantage > boardValue/2 {
-				score += 50 // Strategic sacrifice
+			if sendingAdvantage > gain {
+				score += ai.weights.Sacrifice // Strategic sacrifice
 			}
 		}
 		
 		// 2. Do we create threats elsewhere?
 		if ai.countThreatsCreated(newState, move.BigBoardIndex) > 0 {
-			score += 30 // Creating threats while sacrificing
+			score += ai.weights.SacrificeThreat // Creating threats while sacrificing
 		}
 	}
 	
@@ -866,7 +908,7 @@ func (ai *AIPlayer) evaluateEndgame(originalState, newState *game.GameState, mov
 	// 3. Creating multiple big board threats
 	
 	bigBoardThreats := ai.evaluateBigBoardThreats(newState)
-	score += bigBoardThreats * 200 // Double the importance
+	score += bigBoardThreats * ai.weights.EndgameBigThreat
 	
 	// Check if this move creates or prevents immediate big board wins
 	lines := [][]int{
@@ -891,11 +933,9 @@ func (ai *AIPlayer) evaluateEndgame(originalState, newState *game.GameState, mov
 			}
 		}
 		
-		// Do we complete a line?
-		if aiCount == 3 {
-			score += 5000 // Win!
-		} else if aiCount == 2 && emptyCount == 1 {
-			score += 800 // Almost won!
+		// A completed line has already returned as a win from evaluateMove
+		if aiCount == 2 && emptyCount == 1 {
+			score += ai.weights.EndgameTwoInRow // Almost won!
 		}
 		
 		// Check original state for this line to see if we blocked opponent
@@ -915,7 +955,7 @@ func (ai *AIPlayer) evaluateEndgame(originalState, newState *game.GameState, mov
 		
 		// Did we block opponent win?
 		if origOppCount == 2 && origEmptyCount == 1 && oppCount == 2 && emptyCount == 0 {
-			score += 1000 // Blocked opponent win!
+			score += ai.weights.EndgameBlock // Blocked opponent win!
 		}
 	}
 	
@@ -978,28 +1018,28 @@ func (ai *AIPlayer) evaluatePosition(gameState *game.GameState) float64 {
 	
 	for i := 0; i < 9; i++ {
 		if gameState.BigBoardWins[i] == ai.player {
-			baseScore := 500.0
+			baseScore := ai.weights.BoardWon
 			if i == 4 { // Center board
-				baseScore = 800.0
-				centerBoardBonus += 200.0
+				baseScore = ai.weights.CenterBoardWon
+				centerBoardBonus += ai.weights.CenterBoardBonus
 			}
 			corners := []int{0, 2, 6, 8}
 			for _, corner := range corners {
 				if i == corner {
-					baseScore = 600.0
-					cornerBoardBonus += 100.0
+					baseScore = ai.weights.CornerBoardWon
+					cornerBoardBonus += ai.weights.CornerBoardBonus
 				}
 			}
 			score += baseScore
 		} else if gameState.BigBoardWins[i] == opponent {
-			baseScore := -500.0
+			baseScore := -ai.weights.BoardWon
 			if i == 4 { // Opponent has center
-				baseScore = -800.0
+				baseScore = -ai.weights.CenterBoardWon
 			}
 			corners := []int{0, 2, 6, 8}
 			for _, corner := range corners {
 				if i == corner {
-					baseScore = -600.0
+					baseScore = -ai.weights.CornerBoardWon
 				}
 			}
 			score += baseScore
@@ -1008,7 +1048,7 @@ func (ai *AIPlayer) evaluatePosition(gameState *game.GameState) float64 {
 
 	// BIG BOARD THREATS - look for almost-wins
 	bigBoardThreats := ai.evaluateBigBoardThreats(gameState)
-	score += bigBoardThreats * 300 // Each big board threat is very valuable
+	score += bigBoardThreats * ai.weights.BigBoardThreat // Each big board threat is very valuable
 
 	// SMALL BOARD EVALUATIONS - detailed analysis
 	for i := 0; i < 9; i++ {
@@ -1016,14 +1056,14 @@ func (ai *AIPlayer) evaluatePosition(gameState *game.GameState) float64 {
 			smallBoardScore := ai.evaluateSmallBoardAdvanced(&gameState.BigBoard[i])
 			
 			// Weight small boards by strategic importance
-			weight := 20.0
+			weight := ai.weights.SmallBoard
 			if i == 4 { // Center board
-				weight = 40.0
+				weight = ai.weights.CenterSmallBoard
 			}
 			corners := []int{0, 2, 6, 8}
 			for _, corner := range corners {
 				if i == corner {
-					weight = 30.0
+					weight = ai.weights.CornerSmallBoard
 				}
 			}
 			
@@ -1034,20 +1074,20 @@ func (ai *AIPlayer) evaluatePosition(gameState *game.GameState) float64 {
 	// TEMPO AND BOARD CONTROL
 	if gameState.ActiveBoard == -1 {
 		// Anarchy mode - we can play anywhere, slight advantage
-		score += 50
+		score += ai.weights.Anarchy
 	} else {
 		// Evaluate the active board constraint
 		activeBoard := gameState.ActiveBoard
 		if gameState.BigBoardWins[activeBoard] != game.Empty {
 			// Opponent is forced into anarchy - good for us!
-			score += 100
+			score += ai.weights.ForcedAnarchy
 		} else {
 			// Evaluate if the active board favors us or opponent
 			boardAdvantage := ai.evaluateSmallBoardAdvantage(gameState, activeBoard, opponent)
 			if boardAdvantage < 0 {
-				score += 30 // We have advantage in active board
+				score += ai.weights.ActiveBoardControl // We have advantage in active board
 			} else {
-				score -= 30 // Opponent has advantage
+				score -= ai.weights.ActiveBoardControl // Opponent has advantage
 			}
 		}
 	}
@@ -1090,87 +1130,33 @@ func (ai *AIPlayer) evaluateBigBoardThreats(gameState *game.GameState) float64 {
 
 		// Two in a row with one empty = big threat!
 		if aiCount == 2 && emptyCount == 1 && opponentCount == 0 {
-			threats += 2.0 // Our threat
+			threats += ai.weights.BigTwoInRow // Our threat
 		} else if opponentCount == 2 && emptyCount == 1 && aiCount == 0 {
-			threats -= 2.5 // Opponent threat (more dangerous)
+			threats -= ai.weights.BigOpponentTwoInRow // Opponent threat (more dangerous)
 		} else if aiCount == 1 && emptyCount == 2 && opponentCount == 0 {
-			threats += 0.3 // Our potential
+			threats += ai.weights.BigOneInRow // Our potential
 		} else if opponentCount == 1 && emptyCount == 2 && aiCount == 0 {
-			threats -= 0.3 // Opponent potential
+			threats -= ai.weights.BigOpponentOneInRow // Opponent potential
 		}
 	}
 
 	return threats
 }
 
-// evaluateSmallBoardAdvanced gives detailed small board evaluation  
+// evaluateSmallBoardAdvanced gives detailed small board evaluation from the
+// small board table
 func (ai *AIPlayer) evaluateSmallBoardAdvanced(board *game.SmallBoard) float64 {
-	score := 0.0
-	opponent := game.X
-	if ai.player == game.X {
-		opponent = game.O
-	}
-
-	lines := [][]int{
-		{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, // rows
-		{0, 3, 6}, {1, 4, 7}, {2, 5, 8}, // columns
-		{0, 4, 8}, {2, 4, 6}, // diagonals
-	}
-
-	for _, line := range lines {
-		aiCount := 0
-		opponentCount := 0
-		emptyCount := 0
-
-		for _, pos := range line {
-			switch board[pos] {
-			case ai.player:
-				aiCount++
-			case opponent:
-				opponentCount++
-			case game.Empty:
-				emptyCount++
-			}
-		}
-
-		// Score based on line control with Ultimate TTT weighting
-		if aiCount == 3 {
-			score += 100 // Won this small board
-		} else if aiCount == 2 && emptyCount == 1 && opponentCount == 0 {
-			score += 25 // Two in a row, can win
-		} else if aiCount == 1 && emptyCount == 2 && opponentCount == 0 {
-			score += 3 // One in a row with potential
-		}
-
-		if opponentCount == 3 {
-			score -= 100 // Opponent won
-		} else if opponentCount == 2 && emptyCount == 1 && aiCount == 0 {
-			score -= 30 // Must block opponent
-		} else if opponentCount == 1 && emptyCount == 2 && aiCount == 0 {
-			score -= 3 // Opponent potential
-		}
-	}
-
-	// CENTER AND CORNER BONUSES for small boards
-	if board[4] == ai.player { // Center
-		score += 8
-	} else if board[4] == opponent {
-		score -= 8
-	}
-
-	corners := []int{0, 2, 6, 8}
-	for _, corner := range corners {
-		if board[corner] == ai.player {
-			score += 3
-		} else if board[corner] == opponent {
-			score -= 3
-		}
-	}
-
-	return score
+	f := &smallBoard(board).features[ai.player]
+	return f.linesWon*ai.weights.SmallLineWon +
+		f.twoInRow*ai.weights.SmallTwoInRow +
+		f.oneInRow*ai.weights.SmallOneInRow -
+		f.opponentTwoInRow*ai.weights.SmallOpponentTwoInRow -
+		f.opponentOneInRow*ai.weights.SmallOpponentOneInRow +
+		f.center*ai.weights.SmallCenter +
+		f.corners*ai.weights.SmallCorner +
+		f.forcedWin*ai.weights.SmallForcedWin
 }
 
-
 // getValidMoves returns all valid moves for the current game state
 func (ai *AIPlayer) getValidMoves(gameState *game.GameState) []game.Move {
 	var moves []game.Move
@@ -1231,7 +1217,8 @@ func (ai *AIPlayer) getAllLegalMoves(gameState *game.GameState) []game.Move {
 	return moves
 }
 
-// copyGameState creates a deep copy of the game state
+// copyGameState creates a deep copy of the game state. The move history is
+// left out because search never looks at it.
 func (ai *AIPlayer) copyGameState(original *game.GameState) *game.GameState {
 	newState := &game.GameState{
 		BigBoard:      original.BigBoard,
@@ -0,0 +1,286 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"sort"
+	"t-9/internal/game"
+	"time"
+)
+
+const (
+	DefaultAnalysisDepth = 3
+	MaxAnalysisDepth     = 5
+
+	// winProbabilityScale converts evaluation scores into win probabilities.
+	// A score of this size is roughly a 73% expected score.
+	winProbabilityScale = 1000.0
+)
+
+// Analysis is a full evaluation of a position. Scores are from the point of
+// view of the side to move.
+type Analysis struct {
+	Position       string           `json:"position"`
+	SideToMove     game.Player      `json:"sideToMove"`
+	Depth          int              `json:"depth"`
+	Score          float64          `json:"score"`
+	Moves          []MoveEvaluation `json:"moves"`
+	BestLine       []game.Move      `json:"bestLine"`
+	WinProbability WinProbability   `json:"winProbability"`
+	Threats        ThreatMaps       `json:"threats"`
+	Stats          SearchStats      `json:"stats"`
+}
+
+// MoveEvaluation describes a single legal move in an analysed position
+type MoveEvaluation struct {
+	Move            game.Move   `json:"move"`
+	Score           float64     `json:"score"`
+	Heuristic       float64     `json:"heuristic"`
+	ThreatsCreated  int         `json:"threatsCreated"`
+	BigBoardThreats float64     `json:"bigBoardThreats"`
+	Line            []game.Move `json:"line"`
+}
+
+// WinProbability is the expected score of each side, with draws counting half
+type WinProbability struct {
+	X float64 `json:"x"`
+	O float64 `json:"o"`
+}
+
+// ThreatMaps lists, for every small board, the cells where each player would
+// complete a line with their next move there
+type ThreatMaps struct {
+	X [9][]int `json:"x"`
+	O [9][]int `json:"o"`
+}
+
+// Analyze evaluates every legal move of a position with a fixed-depth search.
+// It stops early with the context's error when ctx is cancelled.
+func Analyze(ctx context.Context, gameState *game.GameState, depth int) (*Analysis, error) {
+	if depth < 1 {
+		depth = DefaultAnalysisDepth
+	}
+	if depth > MaxAnalysisDepth {
+		depth = MaxAnalysisDepth
+	}
+
+	mover := gameState.CurrentPlayer
+	engine := NewAIPlayer(Hard, mover)
+	engine.ctx = ctx
+	engine.startStats()
+	engine.rootDepth, engine.stats.Depth = depth, depth
+
+	analysis := &Analysis{
+		Position:   gameState.Notation(),
+		SideToMove: mover,
+		Depth:      depth,
+		Moves:      []MoveEvaluation{},
+		BestLine:   []game.Move{},
+		Threats:    FindThreats(gameState),
+	}
+
+	if gameState.GameOver {
+		analysis.Score = engine.evaluatePosition(gameState)
+		analysis.WinProbability = resultProbability(gameState)
+		return analysis, nil
+	}
+
+	for _, move := range legalMoves(gameState) {
+		newState := engine.copyGameState(gameState)
+		newState.PlayMove(move)
+
+		score, line := engine.search(newState, depth-1, math.Inf(-1), math.Inf(1))
+		if engine.aborted {
+			return nil, ctx.Err()
+		}
+		analysis.Moves = append(analysis.Moves, MoveEvaluation{
+			Move:            move,
+			Score:           score,
+			Heuristic:       engine.evaluateMove(gameState, move),
+			ThreatsCreated:  engine.countThreatsCreated(newState, move.BigBoardIndex),
+			BigBoardThreats: engine.evaluateBigBoardThreats(newState),
+			Line:            append([]game.Move{move}, line...),
+		})
+	}
+
+	sort.SliceStable(analysis.Moves, func(i, j int) bool {
+		return analysis.Moves[i].Score > analysis.Moves[j].Score
+	})
+
+	engine.finishStats()
+	analysis.Stats = engine.SearchStats()
+
+	best := analysis.Moves[0]
+	analysis.Score = best.Score
+	analysis.BestLine = best.Line
+	analysis.WinProbability = scoreProbability(best.Score, mover)
+
+	return analysis, nil
+}
+
+// FindThreats builds the threat maps for every undecided small board
+func FindThreats(gameState *game.GameState) ThreatMaps {
+	var threats ThreatMaps
+	for i := 0; i < 9; i++ {
+		threats.X[i] = []int{}
+		threats.O[i] = []int{}
+		if gameState.BigBoardWins[i] != game.Empty {
+			continue
+		}
+		threats.X[i] = winningCells(&gameState.BigBoard[i], game.X)
+		threats.O[i] = winningCells(&gameState.BigBoard[i], game.O)
+	}
+	return threats
+}
+
+// search is a minimax search with alpha-beta pruning that generates moves for
+// whichever side is to move. It returns the score from the AI's point of view
+// and the principal variation. Positions already searched deeply enough are
+// answered from the transposition table, and otherwise its best move for the
+// position is tried first.
+func (ai *AIPlayer) search(gameState *game.GameState, depth int, alpha, beta float64) (float64, []game.Move) {
+	ai.nodes++
+	ai.countNode(ai.rootDepth - depth)
+	if ai.searchExpired() {
+		return 0, nil
+	}
+
+	if depth == 0 || gameState.GameOver {
+		return ai.evaluate(gameState), nil
+	}
+
+	moves := legalMoves(gameState)
+	if len(moves) == 0 {
+		return ai.evaluate(gameState), nil
+	}
+
+	key := positionKey(gameState)
+	entry, settled := ai.probeTT(key, depth, alpha, beta)
+	if settled {
+		return entry.score, entry.line
+	}
+	if depth > 1 {
+		ai.orderMoves(gameState, moves)
+	}
+	hashMoveFirst(moves, entry)
+	ai.countInterior()
+
+	maximizing := gameState.CurrentPlayer == ai.player
+	bestScore := math.Inf(1)
+	if maximizing {
+		bestScore = math.Inf(-1)
+	}
+	var bestLine []game.Move
+	windowAlpha, windowBeta := alpha, beta
+	cutoff := false
+
+	for i, move := range moves {
+		ai.stats.children++
+		newState := ai.copyGameState(gameState)
+		newState.PlayMove(move)
+
+		score, line := ai.search(newState, depth-1, alpha, beta)
+
+		if (maximizing && score > bestScore) || (!maximizing && score < bestScore) {
+			bestScore = score
+			bestLine = append([]game.Move{move}, line...)
+		}
+
+		if maximizing {
+			alpha = math.Max(alpha, score)
+		} else {
+			beta = math.Min(beta, score)
+		}
+		if beta <= alpha {
+			ai.countCutoff(i + 1)
+			cutoff = true
+			break // Alpha-beta pruning
+		}
+	}
+
+	bound := searchBound(maximizing, cutoff, bestScore, windowAlpha, windowBeta)
+	ai.storeTT(key, depth, bestScore, bound, bestLine[0], bestLine)
+	return bestScore, bestLine
+}
+
+// searchExpired reports whether the search has run past its node limit or
+// deadline or has been cancelled. The clock and the context are only checked
+// every few thousand nodes.
+func (ai *AIPlayer) searchExpired() bool {
+	if ai.aborted {
+		return true
+	}
+	if ai.nodeLimit > 0 && ai.nodes >= ai.nodeLimit {
+		ai.aborted = true
+		return true
+	}
+	if ai.nodes%2048 != 0 {
+		return false
+	}
+	if ai.ctx != nil && ai.ctx.Err() != nil {
+		ai.aborted = true
+	} else if !ai.deadline.IsZero() && time.Now().After(ai.deadline) {
+		ai.aborted = true
+	}
+	return ai.aborted
+}
+
+// legalMoves returns every legal move for the side to move
+func legalMoves(gameState *game.GameState) []game.Move {
+	var moves []game.Move
+
+	if gameState.GameOver {
+		return moves
+	}
+
+	for bigIndex := 0; bigIndex < 9; bigIndex++ {
+		if gameState.ActiveBoard != -1 && bigIndex != gameState.ActiveBoard {
+			continue
+		}
+		if gameState.BigBoardWins[bigIndex] != game.Empty {
+			continue
+		}
+		for smallIndex := 0; smallIndex < 9; smallIndex++ {
+			if gameState.BigBoard[bigIndex][smallIndex] == game.Empty {
+				moves = append(moves, game.Move{
+					BigBoardIndex:   bigIndex,
+					SmallBoardIndex: smallIndex,
+					Player:          gameState.CurrentPlayer,
+				})
+			}
+		}
+	}
+
+	return moves
+}
+
+// winningCells returns the empty cells that would complete a line for player
+func winningCells(board *game.SmallBoard, player game.Player) []int {
+	return threatCells(smallBoard(board).threats[player])
+}
+
+// expectedScore converts a score into the expected score of the same side
+func expectedScore(score float64) float64 {
+	return 1 / (1 + math.Exp(-score/winProbabilityScale))
+}
+
+// scoreProbability converts a score for player into win probabilities
+func scoreProbability(score float64, player game.Player) WinProbability {
+	p := expectedScore(score)
+	if player == game.X {
+		return WinProbability{X: p, O: 1 - p}
+	}
+	return WinProbability{X: 1 - p, O: p}
+}
+
+// resultProbability returns the win probabilities of a finished game
+func resultProbability(gameState *game.GameState) WinProbability {
+	switch gameState.GameWon {
+	case game.X:
+		return WinProbability{X: 1, O: 0}
+	case game.O:
+		return WinProbability{X: 0, O: 1}
+	default:
+		return WinProbability{X: 0.5, O: 0.5}
+	}
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"t-9/internal/game"
+	"time"
+)
+
+// DefaultBenchDepth is the depth bench searches every position to
+const DefaultBenchDepth = 7
+
+// benchPositions is a fixed suite of opening, middlegame and late positions
+// taken from self-play games
+var benchPositions = []string{
+	game.StartNotation,
+	".......x./........./........./........./o...x..../.o......./........./.....x.o./......... x 1",
+	"...o...x./...x....o/..o....../.....ox../o...x..../xo......./.x....o../.....x.o./..x...... x 2",
+	".x.o...x./...x...oo/..o.....x/o..x.oxx./o..ox..../xo.....o./.x....o../....xx.oo/..xo.x... x 7",
+	"........./...x...../........./........./....x...o/.......o./........./.o.....x./.....x... o 3",
+	"..x....../...x.o.../x.....o../.x.o...../....x...o/......xo./o.......x/.o.....x./..o..x... o 0",
+	"..x....o./...x.o.x./x.....o../.x.o.o..o/....x...o/...x..xo./oo.....ox/.ox...xxo/..ox.xx.. o 2",
+	"........./.x.....o./........./.....x.../....xo.../.o.....x./........./...o...../......... x 7",
+	"..o...x../.x.....o./...x....o/o....x.../....xo.../.o.....x./..x...o../x..o...../.o......x x 1",
+	".oo..ox../xxox...o./x..x.x..o/o..o.x.../....xo.../.oo...xx./..x...oo./xx.o...../.o......x x 3",
+	"........./.......ox/...x...../.x.o...../..o.x..../.o......./........./.....x.../......... o 8",
+	"o.x....../.......ox/...x.o.../.x.o...../..o.x..../.o...x.x./......x.o/.....xo../x....o... o 5",
+	"o.x....../.x.o.o.ox/.oxx.o.o./.xxoo..../.xo.x..../.oox.x.x./......x.o/..x..xo../x....o... o 2",
+}
+
+// BenchResult is the search of one bench position
+type BenchResult struct {
+	Position string
+	Move     game.Move
+	Stats    SearchStats
+}
+
+// Bench searches every bench position to a fixed depth with the built-in
+// evaluation and default weights, calling report after each one. The total
+// node count is a signature of the search: it only changes when the search or
+// the evaluation does.
+func Bench(depth int, report func(BenchResult)) SearchStats {
+	var total SearchStats
+	var elapsed time.Duration
+
+	for _, notation := range benchPositions {
+		state, err := game.ParseNotation(notation)
+		if err != nil {
+			panic(err)
+		}
+
+		start := time.Now()
+		player := NewAIPlayer(Hard, state.CurrentPlayer)
+		move, _ := player.Think(context.Background(), state, depth, 0, nil)
+		elapsed += time.Since(start)
+		stats := player.SearchStats()
+		if report != nil {
+			report(BenchResult{Position: notation, Move: move, Stats: stats})
+		}
+
+		total.Nodes += stats.Nodes
+		total.TTProbes += stats.TTProbes
+		total.TTHits += stats.TTHits
+		total.Cutoffs += stats.Cutoffs
+		total.FirstMoveCutoffs += stats.FirstMoveCutoffs
+		total.interiorNodes += stats.interiorNodes
+		total.children += stats.children
+		if stats.SelDepth > total.SelDepth {
+			total.SelDepth = stats.SelDepth
+		}
+	}
+
+	total.Depth = depth
+	total.setRates(elapsed)
+	return total
+}
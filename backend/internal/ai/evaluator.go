@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"math"
+	"sort"
+	"t-9/internal/game"
+)
+
+// Evaluator scores unfinished positions for the search in place of the
+// built-in evaluation. Scores are from player's point of view and use the
+// same scale as the built-in evaluation.
+type Evaluator interface {
+	Evaluate(gameState *game.GameState, player game.Player) float64
+}
+
+// PolicyEvaluator is an evaluator that can also rate the moves of a position,
+// so the search tries the most promising ones first. Priors are indexed by
+// big board * 9 + small board.
+type PolicyEvaluator interface {
+	Evaluator
+	Policy(gameState *game.GameState) [81]float64
+}
+
+// SetEvaluator replaces the built-in evaluation used by the search. Leveled
+// players and Think use it; the legacy difficulties do not.
+func (ai *AIPlayer) SetEvaluator(evaluator Evaluator) {
+	ai.evaluator = evaluator
+}
+
+// ValueToScore converts an expected result between -1 (loss) and 1 (win)
+// into a score with the same win probability as the built-in evaluation
+func ValueToScore(value float64) float64 {
+	value = math.Max(-0.999, math.Min(0.999, value))
+	return winProbabilityScale * math.Log((1+value)/(1-value))
+}
+
+// evaluate scores a position with the evaluator if one is set. Finished games
+// are always scored by the built-in evaluation.
+func (ai *AIPlayer) evaluate(gameState *game.GameState) float64 {
+	if ai.evaluator == nil || gameState.GameOver {
+		return ai.evaluatePosition(gameState)
+	}
+	return ai.evaluator.Evaluate(gameState, ai.player)
+}
+
+// orderMoves sorts moves by the evaluator's policy, best first, when it has
+// one
+func (ai *AIPlayer) orderMoves(gameState *game.GameState, moves []game.Move) {
+	policy, ok := ai.evaluator.(PolicyEvaluator)
+	if !ok {
+		return
+	}
+
+	priors := policy.Policy(gameState)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return priors[moves[i].BigBoardIndex*9+moves[i].SmallBoardIndex] >
+			priors[moves[j].BigBoardIndex*9+moves[j].SmallBoardIndex]
+	})
+}
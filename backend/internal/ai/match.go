@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"t-9/internal/game"
+)
+
+// Engine chooses moves for the side it plays. It returns an empty move when
+// there are no valid moves, and stops soon after ctx is cancelled.
+type Engine interface {
+	GetBestMoveContext(ctx context.Context, gameState *game.GameState) (game.Move, error)
+}
+
+// PlayGame plays a full game between two engines, starting after the given
+// opening moves
+func PlayGame(ctx context.Context, x, o Engine, opening []game.Move) (*game.GameState, error) {
+	state := game.NewGame()
+	for i, move := range opening {
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("opening move %d: %w", i+1, err)
+		}
+	}
+
+	for !state.GameOver {
+		mover := x
+		if state.CurrentPlayer == game.O {
+			mover = o
+		}
+
+		move, err := mover.GetBestMoveContext(ctx, state)
+		if err != nil {
+			return nil, fmt.Errorf("ply %d: %w", len(state.MoveHistory)+1, err)
+		}
+		if move.Player == game.Empty {
+			return nil, fmt.Errorf("no move found at ply %d", len(state.MoveHistory)+1)
+		}
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("ply %d: %w", len(state.MoveHistory)+1, err)
+		}
+	}
+
+	return state, nil
+}
+
+// RandomOpening returns a sequence of random legal moves from the start
+func RandomOpening(rng *rand.Rand, plies int) []game.Move {
+	state := game.NewGame()
+	for len(state.MoveHistory) < plies && !state.GameOver {
+		moves := legalMoves(state)
+		state.MakeMove(moves[rng.Intn(len(moves))])
+	}
+	return state.MoveHistory
+}
+
+// EloDifference converts a match score between 0 and 1 into a rating
+// difference. Scores are clamped so a whitewash gives a finite answer.
+func EloDifference(score float64) float64 {
+	score = math.Max(0.01, math.Min(0.99, score))
+	return -400 * math.Log10(1/score-1)
+}
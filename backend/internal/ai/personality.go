@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"fmt"
+	"t-9/internal/game"
+)
+
+// Personality is a playing style. Each style reweights the move heuristics
+// and the position evaluation, so it changes how the AI plays without
+// changing its strength level.
+type Personality string
+
+const (
+	Balanced   Personality = "balanced"
+	Aggressive Personality = "aggressive"
+	Defensive  Personality = "defensive"
+	Chaotic    Personality = "chaotic"
+	Trickster  Personality = "trickster"
+)
+
+// PersonalityInfo describes a playing style to players
+type PersonalityInfo struct {
+	Name        Personality `json:"name"`
+	Description string      `json:"description"`
+}
+
+// personalityStyle is the description and reweighting of a style
+type personalityStyle struct {
+	description string
+	adjust      func(w *Weights)
+}
+
+// styleBias is the share of the move heuristics added to leveled search
+// scores, so styles show even when the search decides the move
+const styleBias = 0.1
+
+// personalityOrder lists the styles in the order they are shown
+var personalityOrder = []Personality{Balanced, Aggressive, Defensive, Chaotic, Trickster}
+
+var personalities = map[Personality]personalityStyle{
+	Balanced: {
+		description: "Plays the standard evaluation with no preferences",
+		adjust:      func(w *Weights) {},
+	},
+	Aggressive: {
+		description: "Goes for threats and forks, even at the cost of defence",
+		adjust: func(w *Weights) {
+			w.MoveThreat *= 2.5
+			w.MoveFork *= 2.5
+			w.MoveWinBoard *= 1.5
+			w.MoveChain *= 1.5
+			w.BigTwoInRow *= 1.5
+			w.SmallTwoInRow *= 1.5
+			w.MoveBlockBoard *= 0.6
+			w.BigOpponentTwoInRow *= 0.7
+			w.SmallOpponentTwoInRow *= 0.7
+			w.MoveStyle = styleBias
+		},
+	},
+	Defensive: {
+		description: "Blocks first and only sends you to boards that are safe for it",
+		adjust: func(w *Weights) {
+			w.MoveBlockWin *= 1.5
+			w.MoveBlockBoard *= 2
+			w.MoveSendingTarget *= 1.5
+			w.MoveDangerous = 300
+			w.BigOpponentTwoInRow *= 1.5
+			w.SmallOpponentTwoInRow *= 1.5
+			w.MoveThreat *= 0.7
+			w.MoveAnarchy *= 0.5
+			w.MoveStyle = styleBias
+		},
+	},
+	Chaotic: {
+		description: "Likes open play and sends you to open boards, caring little where",
+		adjust: func(w *Weights) {
+			w.MoveSendToWonBoard *= 2
+			w.MoveAnarchy *= 3
+			w.Anarchy *= 2
+			w.ForcedAnarchy *= 2
+			w.MoveSendingTarget *= 0.5
+			w.MoveChain = 0
+			w.MoveStyle = styleBias
+		},
+	},
+	Trickster: {
+		description: "Sets traps, giving up boards to lure you where it wants you",
+		adjust: func(w *Weights) {
+			w.MoveSacrifice *= 4
+			w.MoveChain *= 2
+			w.MoveSendingTarget *= 1.25
+			w.MoveCenterBoard *= 0.5
+			w.MoveStyle = styleBias
+		},
+	},
+}
+
+// Personalities returns every playing style
+func Personalities() []PersonalityInfo {
+	infos := make([]PersonalityInfo, len(personalityOrder))
+	for i, name := range personalityOrder {
+		infos[i] = name.Info()
+	}
+	return infos
+}
+
+// ParsePersonality returns the style with the given name. An empty name is
+// the balanced style.
+func ParsePersonality(name string) (Personality, error) {
+	if name == "" {
+		return Balanced, nil
+	}
+	if _, ok := personalities[Personality(name)]; !ok {
+		return "", fmt.Errorf("unknown style %q", name)
+	}
+	return Personality(name), nil
+}
+
+// Info returns the name and description of a style
+func (p Personality) Info() PersonalityInfo {
+	return PersonalityInfo{Name: p, Description: personalities[p].description}
+}
+
+// Apply returns the weights reweighted for the style
+func (p Personality) Apply(weights Weights) Weights {
+	if style, ok := personalities[p]; ok {
+		style.adjust(&weights)
+	}
+	return weights
+}
+
+// SetPersonality gives an AI player a playing style by reweighting its
+// current weights. Call it after SetWeights.
+func (ai *AIPlayer) SetPersonality(personality Personality) {
+	ai.personality = personality
+	ai.weights = personality.Apply(ai.weights)
+}
+
+// Personality returns the playing style of an AI player
+func (ai *AIPlayer) Personality() Personality {
+	if ai.personality == "" {
+		return Balanced
+	}
+	return ai.personality
+}
+
+// styleBonus is the share of a move's heuristic score that a styled player
+// adds to its search score
+func (ai *AIPlayer) styleBonus(gameState *game.GameState, move game.Move) float64 {
+	if ai.weights.MoveStyle == 0 {
+		return 0
+	}
+	return ai.weights.MoveStyle * ai.evaluateMove(gameState, move)
+}
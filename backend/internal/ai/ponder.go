@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"t-9/internal/game"
+	"time"
+)
+
+const (
+	// The opponent's reply is guessed with a short fixed search, so most of
+	// the ponder time goes to the answer
+	ponderGuessDepth = 4
+	ponderGuessTime  = 200 * time.Millisecond
+)
+
+// PonderConfig controls pondering, searching on the opponent's time
+type PonderConfig struct {
+	Enabled bool
+	MaxTime time.Duration // Longest a single ponder search may run
+
+	// Run starts a ponder search, so it can share the workers of other
+	// searches. It returns false if the search cannot start now; otherwise it
+	// must call search exactly once, with a cancelled context if the search
+	// is dropped before it starts. Without Run searches get a goroutine each.
+	Run func(search func(ctx context.Context)) bool
+}
+
+// Session is an AI player kept for the whole of a game. After each of its
+// moves it guesses the opponent's reply and searches its answer in the
+// background, so the answer is ready when the guess is played.
+//
+// The player is only used by one search at a time: Move waits for or stops
+// the ponder search before searching itself.
+type Session struct {
+	player *AIPlayer
+	config PonderConfig
+
+	mu     sync.Mutex
+	ponder *ponderSearch
+}
+
+// ponderSearch is a background search of the position after a guessed reply
+type ponderSearch struct {
+	cancel  context.CancelFunc
+	guessed chan struct{} // Closed once the guess is known or guessing failed
+	done    chan struct{}
+
+	// Set before guessed is closed. An empty position means no guess.
+	guess    game.Move
+	position string // Notation of the position after the guess
+
+	// Set before done is closed
+	move  game.Move
+	err   error
+	stats SearchStats
+}
+
+// SessionMove is a move chosen by a session
+type SessionMove struct {
+	Move      game.Move
+	Stats     SearchStats
+	PonderHit bool // The move was searched while the opponent was thinking
+}
+
+// NewSession creates a session for an AI player
+func NewSession(player *AIPlayer, config PonderConfig) *Session {
+	return &Session{player: player, config: config}
+}
+
+// Player returns the session's AI player, for its settings. It must not be
+// searched with directly.
+func (s *Session) Player() *AIPlayer {
+	return s.player
+}
+
+// Move returns the AI's move in a position. If the position is the one being
+// pondered the ponder result is used, waiting for the search to finish if
+// needed; otherwise pondering stops and a normal search runs.
+func (s *Session) Move(ctx context.Context, gameState *game.GameState) (SessionMove, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.takePonder(); p != nil {
+		if result, hit := p.result(ctx, gameState.Notation()); hit {
+			return result, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return SessionMove{}, err
+		}
+	}
+
+	move, err := s.player.GetBestMoveContext(ctx, gameState)
+	if err != nil {
+		return SessionMove{}, err
+	}
+	return SessionMove{Move: move, Stats: s.player.SearchStats()}, nil
+}
+
+// Ponder starts searching in the background on the opponent's time. It is
+// called with the position after the AI's move, the opponent to move.
+func (s *Session) Ponder(gameState *game.GameState) {
+	if !s.config.Enabled || s.config.MaxTime <= 0 || gameState.GameOver {
+		return
+	}
+	if gameState.CurrentPlayer == s.player.player {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.takePonder(); p != nil {
+		p.stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.MaxTime)
+	p := &ponderSearch{
+		cancel:  cancel,
+		guessed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	gameState = gameState.Clone()
+	search := func(runCtx context.Context) {
+		defer close(p.done)
+		defer cancel()
+		stop := context.AfterFunc(runCtx, cancel)
+		defer stop()
+
+		next, ok := p.makeGuess(ctx, gameState)
+		close(p.guessed)
+		if !ok {
+			return
+		}
+		p.move, p.err = s.player.GetBestMoveContext(ctx, next)
+		p.stats = s.player.SearchStats()
+	}
+
+	if s.config.Run == nil {
+		go search(ctx)
+	} else if !s.config.Run(search) {
+		cancel()
+		return
+	}
+	s.ponder = p
+}
+
+// Observe stops pondering when the opponent plays something other than the
+// guess. It is called with the position after the opponent's move.
+func (s *Session) Observe(gameState *game.GameState) {
+	// A session that is busy is in Move, which checks the position itself
+	if !s.mu.TryLock() {
+		return
+	}
+	defer s.mu.Unlock()
+	if s.ponder == nil {
+		return
+	}
+	select {
+	case <-s.ponder.guessed:
+		if s.ponder.position != gameState.Notation() {
+			s.takePonder().stop()
+		}
+	default:
+		// Still guessing, Move will sort it out
+	}
+}
+
+// Stop stops pondering and waits for the search to end
+func (s *Session) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.takePonder(); p != nil {
+		p.stop()
+	}
+}
+
+// takePonder removes the ponder search from the session. The caller holds
+// s.mu.
+func (s *Session) takePonder() *ponderSearch {
+	p := s.ponder
+	s.ponder = nil
+	return p
+}
+
+// makeGuess guesses the opponent's reply and returns the position after it.
+// It fails if there is nothing to ponder.
+func (p *ponderSearch) makeGuess(ctx context.Context, gameState *game.GameState) (*game.GameState, bool) {
+	guess, err := guessReply(ctx, gameState)
+	if err != nil || guess.Player == game.Empty {
+		return nil, false
+	}
+	next := gameState.Clone()
+	if err := next.MakeMove(guess); err != nil || next.GameOver {
+		return nil, false
+	}
+	p.guess, p.position = guess, next.Notation()
+	return next, true
+}
+
+// result returns the ponder move if position is the one pondered, waiting for
+// the search to finish. Otherwise, or if ctx is cancelled, the search is
+// stopped.
+func (p *ponderSearch) result(ctx context.Context, position string) (SessionMove, bool) {
+	select {
+	case <-p.guessed:
+	case <-ctx.Done():
+		p.stop()
+		return SessionMove{}, false
+	}
+	if p.position != position {
+		p.stop()
+		return SessionMove{}, false
+	}
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.stop()
+		return SessionMove{}, false
+	}
+	// A search cut short by the ponder time limit has no move
+	if p.err != nil || p.move.Player == game.Empty {
+		return SessionMove{}, false
+	}
+	return SessionMove{Move: p.move, Stats: p.stats, PonderHit: true}, true
+}
+
+// stop cancels the search and waits until it no longer uses the player
+func (p *ponderSearch) stop() {
+	p.cancel()
+	<-p.done
+}
+
+// guessReply predicts the opponent's move with a short search
+func guessReply(ctx context.Context, gameState *game.GameState) (game.Move, error) {
+	guesser := NewAIPlayer(Hard, gameState.CurrentPlayer)
+	return guesser.Think(ctx, gameState, ponderGuessDepth, ponderGuessTime, nil)
+}
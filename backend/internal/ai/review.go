@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"t-9/internal/game"
+)
+
+// MoveClass classifies a played move by how much expected score it gave away
+type MoveClass string
+
+const (
+	ClassBest       MoveClass = "best"
+	ClassGood       MoveClass = "good"
+	ClassInaccuracy MoveClass = "inaccuracy"
+	ClassMistake    MoveClass = "mistake"
+	ClassBlunder    MoveClass = "blunder"
+)
+
+const (
+	// Expected-score loss thresholds for each move class
+	goodLoss       = 0.05
+	inaccuracyLoss = 0.10
+	mistakeLoss    = 0.20
+
+	// Moves that swing the expected score at least this much are turning points
+	turningPointSwing = 0.15
+	maxTurningPoints  = 5
+)
+
+// GameReview is the engine's verdict on every move of a game
+type GameReview struct {
+	Result        game.Player    `json:"result"`
+	Depth         int            `json:"depth"`
+	Moves         []MoveReview   `json:"moves"`
+	X             PlayerReview   `json:"x"`
+	O             PlayerReview   `json:"o"`
+	TurningPoints []TurningPoint `json:"turningPoints"`
+}
+
+// MoveReview compares a played move with the engine's best move
+type MoveReview struct {
+	Ply            int            `json:"ply"`
+	Move           game.Move      `json:"move"`
+	Class          MoveClass      `json:"class"`
+	Score          float64        `json:"score"`
+	BestMove       game.Move      `json:"bestMove"`
+	BestScore      float64        `json:"bestScore"`
+	Loss           float64        `json:"loss"`
+	Accuracy       float64        `json:"accuracy"`
+	WinProbability WinProbability `json:"winProbability"`
+}
+
+// PlayerReview summarises the moves of one side
+type PlayerReview struct {
+	Player   game.Player       `json:"player"`
+	Accuracy float64           `json:"accuracy"`
+	Counts   map[MoveClass]int `json:"counts"`
+}
+
+// TurningPoint is a move that changed the expected outcome of the game
+type TurningPoint struct {
+	Ply         int       `json:"ply"`
+	Move        game.Move `json:"move"`
+	Swing       float64   `json:"swing"`
+	Description string    `json:"description"`
+}
+
+// ReviewGame replays a move history and evaluates each position with the
+// engine. Scores are from the point of view of the player who moved. The
+// result is the game's final result as stored, since a resignation or timeout
+// is not among the moves. It stops early with the context's error when ctx is
+// cancelled.
+func ReviewGame(ctx context.Context, moves []game.Move, result game.Player, depth int) (*GameReview, error) {
+	if depth < 1 {
+		depth = DefaultAnalysisDepth
+	}
+	if depth > MaxAnalysisDepth {
+		depth = MaxAnalysisDepth
+	}
+
+	review := &GameReview{
+		Result:        result,
+		Depth:         depth,
+		Moves:         []MoveReview{},
+		X:             PlayerReview{Player: game.X, Counts: map[MoveClass]int{}},
+		O:             PlayerReview{Player: game.O, Counts: map[MoveClass]int{}},
+		TurningPoints: []TurningPoint{},
+	}
+
+	state := game.NewGame()
+	for i, move := range moves {
+		analysis, err := Analyze(ctx, state, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		played, found := findEvaluation(analysis, move)
+		if !found {
+			return nil, fmt.Errorf("move %d is not legal in this position: %w", i+1, game.ErrInvalidMove)
+		}
+
+		best := analysis.Moves[0]
+		loss := math.Max(0, expectedScore(best.Score)-expectedScore(played.Score))
+
+		moveReview := MoveReview{
+			Ply:            i + 1,
+			Move:           move,
+			Class:          classifyMove(move, best.Move, loss),
+			Score:          played.Score,
+			BestMove:       best.Move,
+			BestScore:      best.Score,
+			Loss:           loss,
+			Accuracy:       moveAccuracy(loss),
+			WinProbability: scoreProbability(played.Score, move.Player),
+		}
+		review.Moves = append(review.Moves, moveReview)
+
+		boardsBefore := state.BigBoardWins
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+
+		if loss >= turningPointSwing {
+			review.TurningPoints = append(review.TurningPoints, TurningPoint{
+				Ply:         moveReview.Ply,
+				Move:        move,
+				Swing:       loss,
+				Description: describeTurningPoint(moveReview, boardsBefore, state),
+			})
+		}
+	}
+
+	review.X = summarisePlayer(game.X, review.Moves)
+	review.O = summarisePlayer(game.O, review.Moves)
+
+	// Keep only the biggest swings, reported in game order
+	sort.SliceStable(review.TurningPoints, func(i, j int) bool {
+		return review.TurningPoints[i].Swing > review.TurningPoints[j].Swing
+	})
+	if len(review.TurningPoints) > maxTurningPoints {
+		review.TurningPoints = review.TurningPoints[:maxTurningPoints]
+	}
+	sort.SliceStable(review.TurningPoints, func(i, j int) bool {
+		return review.TurningPoints[i].Ply < review.TurningPoints[j].Ply
+	})
+
+	return review, nil
+}
+
+// findEvaluation looks up a move in an analysis
+func findEvaluation(analysis *Analysis, move game.Move) (MoveEvaluation, bool) {
+	for _, evaluation := range analysis.Moves {
+		if evaluation.Move == move {
+			return evaluation, true
+		}
+	}
+	return MoveEvaluation{}, false
+}
+
+// classifyMove buckets a move by the expected score it gave away
+func classifyMove(move, bestMove game.Move, loss float64) MoveClass {
+	switch {
+	case move == bestMove || loss == 0:
+		return ClassBest
+	case loss < goodLoss:
+		return ClassGood
+	case loss < inaccuracyLoss:
+		return ClassInaccuracy
+	case loss < mistakeLoss:
+		return ClassMistake
+	default:
+		return ClassBlunder
+	}
+}
+
+// moveAccuracy maps an expected-score loss onto a 0-100 accuracy
+func moveAccuracy(loss float64) float64 {
+	accuracy := 103.1668*math.Exp(-4.354*loss) - 3.1669
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// summarisePlayer averages accuracy and counts move classes for one side
+func summarisePlayer(player game.Player, moves []MoveReview) PlayerReview {
+	summary := PlayerReview{Player: player, Counts: map[MoveClass]int{}}
+
+	total := 0.0
+	count := 0
+	for _, move := range moves {
+		if move.Move.Player != player {
+			continue
+		}
+		summary.Counts[move.Class]++
+		total += move.Accuracy
+		count++
+	}
+
+	if count > 0 {
+		summary.Accuracy = total / float64(count)
+	}
+	return summary
+}
+
+// describeTurningPoint explains what happened on a turning point move
+func describeTurningPoint(move MoveReview, boardsBefore game.BigBoardWins, after *game.GameState) string {
+	opponent := game.X
+	if move.Move.Player == game.X {
+		opponent = game.O
+	}
+
+	switch {
+	case after.GameOver && after.GameWon == move.Move.Player:
+		return fmt.Sprintf("%v won the game", move.Move.Player)
+	case boardsBefore[move.Move.BigBoardIndex] == game.Empty &&
+		after.BigBoardWins[move.Move.BigBoardIndex] == move.Move.Player:
+		return fmt.Sprintf("%v won board %d but missed a stronger move", move.Move.Player, move.Move.BigBoardIndex)
+	case after.ActiveBoard == -1:
+		return fmt.Sprintf("%v gave %v a free move", move.Move.Player, opponent)
+	default:
+		return fmt.Sprintf("%v sent %v to board %d", move.Move.Player, opponent, after.ActiveBoard)
+	}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"t-9/internal/game"
+)
+
+// solveNodeLimit bounds a single WinningMoves call. Open positions where
+// every board is playable can otherwise take minutes.
+const solveNodeLimit = 2000000
+
+var ErrSolveLimit = errors.New("solver node limit reached")
+
+// solver proves forced wins with an exact AND-OR search: the attacker needs
+// one winning move, every defender move must lose
+type solver struct {
+	ctx      context.Context
+	attacker game.Player
+	nodes    int
+}
+
+// WinningMoves returns the moves that force a win for the side to move within
+// plies plies, whatever the opponent plays. It returns ErrSolveLimit when the
+// position is too open to solve.
+func WinningMoves(ctx context.Context, gameState *game.GameState, plies int) ([]game.Move, error) {
+	s := &solver{ctx: ctx, attacker: gameState.CurrentPlayer}
+
+	var moves []game.Move
+	for _, move := range legalMoves(gameState) {
+		if plies == 1 && !s.winsBoard(gameState, move) {
+			continue
+		}
+		won, err := s.wins(playSolverMove(gameState, move), plies-1)
+		if err != nil {
+			return nil, err
+		}
+		if won {
+			moves = append(moves, move)
+		}
+	}
+	return moves, nil
+}
+
+// ShortestWin returns the moves that force the quickest win for the side to
+// move and how many plies the win takes, looking at most maxPlies ahead. No
+// moves means there is no forced win within maxPlies.
+func ShortestWin(ctx context.Context, gameState *game.GameState, maxPlies int) ([]game.Move, int, error) {
+	// The attacker moves on odd plies, so only those can end the game
+	for plies := 1; plies <= maxPlies; plies += 2 {
+		moves, err := WinningMoves(ctx, gameState, plies)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(moves) > 0 {
+			return moves, plies, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// wins reports whether the attacker wins from a position within plies plies
+func (s *solver) wins(gameState *game.GameState, plies int) (bool, error) {
+	s.nodes++
+	if s.nodes > solveNodeLimit {
+		return false, ErrSolveLimit
+	}
+	if s.nodes%4096 == 0 && s.ctx.Err() != nil {
+		return false, s.ctx.Err()
+	}
+
+	if gameState.GameOver {
+		return gameState.GameWon == s.attacker, nil
+	}
+	if plies == 0 {
+		return false, nil
+	}
+
+	moves := legalMoves(gameState)
+	if gameState.CurrentPlayer == s.attacker {
+		for _, move := range moves {
+			// On the last ply only a move that wins its board can win the game
+			if plies == 1 && !s.winsBoard(gameState, move) {
+				continue
+			}
+			won, err := s.wins(playSolverMove(gameState, move), plies-1)
+			if err != nil || won {
+				return won, err
+			}
+		}
+		return false, nil
+	}
+
+	// The defender's move cannot be the winning one
+	if plies == 1 {
+		return false, nil
+	}
+	for _, move := range moves {
+		won, err := s.wins(playSolverMove(gameState, move), plies-1)
+		if err != nil || !won {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// winsBoard reports whether a move completes a line on its small board
+func (s *solver) winsBoard(gameState *game.GameState, move game.Move) bool {
+	threats := smallBoard(&gameState.BigBoard[move.BigBoardIndex]).threats[move.Player]
+	return threats&(1<<move.SmallBoardIndex) != 0
+}
+
+// playSolverMove returns the position after a legal move. The move history is
+// dropped because the solver never needs it.
+func playSolverMove(gameState *game.GameState, move game.Move) *game.GameState {
+	next := *gameState
+	next.MoveHistory = nil
+	next.PlayMove(move)
+	return &next
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"time"
+)
+
+// SearchStats describes the work done by the last search of an AI player.
+// TTProbes counts the interior nodes looked up in the transposition table
+// and TTHits those whose stored score was used instead of searching them.
+type SearchStats struct {
+	Nodes            int     `json:"nodes"`
+	NodesPerSecond   int     `json:"nodesPerSecond"`
+	Depth            int     `json:"depth"`
+	SelDepth         int     `json:"selDepth"` // Deepest ply reached
+	TimeMs           int64   `json:"timeMs"`
+	TTProbes         int     `json:"ttProbes"`
+	TTHits           int     `json:"ttHits"`
+	TTHitRate        float64 `json:"ttHitRate"`
+	BranchingFactor  float64 `json:"branchingFactor"` // Moves searched per interior node
+	Cutoffs          int     `json:"cutoffs"`
+	FirstMoveCutoffs int     `json:"firstMoveCutoffs"` // Cutoffs by the first move tried, a sign of good move ordering
+
+	interiorNodes int
+	children      int
+	start         time.Time
+}
+
+// SearchStats returns the statistics of the AI player's last search
+func (ai *AIPlayer) SearchStats() SearchStats {
+	stats := ai.stats
+	stats.Nodes = ai.nodes
+
+	elapsed := time.Since(stats.start)
+	if !ai.statsEnd.IsZero() {
+		elapsed = ai.statsEnd.Sub(stats.start)
+	}
+	stats.setRates(elapsed)
+	return stats
+}
+
+// setRates fills in the time and the rates derived from the counters
+func (s *SearchStats) setRates(elapsed time.Duration) {
+	s.TimeMs = elapsed.Milliseconds()
+	if elapsed > 0 {
+		s.NodesPerSecond = int(float64(s.Nodes) / elapsed.Seconds())
+	}
+	if s.TTProbes > 0 {
+		s.TTHitRate = float64(s.TTHits) / float64(s.TTProbes)
+	}
+	if s.interiorNodes > 0 {
+		s.BranchingFactor = float64(s.children) / float64(s.interiorNodes)
+	}
+}
+
+// startStats resets the statistics for a new search. Entries the
+// transposition table holds from earlier searches are left out of it.
+func (ai *AIPlayer) startStats() {
+	ai.stats = SearchStats{start: time.Now()}
+	ai.statsEnd = time.Time{}
+	ai.nodes = 0
+	ai.ttGeneration++
+}
+
+// finishStats stops the search clock
+func (ai *AIPlayer) finishStats() {
+	ai.statsEnd = time.Now()
+}
+
+// countNode records a node at ply plies from the root
+func (ai *AIPlayer) countNode(ply int) {
+	if ply > ai.stats.SelDepth {
+		ai.stats.SelDepth = ply
+	}
+}
+
+// countInterior records an interior node whose moves are searched
+func (ai *AIPlayer) countInterior() {
+	ai.stats.interiorNodes++
+}
+
+// countCutoff records a beta cutoff after trying moves moves
+func (ai *AIPlayer) countCutoff(moves int) {
+	ai.stats.Cutoffs++
+	if moves == 1 {
+		ai.stats.FirstMoveCutoffs++
+	}
+}
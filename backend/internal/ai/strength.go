@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"sort"
+	"t-9/internal/game"
+	"time"
+)
+
+const (
+	MinLevel = 1
+	MaxLevel = 20
+
+	// nodesPerMillisecond converts time limits into node limits for seeded
+	// players. It is roughly the search speed on a laptop.
+	nodesPerMillisecond = 2000
+)
+
+// Strength describes how well a leveled AI plays. Weaker levels search less
+// deeply, add more noise to their scores and more often play a random move.
+type Strength struct {
+	Level         int           `json:"level"`
+	Depth         int           `json:"depth"`
+	TimeLimit     time.Duration `json:"-"`
+	Noise         float64       `json:"noise"` // Standard deviation of the noise added to each move's score
+	BlunderChance float64       `json:"blunderChance"`
+	Rating        int           `json:"rating"` // Measured with cmd/calibrate
+}
+
+// strengthLevels holds the parameters of every level. Depth only rises from
+// one level to the next, and levels of the same depth differ by less noise
+// and fewer blunders. Ratings are those published by `go run ./cmd/calibrate`
+// with its default flags, whose output is kept in cmd/calibrate/ratings.txt:
+// each pair of neighbouring levels plays until its Elo difference is known to
+// within ±20 at 95%, or 2000 games, anchored at level 1 = 400. Calibration
+// uses seeded players, which stop after TimeLimit in milliseconds times
+// nodesPerMillisecond nodes rather than at the time limit, so the run gives
+// the same ratings on any machine and took about two hours, not the days the
+// time limits suggest.
+var strengthLevels = [MaxLevel]Strength{
+	{Level: 1, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 6000, BlunderChance: 1, Rating: 400},
+	{Level: 2, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 6000, BlunderChance: 0.5, Rating: 444},
+	{Level: 3, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 3000, BlunderChance: 0.3, Rating: 500},
+	{Level: 4, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 1500, BlunderChance: 0.2, Rating: 539},
+	{Level: 5, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 800, BlunderChance: 0.1, Rating: 599},
+	{Level: 6, Depth: 1, TimeLimit: 200 * time.Millisecond, Noise: 400, BlunderChance: 0, Rating: 633},
+	{Level: 7, Depth: 2, TimeLimit: 200 * time.Millisecond, Noise: 1500, BlunderChance: 0.08, Rating: 657},
+	{Level: 8, Depth: 2, TimeLimit: 200 * time.Millisecond, Noise: 700, BlunderChance: 0.02, Rating: 717},
+	{Level: 9, Depth: 2, TimeLimit: 200 * time.Millisecond, Noise: 300, BlunderChance: 0, Rating: 747},
+	{Level: 10, Depth: 3, TimeLimit: 200 * time.Millisecond, Noise: 800, BlunderChance: 0.02, Rating: 775},
+	{Level: 11, Depth: 3, TimeLimit: 200 * time.Millisecond, Noise: 400, BlunderChance: 0, Rating: 818},
+	{Level: 12, Depth: 4, TimeLimit: 300 * time.Millisecond, Noise: 400, BlunderChance: 0, Rating: 861},
+	{Level: 13, Depth: 4, TimeLimit: 300 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 895},
+	{Level: 14, Depth: 5, TimeLimit: 400 * time.Millisecond, Noise: 300, BlunderChance: 0, Rating: 943},
+	{Level: 15, Depth: 5, TimeLimit: 400 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 977},
+	{Level: 16, Depth: 6, TimeLimit: 600 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 1041},
+	{Level: 17, Depth: 7, TimeLimit: 1000 * time.Millisecond, Noise: 250, BlunderChance: 0, Rating: 1108},
+	{Level: 18, Depth: 7, TimeLimit: 1000 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 1163},
+	{Level: 19, Depth: 8, TimeLimit: 1500 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 1203},
+	{Level: 20, Depth: 9, TimeLimit: 2500 * time.Millisecond, Noise: 0, BlunderChance: 0, Rating: 1306},
+}
+
+// StrengthLevels returns the parameters of every level, weakest first
+func StrengthLevels() []Strength {
+	levels := make([]Strength, len(strengthLevels))
+	copy(levels, strengthLevels[:])
+	return levels
+}
+
+// StrengthForLevel returns the strength of a level, clamped to 1-20
+func StrengthForLevel(level int) Strength {
+	if level < MinLevel {
+		level = MinLevel
+	}
+	if level > MaxLevel {
+		level = MaxLevel
+	}
+	return strengthLevels[level-1]
+}
+
+// StrengthForRating returns the level whose measured rating is closest to
+// the target rating
+func StrengthForRating(rating int) Strength {
+	best := strengthLevels[0]
+	for _, strength := range strengthLevels {
+		if abs(strength.Rating-rating) < abs(best.Rating-rating) {
+			best = strength
+		}
+	}
+	return best
+}
+
+// NewLeveledAIPlayer creates an AI player that plays at the given strength
+func NewLeveledAIPlayer(strength Strength, player game.Player) *AIPlayer {
+	aiPlayer := NewAIPlayer(Hard, player)
+	aiPlayer.strength = &strength
+	return aiPlayer
+}
+
+// NewSeededLeveledAIPlayer creates a leveled AI player with a fixed random
+// seed, so calibration matches can be replayed
+func NewSeededLeveledAIPlayer(strength Strength, player game.Player, seed int64) *AIPlayer {
+	aiPlayer := NewSeededAIPlayer(Hard, player, seed)
+	aiPlayer.strength = &strength
+	return aiPlayer
+}
+
+// Strength returns the strength of a leveled AI player
+func (ai *AIPlayer) Strength() (Strength, bool) {
+	if ai.strength == nil {
+		return Strength{}, false
+	}
+	return *ai.strength, true
+}
+
+// getLeveledMove searches to the level's depth within its time limit and
+// picks the best move after adding the level's noise to every score, unless
+// it blunders
+func (ai *AIPlayer) getLeveledMove(gameState *game.GameState, moves []game.Move) game.Move {
+	strength := ai.strength
+
+	if ai.rng.Float64() < strength.BlunderChance {
+		return moves[ai.rng.Intn(len(moves))]
+	}
+
+	scores, _, _ := ai.iterativeDeepening(gameState, moves, strength.Depth, strength.TimeLimit, nil)
+
+	bestMove := moves[0]
+	bestScore := math.Inf(-1)
+	for i, move := range moves {
+		score := scores[i] + ai.rng.NormFloat64()*strength.Noise + ai.styleBonus(gameState, move)
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+	}
+
+	return bestMove
+}
+
+// SearchInfo describes a completed iteration of a search. The score is from
+// the point of view of the side to move.
+type SearchInfo struct {
+	Depth   int
+	Score   float64
+	Nodes   int
+	Elapsed time.Duration
+	Line    []game.Move
+}
+
+// Think searches to maxDepth, or until timeLimit runs out when it is set, and
+// returns the best move. report, if not nil, is called after every completed
+// iteration. The AI must play the side to move; its strength settings are
+// ignored. When ctx is cancelled the best move so far is returned, or the
+// context's error if no iteration has completed.
+func (ai *AIPlayer) Think(ctx context.Context, gameState *game.GameState, maxDepth int, timeLimit time.Duration, report func(SearchInfo)) (game.Move, error) {
+	moves := legalMoves(gameState)
+	if len(moves) == 0 {
+		return game.Move{}, nil
+	}
+
+	ai.ctx = ctx
+	defer func() { ai.ctx = nil }()
+	ai.startStats()
+	defer ai.finishStats()
+
+	scores, _, completed := ai.iterativeDeepening(gameState, moves, maxDepth, timeLimit, report)
+	if completed == 0 {
+		return game.Move{}, ctx.Err()
+	}
+
+	best := 0
+	for i := range moves {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	return moves[best], nil
+}
+
+// iterativeDeepening scores every root move at increasing depths until the
+// maximum depth is reached, time runs out or the search is cancelled. It
+// returns the scores and principal variations of the last completed depth.
+// Results from an unfinished iteration are discarded. Seeded players count
+// nodes instead of reading the clock, so their searches are reproducible.
+func (ai *AIPlayer) iterativeDeepening(gameState *game.GameState, moves []game.Move, maxDepth int, timeLimit time.Duration, report func(SearchInfo)) ([]float64, [][]game.Move, int) {
+	start := time.Now()
+	ai.nodes = 0
+	ai.aborted = false
+	defer func() {
+		ai.deadline = time.Time{}
+		ai.nodeLimit = 0
+		ai.aborted = false
+	}()
+
+	order := make([]int, len(moves))
+	for i := range order {
+		order[i] = i
+	}
+
+	scores := make([]float64, len(moves))
+	lines := make([][]game.Move, len(moves))
+	completed := 0
+	for depth := 1; depth <= maxDepth; depth++ {
+		// Unless cancelled, the first iteration always completes so there is
+		// something to play
+		if depth > 1 && timeLimit > 0 {
+			if ai.seeded {
+				ai.nodeLimit = int(timeLimit.Milliseconds()) * nodesPerMillisecond
+			} else {
+				ai.deadline = start.Add(timeLimit)
+			}
+		}
+
+		ai.rootDepth = depth
+		iteration := make([]float64, len(moves))
+		iterationLines := make([][]game.Move, len(moves))
+		for _, i := range order {
+			newState := ai.copyGameState(gameState)
+			newState.PlayMove(moves[i])
+			score, line := ai.search(newState, depth-1, math.Inf(-1), math.Inf(1))
+			if ai.aborted {
+				break
+			}
+			iteration[i] = score
+			iterationLines[i] = append([]game.Move{moves[i]}, line...)
+		}
+
+		if ai.aborted {
+			break
+		}
+		scores = iteration
+		lines = iterationLines
+		completed = depth
+		ai.stats.Depth = depth
+
+		// Search the most promising moves first on the next iteration
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+
+		if report != nil {
+			report(SearchInfo{
+				Depth:   depth,
+				Score:   scores[order[0]],
+				Nodes:   ai.nodes,
+				Elapsed: time.Since(start),
+				Line:    lines[order[0]],
+			})
+		}
+	}
+
+	return scores, lines, completed
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
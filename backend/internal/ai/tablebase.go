@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"math/bits"
+	"t-9/internal/game"
+)
+
+// smallBoardStates is the number of small board states, 3^9
+const smallBoardStates = 19683
+
+// smallBoardEntry is everything the AI needs to know about one small board
+// state. Arrays indexed by player use game.X and game.O.
+type smallBoardEntry struct {
+	winner      game.Player
+	threats     [3]uint16 // Empty cells that complete a line, as a bit mask
+	threatLines [3]uint8  // Lines with two of the player's pieces and an empty cell
+
+	// outcome is the result of the board played out alone as tic-tac-toe
+	// with perfect alternating play, indexed by the player to move. Empty is
+	// a draw.
+	outcome [3]game.Player
+
+	// features are the line and square counts evaluateSmallBoardAdvanced
+	// weighs, from each player's point of view
+	features [3]smallBoardFeatures
+}
+
+// smallBoardFeatures counts the parts of a small board the evaluation weighs.
+// Opponent counts are kept apart because they have their own weights.
+type smallBoardFeatures struct {
+	linesWon         float64 // Own lines of three minus the opponent's
+	twoInRow         float64
+	oneInRow         float64
+	opponentTwoInRow float64
+	opponentOneInRow float64
+	center           float64 // 1 if the center is own, -1 if the opponent's
+	corners          float64 // Own corners minus the opponent's
+	forcedWin        float64 // 1 if the board is won locally whoever moves first, -1 if lost
+}
+
+// smallBoardTable holds every small board state, indexed by smallBoardIndex.
+// It is generated at startup, which takes a few milliseconds.
+var smallBoardTable = generateSmallBoardTable()
+
+// smallBoardIndex returns the table index of a small board, reading the
+// cells as base-3 digits
+func smallBoardIndex(board *game.SmallBoard) int {
+	index := 0
+	for i := 8; i >= 0; i-- {
+		index = index*3 + int(board[i])
+	}
+	return index
+}
+
+// smallBoard looks up a small board in the table
+func smallBoard(board *game.SmallBoard) *smallBoardEntry {
+	return &smallBoardTable[smallBoardIndex(board)]
+}
+
+// generateSmallBoardTable builds the table
+func generateSmallBoardTable() []smallBoardEntry {
+	table := make([]smallBoardEntry, smallBoardStates)
+	for index := range table {
+		table[index] = newSmallBoardEntry(boardFromIndex(index))
+	}
+
+	// Outcomes depend on the positions after each move, so they are solved
+	// with memoisation once every entry has its winner
+	solved := make([][3]bool, smallBoardStates)
+	for index := range table {
+		for _, toMove := range []game.Player{game.X, game.O} {
+			solveLocalOutcome(table, solved, index, toMove)
+		}
+	}
+
+	for index := range table {
+		entry := &table[index]
+		for _, player := range []game.Player{game.X, game.O} {
+			opponent := otherPlayer(player)
+			if entry.outcome[game.X] == player && entry.outcome[game.O] == player {
+				entry.features[player].forcedWin = 1
+			} else if entry.outcome[game.X] == opponent && entry.outcome[game.O] == opponent {
+				entry.features[player].forcedWin = -1
+			}
+		}
+	}
+	return table
+}
+
+// boardFromIndex is the inverse of smallBoardIndex
+func boardFromIndex(index int) game.SmallBoard {
+	var board game.SmallBoard
+	for i := 0; i < 9; i++ {
+		board[i] = game.Player(index % 3)
+		index /= 3
+	}
+	return board
+}
+
+// newSmallBoardEntry scans a board's lines once to fill in an entry, all but
+// its outcome
+func newSmallBoardEntry(board game.SmallBoard) smallBoardEntry {
+	var entry smallBoardEntry
+	wonBy := [3]bool{}
+
+	for _, line := range game.WinningLines {
+		var counts [3]int
+		emptyCell := -1
+		for _, pos := range line {
+			counts[board[pos]]++
+			if board[pos] == game.Empty {
+				emptyCell = pos
+			}
+		}
+
+		for _, player := range []game.Player{game.X, game.O} {
+			opponent := otherPlayer(player)
+			own, theirs := &entry.features[player], &entry.features[opponent]
+			switch {
+			case counts[player] == 3:
+				wonBy[player] = true
+				own.linesWon++
+				theirs.linesWon--
+			case counts[player] == 2 && counts[game.Empty] == 1:
+				entry.threats[player] |= 1 << emptyCell
+				entry.threatLines[player]++
+				own.twoInRow++
+				theirs.opponentTwoInRow++
+			case counts[player] == 1 && counts[game.Empty] == 2:
+				own.oneInRow++
+				theirs.opponentOneInRow++
+			}
+		}
+	}
+
+	for _, player := range []game.Player{game.X, game.O} {
+		features := &entry.features[player]
+		if board[4] == player {
+			features.center = 1
+		} else if board[4] != game.Empty {
+			features.center = -1
+		}
+		for _, corner := range []int{0, 2, 6, 8} {
+			if board[corner] == player {
+				features.corners++
+			} else if board[corner] != game.Empty {
+				features.corners--
+			}
+		}
+	}
+
+	switch {
+	case wonBy[game.X]:
+		entry.winner = game.X
+	case wonBy[game.O]:
+		entry.winner = game.O
+	}
+	return entry
+}
+
+// solveLocalOutcome finds the result of a board played out alone with
+// perfect alternating play
+func solveLocalOutcome(table []smallBoardEntry, solved [][3]bool, index int, toMove game.Player) game.Player {
+	entry := &table[index]
+	if solved[index][toMove] {
+		return entry.outcome[toMove]
+	}
+
+	result := entry.winner
+	if result == game.Empty {
+		board := boardFromIndex(index)
+		opponent := otherPlayer(toMove)
+		best := opponent
+		moved := false
+		power := 1
+		for cell := 0; cell < 9; cell, power = cell+1, power*3 {
+			if board[cell] != game.Empty {
+				continue
+			}
+			moved = true
+			outcome := solveLocalOutcome(table, solved, index+power*int(toMove), opponent)
+			if outcome == toMove {
+				best = toMove
+				break
+			}
+			if outcome == game.Empty {
+				best = game.Empty
+			}
+		}
+		if moved {
+			result = best
+		}
+	}
+
+	entry.outcome[toMove] = result
+	solved[index][toMove] = true
+	return result
+}
+
+// threatCells returns the cells of a threat mask in ascending order
+func threatCells(mask uint16) []int {
+	cells := make([]int, 0, bits.OnesCount16(mask))
+	for mask != 0 {
+		cell := bits.TrailingZeros16(mask)
+		cells = append(cells, cell)
+		mask &= mask - 1
+	}
+	return cells
+}
+
+// otherPlayer returns the opponent of a player
+func otherPlayer(player game.Player) game.Player {
+	if player == game.X {
+		return game.O
+	}
+	return game.X
+}
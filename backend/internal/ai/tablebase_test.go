@@ -0,0 +1,248 @@
+package ai
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"t-9/internal/game"
+)
+
+// The scan functions are the line scans the small board table replaced. The
+// table must agree with them on every state.
+
+// scanEvaluateSmallBoard is evaluateSmallBoardAdvanced before the table
+func scanEvaluateSmallBoard(ai *AIPlayer, board *game.SmallBoard) float64 {
+	score := 0.0
+	opponent := otherPlayer(ai.player)
+
+	for _, line := range game.WinningLines {
+		aiCount, opponentCount, emptyCount := 0, 0, 0
+		for _, pos := range line {
+			switch board[pos] {
+			case ai.player:
+				aiCount++
+			case opponent:
+				opponentCount++
+			case game.Empty:
+				emptyCount++
+			}
+		}
+
+		if aiCount == 3 {
+			score += ai.weights.SmallLineWon
+		} else if aiCount == 2 && emptyCount == 1 && opponentCount == 0 {
+			score += ai.weights.SmallTwoInRow
+		} else if aiCount == 1 && emptyCount == 2 && opponentCount == 0 {
+			score += ai.weights.SmallOneInRow
+		}
+
+		if opponentCount == 3 {
+			score -= ai.weights.SmallLineWon
+		} else if opponentCount == 2 && emptyCount == 1 && aiCount == 0 {
+			score -= ai.weights.SmallOpponentTwoInRow
+		} else if opponentCount == 1 && emptyCount == 2 && aiCount == 0 {
+			score -= ai.weights.SmallOpponentOneInRow
+		}
+	}
+
+	if board[4] == ai.player {
+		score += ai.weights.SmallCenter
+	} else if board[4] == opponent {
+		score -= ai.weights.SmallCenter
+	}
+	for _, corner := range []int{0, 2, 6, 8} {
+		if board[corner] == ai.player {
+			score += ai.weights.SmallCorner
+		} else if board[corner] == opponent {
+			score -= ai.weights.SmallCorner
+		}
+	}
+	return score
+}
+
+// scanWinningCells is winningCells before the table
+func scanWinningCells(board *game.SmallBoard, player game.Player) []int {
+	cells := []int{}
+	seen := [9]bool{}
+	for _, line := range game.WinningLines {
+		playerCount := 0
+		emptyCell := -1
+		for _, pos := range line {
+			if board[pos] == player {
+				playerCount++
+			} else if board[pos] == game.Empty {
+				emptyCell = pos
+			}
+		}
+		if playerCount == 2 && emptyCell != -1 && !seen[emptyCell] {
+			seen[emptyCell] = true
+			cells = append(cells, emptyCell)
+		}
+	}
+	sort.Ints(cells)
+	return cells
+}
+
+// scanThreatLines is countThreatsCreated before the table
+func scanThreatLines(board *game.SmallBoard, player game.Player) int {
+	threats := 0
+	for _, line := range game.WinningLines {
+		playerCount, emptyCount := 0, 0
+		for _, pos := range line {
+			if board[pos] == player {
+				playerCount++
+			} else if board[pos] == game.Empty {
+				emptyCount++
+			}
+		}
+		if playerCount == 2 && emptyCount == 1 {
+			threats++
+		}
+	}
+	return threats
+}
+
+// scanLineWon reports whether a player has three in a row
+func scanLineWon(board *game.SmallBoard, player game.Player) bool {
+	for _, line := range game.WinningLines {
+		if board[line[0]] == player && board[line[1]] == player && board[line[2]] == player {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSmallBoard reads a board written as nine cells of X, O and '.'
+func parseSmallBoard(t testing.TB, cells string) game.SmallBoard {
+	t.Helper()
+	var board game.SmallBoard
+	if len(cells) != 9 {
+		t.Fatalf("board %q does not have nine cells", cells)
+	}
+	for i, cell := range cells {
+		switch cell {
+		case 'X':
+			board[i] = game.X
+		case 'O':
+			board[i] = game.O
+		case '.':
+		default:
+			t.Fatalf("board %q has a bad cell %q", cells, cell)
+		}
+	}
+	return board
+}
+
+func TestSmallBoardTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		board       string
+		winner      game.Player
+		threatsX    []int
+		threatsO    []int
+		threatLines [3]int
+		outcome     [3]game.Player // By the player to move
+	}{
+		{"empty", ".........", game.Empty, []int{}, []int{}, [3]int{}, [3]game.Player{}},
+		{"X row", "XXX.O.O..", game.X, []int{}, []int{}, [3]int{}, [3]game.Player{game.Empty, game.X, game.X}},
+		{"X fork", "X.X.X..OO", game.Empty, []int{1, 6}, []int{6}, [3]int{0, 2, 1}, [3]game.Player{game.Empty, game.X, game.O}},
+		{"blocked", "XOXXOOOXX", game.Empty, []int{}, []int{}, [3]int{}, [3]game.Player{}},
+		{"shared cell", "XX.OO....", game.Empty, []int{2}, []int{5}, [3]int{0, 1, 1}, [3]game.Player{game.Empty, game.X, game.O}},
+		{"both won", "XXXOOO...", game.X, []int{}, []int{}, [3]int{}, [3]game.Player{game.Empty, game.X, game.X}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := parseSmallBoard(t, tt.board)
+			entry := smallBoard(&board)
+			if entry.winner != tt.winner {
+				t.Errorf("winner = %v, want %v", entry.winner, tt.winner)
+			}
+			if got := threatCells(entry.threats[game.X]); !reflect.DeepEqual(got, tt.threatsX) {
+				t.Errorf("X threats = %v, want %v", got, tt.threatsX)
+			}
+			if got := threatCells(entry.threats[game.O]); !reflect.DeepEqual(got, tt.threatsO) {
+				t.Errorf("O threats = %v, want %v", got, tt.threatsO)
+			}
+			for _, player := range []game.Player{game.X, game.O} {
+				if got := int(entry.threatLines[player]); got != tt.threatLines[player] {
+					t.Errorf("threat lines of %v = %d, want %d", player, got, tt.threatLines[player])
+				}
+				if entry.outcome[player] != tt.outcome[player] {
+					t.Errorf("outcome with %v to move = %v, want %v", player, entry.outcome[player], tt.outcome[player])
+				}
+			}
+		})
+	}
+}
+
+func TestSmallBoardTableMatchesScans(t *testing.T) {
+	players := []*AIPlayer{NewSeededAIPlayer(Hard, game.X, 1), NewSeededAIPlayer(Hard, game.O, 1)}
+
+	for index := 0; index < smallBoardStates; index++ {
+		board := boardFromIndex(index)
+		if smallBoardIndex(&board) != index {
+			t.Fatalf("board %v has index %d, want %d", board, smallBoardIndex(&board), index)
+		}
+		entry := smallBoard(&board)
+
+		// A board stops being played once it is won, so no game reaches a
+		// board with lines for both players
+		wonX, wonO := scanLineWon(&board, game.X), scanLineWon(&board, game.O)
+		if !(wonX && wonO) {
+			want := game.Empty
+			if wonX {
+				want = game.X
+			} else if wonO {
+				want = game.O
+			}
+			if entry.winner != want {
+				t.Fatalf("board %v: winner = %v, want %v", board, entry.winner, want)
+			}
+		}
+		for _, ai := range players {
+			if got, want := ai.evaluateSmallBoardAdvanced(&board), scanEvaluateSmallBoard(ai, &board); got != want {
+				t.Fatalf("board %v, %v: evaluation = %v, want %v", board, ai.player, got, want)
+			}
+			if got, want := winningCells(&board, ai.player), scanWinningCells(&board, ai.player); !reflect.DeepEqual(got, want) {
+				t.Fatalf("board %v, %v: winning cells = %v, want %v", board, ai.player, got, want)
+			}
+			if got, want := int(entry.threatLines[ai.player]), scanThreatLines(&board, ai.player); got != want {
+				t.Fatalf("board %v, %v: threat lines = %d, want %d", board, ai.player, got, want)
+			}
+		}
+	}
+}
+
+// benchmarkBoards are the small boards of a midgame position
+func benchmarkBoards(b *testing.B) []game.SmallBoard {
+	boards := []string{"X...O....", "XO.X..O..", ".X.OXO...", "XXOO.X.O.", ".........", "O..XX..O.", "XOXOXO...", "..O.X.X..", "OXXXOO.XO"}
+	parsed := make([]game.SmallBoard, len(boards))
+	for i, board := range boards {
+		parsed[i] = parseSmallBoard(b, board)
+	}
+	return parsed
+}
+
+func BenchmarkEvaluateSmallBoardTable(b *testing.B) {
+	ai := NewSeededAIPlayer(Hard, game.X, 1)
+	boards := benchmarkBoards(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range boards {
+			ai.evaluateSmallBoardAdvanced(&boards[j])
+		}
+	}
+}
+
+func BenchmarkEvaluateSmallBoardScan(b *testing.B) {
+	ai := NewSeededAIPlayer(Hard, game.X, 1)
+	boards := benchmarkBoards(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range boards {
+			scanEvaluateSmallBoard(ai, &boards[j])
+		}
+	}
+}
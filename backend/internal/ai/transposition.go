@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"math/rand"
+	"t-9/internal/game"
+)
+
+// ttSize is the number of entries in the transposition table. It must be a
+// power of two.
+const ttSize = 1 << 16
+
+// ttBound says what a stored score tells about a position's true score
+type ttBound uint8
+
+const (
+	boundExact ttBound = iota
+	boundLower         // The search failed high: the score is at least this
+	boundUpper         // The search failed low: the score is at most this
+)
+
+// ttEntry is a position searched earlier in the same search. Scores are from
+// the AI's point of view, like those of search.
+type ttEntry struct {
+	key        uint64
+	score      float64
+	line       []game.Move // Principal variation from the position
+	generation uint32      // Search that stored the entry
+	depth      int8
+	bound      ttBound
+	move       uint8 // Best move found, as big board * 9 + small board
+}
+
+// zobrist holds random keys for every cell owner, active board and side to
+// move. The seed is fixed so keys are the same on every run.
+var zobrist = func() (keys struct {
+	cells  [81][3]uint64
+	active [10]uint64
+	side   uint64
+}) {
+	rng := rand.New(rand.NewSource(9))
+	for i := range keys.cells {
+		for j := range keys.cells[i] {
+			keys.cells[i][j] = rng.Uint64()
+		}
+	}
+	for i := range keys.active {
+		keys.active[i] = rng.Uint64()
+	}
+	keys.side = rng.Uint64()
+	return keys
+}()
+
+// positionKey hashes a position
+func positionKey(gameState *game.GameState) uint64 {
+	var key uint64
+	for big := 0; big < 9; big++ {
+		for small := 0; small < 9; small++ {
+			if owner := gameState.BigBoard[big][small]; owner != game.Empty {
+				key ^= zobrist.cells[big*9+small][owner]
+			}
+		}
+	}
+	key ^= zobrist.active[gameState.ActiveBoard+1]
+	if gameState.CurrentPlayer == game.O {
+		key ^= zobrist.side
+	}
+	return key
+}
+
+// probeTT looks a position up before it is searched depth plies deep. It
+// returns the entry stored for the position in this search, if any, and
+// whether its score settles the position within alpha and beta so the search
+// can be skipped.
+func (ai *AIPlayer) probeTT(key uint64, depth int, alpha, beta float64) (*ttEntry, bool) {
+	ai.stats.TTProbes++
+	if ai.tt == nil {
+		return nil, false
+	}
+
+	entry := &ai.tt[key&(ttSize-1)]
+	if entry.key != key || entry.generation != ai.ttGeneration {
+		return nil, false
+	}
+	if int(entry.depth) >= depth &&
+		(entry.bound == boundExact ||
+			entry.bound == boundLower && entry.score >= beta ||
+			entry.bound == boundUpper && entry.score <= alpha) {
+		ai.stats.TTHits++
+		return entry, true
+	}
+	return entry, false
+}
+
+// storeTT records the result of searching a position depth plies deep. A
+// deeper entry for another position of the same search is kept, and nothing
+// from an aborted search is stored.
+func (ai *AIPlayer) storeTT(key uint64, depth int, score float64, bound ttBound, move game.Move, line []game.Move) {
+	if ai.aborted {
+		return
+	}
+	if ai.tt == nil {
+		ai.tt = make([]ttEntry, ttSize)
+	}
+
+	entry := &ai.tt[key&(ttSize-1)]
+	if entry.generation == ai.ttGeneration && entry.key != key && int(entry.depth) > depth {
+		return
+	}
+	*entry = ttEntry{
+		key:        key,
+		score:      score,
+		line:       line,
+		generation: ai.ttGeneration,
+		depth:      int8(depth),
+		bound:      bound,
+		move:       uint8(move.BigBoardIndex*9 + move.SmallBoardIndex),
+	}
+}
+
+// hashMoveFirst moves the best move of a stored entry to the front, keeping
+// the order of the others
+func hashMoveFirst(moves []game.Move, entry *ttEntry) {
+	if entry == nil {
+		return
+	}
+	for i, move := range moves {
+		if uint8(move.BigBoardIndex*9+move.SmallBoardIndex) == entry.move {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = move
+			return
+		}
+	}
+}
+
+// moveFromCell is the move of player on a cell stored as big board * 9 +
+// small board
+func moveFromCell(cell uint8, player game.Player) game.Move {
+	return game.Move{BigBoardIndex: int(cell) / 9, SmallBoardIndex: int(cell) % 9, Player: player}
+}
+
+// searchBound classifies the best score of a node searched with the window
+// alpha to beta. A node that was cut off only has a bound on its score, as
+// does one where every move fell outside the window.
+func searchBound(maximizing, cutoff bool, bestScore, alpha, beta float64) ttBound {
+	switch {
+	case cutoff && maximizing, !cutoff && bestScore >= beta:
+		return boundLower
+	case cutoff, bestScore <= alpha:
+		return boundUpper
+	default:
+		return boundExact
+	}
+}
@@ -0,0 +1,154 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"t-9/internal/game"
+)
+
+// plainSearch is search without the transposition table
+func plainSearch(ai *AIPlayer, gameState *game.GameState, depth int, alpha, beta float64) float64 {
+	if depth == 0 || gameState.GameOver {
+		return ai.evaluate(gameState)
+	}
+	moves := legalMoves(gameState)
+	if len(moves) == 0 {
+		return ai.evaluate(gameState)
+	}
+
+	maximizing := gameState.CurrentPlayer == ai.player
+	bestScore := math.Inf(1)
+	if maximizing {
+		bestScore = math.Inf(-1)
+	}
+	for _, move := range moves {
+		newState := ai.copyGameState(gameState)
+		newState.PlayMove(move)
+		score := plainSearch(ai, newState, depth-1, alpha, beta)
+		if maximizing {
+			bestScore = math.Max(bestScore, score)
+			alpha = math.Max(alpha, score)
+		} else {
+			bestScore = math.Min(bestScore, score)
+			beta = math.Min(beta, score)
+		}
+		if beta <= alpha {
+			break
+		}
+	}
+	return bestScore
+}
+
+// The table must not change the result of any search. Each move is searched
+// to increasing depths, as iterative deepening does, and with several windows
+// around its true score, all sharing the table, so entries stored by one
+// search are met by the others. A search that fails low or high must return a
+// true bound, and one whose window holds the true score must return it
+// exactly.
+func TestTranspositionTableKeepsScores(t *testing.T) {
+	const maxDepth = 6
+	windows := []struct{ alpha, beta float64 }{ // Around the true score
+		{math.Inf(-1), math.Inf(1)},
+		{-100, -10},
+		{10, 100},
+		{-50, 0},
+		{0, 50},
+		{-1, 1},
+	}
+	hits := 0
+
+	for _, notation := range benchPositions {
+		state, err := game.ParseNotation(notation)
+		if err != nil {
+			t.Fatalf("position %q: %v", notation, err)
+		}
+		player := NewAIPlayer(Hard, state.CurrentPlayer)
+		player.startStats()
+
+		for depth := 2; depth <= maxDepth; depth++ {
+			player.rootDepth = depth
+			for _, move := range legalMoves(state) {
+				newState := player.copyGameState(state)
+				newState.PlayMove(move)
+				want := plainSearch(player, newState, depth-1, math.Inf(-1), math.Inf(1))
+
+				for _, window := range windows {
+					alpha, beta := want+window.alpha, want+window.beta
+					got, line := player.search(newState, depth-1, alpha, beta)
+					if (got <= alpha && want > got) || (got >= beta && want < got) || (alpha < want && want < beta && got != want) {
+						t.Errorf("position %q, depth %d, move %s, window %v to %v: score = %v, true score %v",
+							notation, depth, game.FormatMove(move), alpha, beta, got, want)
+					}
+					if len(line) == 0 && !newState.GameOver {
+						t.Errorf("position %q, depth %d, move %s: no principal variation", notation, depth, game.FormatMove(move))
+					}
+				}
+			}
+		}
+		hits += player.SearchStats().TTHits
+		checkEntries(t, player, state, maxDepth-2)
+	}
+
+	if hits == 0 {
+		t.Error("no position was answered from the table")
+	}
+}
+
+// checkEntries checks that the table's entry for every position up to plies
+// moves from a position holds the position's true score, or a true bound on
+// it
+func checkEntries(t *testing.T, player *AIPlayer, gameState *game.GameState, plies int) {
+	t.Helper()
+	checked := map[uint64]bool{}
+	var walk func(position *game.GameState, ply int)
+	walk = func(position *game.GameState, ply int) {
+		key := positionKey(position)
+		if entry := &player.tt[key&(ttSize-1)]; !checked[key] && entry.key == key && entry.generation == player.ttGeneration {
+			checked[key] = true
+			want := plainSearch(player, position, int(entry.depth), math.Inf(-1), math.Inf(1))
+			if entry.bound == boundExact && entry.score != want ||
+				entry.bound == boundLower && want < entry.score ||
+				entry.bound == boundUpper && want > entry.score {
+				t.Errorf("position %q, depth %d: entry scores %v with bound %d, true score %v",
+					position.Notation(), entry.depth, entry.score, entry.bound, want)
+			}
+		}
+		if ply == plies {
+			return
+		}
+		for _, move := range legalMoves(position) {
+			child := player.copyGameState(position)
+			child.PlayMove(move)
+			walk(child, ply+1)
+		}
+	}
+	walk(gameState, 0)
+	if len(checked) == 0 {
+		t.Errorf("position %q: no entries found", gameState.Notation())
+	}
+}
+
+func TestTranspositionTableStats(t *testing.T) {
+	player := NewAIPlayer(Hard, game.X)
+	if _, err := player.Think(context.Background(), game.NewGame(), 6, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	stats := player.SearchStats()
+	if stats.TTProbes == 0 || stats.TTHits == 0 {
+		t.Fatalf("probes = %d, hits = %d; want both above 0", stats.TTProbes, stats.TTHits)
+	}
+	if want := float64(stats.TTHits) / float64(stats.TTProbes); stats.TTHitRate != want {
+		t.Errorf("hit rate = %v, want %v", stats.TTHitRate, want)
+	}
+
+	// A new search does not use the entries of the last one, so it does the
+	// same work again
+	if _, err := player.Think(context.Background(), game.NewGame(), 6, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if again := player.SearchStats(); again.Nodes != stats.Nodes || again.TTHits != stats.TTHits {
+		t.Errorf("repeated search: %d nodes and %d hits, want %d and %d", again.Nodes, again.TTHits, stats.Nodes, stats.TTHits)
+	}
+}
@@ -0,0 +1,330 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"t-9/internal/game"
+)
+
+// Weights are the tunable constants of the handwritten evaluation. They are
+// stored as JSON, one field per weight, so the AI can be changed and measured
+// without a code edit. Fields missing from a file keep their default value.
+//
+// A weight's tune tag says how tuners may move it, see Ranges. Weights tagged
+// "-" are not tuned.
+type Weights struct {
+	Name string `json:"name,omitempty"`
+
+	// Big board control, used by evaluatePosition
+	BoardWon            float64 `json:"boardWon"`
+	CenterBoardWon      float64 `json:"centerBoardWon"`
+	CornerBoardWon      float64 `json:"cornerBoardWon"`
+	CenterBoardBonus    float64 `json:"centerBoardBonus"`
+	CornerBoardBonus    float64 `json:"cornerBoardBonus"`
+	BigBoardThreat      float64 `json:"bigBoardThreat"` // Multiplies the big board threat count
+	BigTwoInRow         float64 `json:"bigTwoInRow"`
+	BigOpponentTwoInRow float64 `json:"bigOpponentTwoInRow"`
+	BigOneInRow         float64 `json:"bigOneInRow"`
+	BigOpponentOneInRow float64 `json:"bigOpponentOneInRow"`
+	SmallBoard          float64 `json:"smallBoard"` // Multiplies small board scores
+	CenterSmallBoard    float64 `json:"centerSmallBoard"`
+	CornerSmallBoard    float64 `json:"cornerSmallBoard"`
+	Anarchy             float64 `json:"anarchy"`
+	ForcedAnarchy       float64 `json:"forcedAnarchy"`
+	ActiveBoardControl  float64 `json:"activeBoardControl"`
+
+	// Small board lines, used by evaluateSmallBoardAdvanced
+	SmallLineWon          float64 `json:"smallLineWon"`
+	SmallTwoInRow         float64 `json:"smallTwoInRow"`
+	SmallOneInRow         float64 `json:"smallOneInRow"`
+	SmallOpponentTwoInRow float64 `json:"smallOpponentTwoInRow"`
+	SmallOpponentOneInRow float64 `json:"smallOpponentOneInRow"`
+	SmallCenter           float64 `json:"smallCenter"`
+	SmallCorner           float64 `json:"smallCorner"`
+	SmallForcedWin        float64 `json:"smallForcedWin" tune:"unit=20"` // Board won with perfect local play whoever moves first
+
+	// Move heuristics, used by evaluateMove
+	MoveBlockWin       float64 `json:"moveBlockWin"`
+	MoveWinBoard       float64 `json:"moveWinBoard"`
+	MoveBlockBoard     float64 `json:"moveBlockBoard"`
+	MoveSendToWonBoard float64 `json:"moveSendToWonBoard"`
+	MoveCenterBoard    float64 `json:"moveCenterBoard"`
+	MoveThreat         float64 `json:"moveThreat"`
+	MoveFork           float64 `json:"moveFork"`
+	MoveAnarchy        float64 `json:"moveAnarchy"`
+	MoveSendingTarget  float64 `json:"moveSendingTarget"`                   // Multiplies evaluateSendingTarget
+	MoveChain          float64 `json:"moveChain"`                           // Multiplies evaluateChainStrategy
+	MoveSacrifice      float64 `json:"moveSacrifice"`                       // Multiplies evaluateSacrificeStrategy
+	MoveDangerous      float64 `json:"moveDangerous" tune:"unit=100,min=0"` // Penalty for moves isMoveDangerous flags
+	MoveStyle          float64 `json:"moveStyle" tune:"-"`                  // Share of evaluateMove added to leveled search scores, set by personalities
+
+	// Where a move sends the opponent, used by evaluateSendingTarget
+	SendOpponentAhead  float64 `json:"sendOpponentAhead"`  // Penalty when the opponent has more pieces there
+	SendOpponentThreat float64 `json:"sendOpponentThreat"` // Penalty per line the opponent is one cell from completing there
+	SendOwnBoard       float64 `json:"sendOwnBoard"`       // Board where the AI has more pieces and a threat
+	SendCenterOwned    float64 `json:"sendCenterOwned"`    // Center board holding only the AI's pieces
+	SendCenterLost     float64 `json:"sendCenterLost"`     // Penalty when the opponent has more pieces on the center board
+	SendOpponentCanWin float64 `json:"sendOpponentCanWin"` // Penalty when the opponent can win the board at once
+
+	// The cell played, used by evaluateCenterMove and evaluateNonCenterMove
+	CellCenterFork     float64 `json:"cellCenterFork"` // Center cell making two threats
+	CellCenterThreat   float64 `json:"cellCenterThreat"`
+	CellCenter         float64 `json:"cellCenter"`
+	CellCenterToCenter float64 `json:"cellCenterToCenter"` // Penalty for a center cell sending the opponent to a center board it leads on
+	CellCornerBlock    float64 `json:"cellCornerBlock"`    // Corner cell blocking an opponent threat
+	CellCorner         float64 `json:"cellCorner"`
+	CellEdgeBlock      float64 `json:"cellEdgeBlock"`
+	CellEdge           float64 `json:"cellEdge"`
+
+	// The opponent's replies, used by evaluateChainStrategy
+	ChainFreeMove  float64 `json:"chainFreeMove"`  // Penalty for sending the opponent to a finished board
+	ChainTrapped   float64 `json:"chainTrapped"`   // The opponent has no move on the board it is sent to
+	ChainFreeReply float64 `json:"chainFreeReply"` // Every reply sends the AI to a finished board
+	ChainAdvantage float64 `json:"chainAdvantage"` // Multiplies the AI's lead on the board the opponent's worst reply sends it to
+
+	// Giving up a board, used by evaluateSacrificeStrategy
+	SacrificeGain       float64 `json:"sacrificeGain"`       // Sending score that makes giving up a board worth it
+	SacrificeCenterGain float64 `json:"sacrificeCenterGain"` // Sending score that makes giving up the center board worth it
+	Sacrifice           float64 `json:"sacrifice"`           // A sacrifice worth it for where it sends the opponent
+	SacrificeThreat     float64 `json:"sacrificeThreat"`     // A sacrifice that still makes a threat on the board
+
+	// Once five boards are decided, used by evaluateEndgame
+	EndgameBigThreat float64 `json:"endgameBigThreat"` // Multiplies the big board threat count
+	EndgameTwoInRow  float64 `json:"endgameTwoInRow"`  // Big board line with two boards won and the third open
+	EndgameBlock     float64 `json:"endgameBlock"`     // Closing a big board line the opponent was one board from
+}
+
+// defaultWeights are the hand-picked values the evaluation started with
+var defaultWeights = Weights{
+	Name: "default",
+
+	BoardWon:            500,
+	CenterBoardWon:      800,
+	CornerBoardWon:      600,
+	CenterBoardBonus:    200,
+	CornerBoardBonus:    100,
+	BigBoardThreat:      300,
+	BigTwoInRow:         2.0,
+	BigOpponentTwoInRow: 2.5,
+	BigOneInRow:         0.3,
+	BigOpponentOneInRow: 0.3,
+	SmallBoard:          20,
+	CenterSmallBoard:    40,
+	CornerSmallBoard:    30,
+	Anarchy:             50,
+	ForcedAnarchy:       100,
+	ActiveBoardControl:  30,
+
+	SmallLineWon:          100,
+	SmallTwoInRow:         25,
+	SmallOneInRow:         3,
+	SmallOpponentTwoInRow: 30,
+	SmallOpponentOneInRow: 3,
+	SmallCenter:           8,
+	SmallCorner:           3,
+	SmallForcedWin:        0, // Measured no gain at level 6, left for the tuners
+
+	MoveBlockWin:       5000,
+	MoveWinBoard:       1000,
+	MoveBlockBoard:     800,
+	MoveSendToWonBoard: 800,
+	MoveCenterBoard:    80,
+	MoveThreat:         30,
+	MoveFork:           100,
+	MoveAnarchy:        150,
+	MoveSendingTarget:  1,
+	MoveChain:          1,
+	MoveSacrifice:      1,
+
+	SendOpponentAhead:  300,
+	SendOpponentThreat: 400,
+	SendOwnBoard:       200,
+	SendCenterOwned:    150,
+	SendCenterLost:     200,
+	SendOpponentCanWin: 600,
+
+	CellCenterFork:     100,
+	CellCenterThreat:   40,
+	CellCenter:         10,
+	CellCenterToCenter: 60,
+	CellCornerBlock:    40,
+	CellCorner:         15,
+	CellEdgeBlock:      35,
+	CellEdge:           8,
+
+	ChainFreeMove:  50,
+	ChainTrapped:   100,
+	ChainFreeReply: 24,
+	ChainAdvantage: 6,
+
+	SacrificeGain:       50,
+	SacrificeCenterGain: 100,
+	Sacrifice:           50,
+	SacrificeThreat:     30,
+
+	EndgameBigThreat: 200,
+	EndgameTwoInRow:  800,
+	EndgameBlock:     1000,
+}
+
+// DefaultWeights returns the weights new AI players start with
+func DefaultWeights() Weights {
+	return defaultWeights
+}
+
+// SetDefaultWeights changes the weights of AI players created afterwards. It
+// is meant to be called once at startup.
+func SetDefaultWeights(weights Weights) {
+	defaultWeights = weights
+}
+
+// SetWeights changes the evaluation weights of an AI player
+func (ai *AIPlayer) SetWeights(weights Weights) {
+	ai.weights = weights
+}
+
+// Weights returns the evaluation weights of an AI player
+func (ai *AIPlayer) Weights() Weights {
+	return ai.weights
+}
+
+// EvaluateWithWeights scores a position from player's point of view with the
+// handwritten evaluation and the given weights
+func EvaluateWithWeights(gameState *game.GameState, player game.Player, weights Weights) float64 {
+	evaluator := &AIPlayer{player: player, weights: weights}
+	return evaluator.evaluatePosition(gameState)
+}
+
+// LoadWeights reads weights from JSON. Unknown fields are rejected so a typo
+// cannot silently leave a weight at its default.
+func LoadWeights(r io.Reader) (Weights, error) {
+	weights := DefaultWeights()
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&weights); err != nil {
+		return Weights{}, fmt.Errorf("invalid weights: %w", err)
+	}
+	return weights, nil
+}
+
+// LoadWeightsFile reads weights from a JSON file
+func LoadWeightsFile(path string) (Weights, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Weights{}, err
+	}
+	defer file.Close()
+	return LoadWeights(file)
+}
+
+// Save writes weights as indented JSON
+func (w Weights) Save(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(w)
+}
+
+// tunable reports whether a field of Weights is a tunable weight
+func tunable(field reflect.StructField) bool {
+	return field.Type.Kind() == reflect.Float64 && field.Tag.Get("tune") != "-"
+}
+
+// WeightNames returns the JSON name of every tunable weight, in the order
+// used by Vector and SetVector
+func WeightNames() []string {
+	var names []string
+	t := reflect.TypeOf(Weights{})
+	for i := 0; i < t.NumField(); i++ {
+		if tunable(t.Field(i)) {
+			names = append(names, t.Field(i).Tag.Get("json"))
+		}
+	}
+	return names
+}
+
+// Vector returns the tunable weights as a slice, for tuners
+func (w Weights) Vector() []float64 {
+	var values []float64
+	v := reflect.ValueOf(w)
+	for i := 0; i < v.NumField(); i++ {
+		if tunable(v.Type().Field(i)) {
+			values = append(values, v.Field(i).Float())
+		}
+	}
+	return values
+}
+
+// SetVector sets the tunable weights from a slice made by Vector
+func (w *Weights) SetVector(values []float64) {
+	v := reflect.ValueOf(w).Elem()
+	next := 0
+	for i := 0; i < v.NumField() && next < len(values); i++ {
+		if tunable(v.Type().Field(i)) {
+			v.Field(i).SetFloat(values[next])
+			next++
+		}
+	}
+}
+
+// WeightRange is how a tuner may move a weight: in steps sized relative to
+// Unit, and never below Min or above Max
+type WeightRange struct {
+	Unit float64
+	Min  float64
+	Max  float64
+}
+
+// Ranges returns the range of every tunable weight, in Vector order. A weight
+// moves in units of its own size and keeps its sign, unless its tune tag sets
+// a unit, a min or a max, as in tune:"unit=100,min=0". A weight of zero
+// without a unit in its tag has a unit of zero, so it is not tuned.
+func (w Weights) Ranges() []WeightRange {
+	var ranges []WeightRange
+	v := reflect.ValueOf(w)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !tunable(field) {
+			continue
+		}
+
+		value := v.Field(i).Float()
+		r := WeightRange{Unit: math.Abs(value), Min: math.Inf(-1), Max: math.Inf(1)}
+		if value > 0 {
+			r.Min = 0
+		} else if value < 0 {
+			r.Max = 0
+		}
+		if tag := field.Tag.Get("tune"); tag != "" {
+			for _, option := range strings.Split(tag, ",") {
+				key, text, _ := strings.Cut(option, "=")
+				number, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					panic(fmt.Sprintf("weights: bad tune tag on %s: %q", field.Name, tag))
+				}
+				switch key {
+				case "unit":
+					r.Unit = number
+				case "min":
+					r.Min = number
+				case "max":
+					r.Max = number
+				default:
+					panic(fmt.Sprintf("weights: bad tune tag on %s: %q", field.Name, tag))
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// Clamp limits a value to the range
+func (r WeightRange) Clamp(value float64) float64 {
+	return math.Max(r.Min, math.Min(r.Max, value))
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"t-9/internal/logging"
+	"t-9/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// analysisRequest is the body accepted by the analysis endpoint. Exactly one
+// of Notation or Game must be set.
+type analysisRequest struct {
+	Notation string          `json:"notation"`
+	Game     *game.GameState `json:"game"`
+	Depth    int             `json:"depth"`
+}
+
+// AnalyzePosition evaluates every legal move of an arbitrary position. The
+// search runs as a job on the AI scheduler.
+func AnalyzePosition(jobs *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request analysisRequest
+		if err := bindValidJSON(c, "AnalysisRequest", &request); err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+			return
+		}
+
+		var position *game.GameState
+		switch {
+		case request.Notation != "" && request.Game != nil:
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("Provide either notation or game, not both"))
+			return
+		case request.Notation != "":
+			parsed, err := game.ParseNotation(request.Notation)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+				return
+			}
+			position = parsed
+		case request.Game != nil:
+			if err := request.Game.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid game state: "+err.Error()))
+				return
+			}
+			position = request.Game
+		default:
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("Either notation or game is required"))
+			return
+		}
+
+		client := c.ClientIP()
+		job, err := jobs.Submit(client, func(ctx context.Context) (interface{}, error) {
+			analysis, err := ai.Analyze(ctx, position, request.Depth)
+			if err != nil {
+				return nil, err
+			}
+			logging.DefaultLogger.Info("Position analyzed", map[string]interface{}{
+				"position": analysis.Position,
+				"nodes":    analysis.Stats.Nodes,
+				"timeMs":   analysis.Stats.TimeMs,
+				"clientIP": client,
+			})
+			return newJobResponse(http.StatusOK, analysis), nil
+		})
+		if err != nil {
+			respondWithSchedulerError(c, "Position analysis", err)
+			return
+		}
+		awaitJob(c, job, "Position analysis")
+	}
+}
+
+// AnalyzeGame evaluates the current position of an existing game. The search
+// runs as a job on the AI scheduler.
+func (gm *GameManager) AnalyzeGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	depth := 0
+	if value := c.Query("depth"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("depth must be a number"))
+			return
+		}
+		depth = parsed
+	}
+
+	position, _, err := gm.games.Get(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Game"))
+		return
+	}
+
+	job, err := gm.jobs.Submit(c.ClientIP(), func(ctx context.Context) (interface{}, error) {
+		analysis, err := ai.Analyze(ctx, position, depth)
+		if err != nil {
+			return nil, err
+		}
+		logSearchStats("Game analyzed", gameID, analysis.Stats)
+		return newJobResponse(http.StatusOK, analysis), nil
+	})
+	if err != nil {
+		respondWithSchedulerError(c, "Game analysis", err)
+		return
+	}
+	awaitJob(c, job, "Game analysis")
+}
+
+// logSearchCancelled records a search abandoned because the client went away.
+// Nothing is written since nobody is left to read it.
+func logSearchCancelled(c *gin.Context, what string, err error) {
+	logging.DefaultLogger.Info(what+" cancelled", map[string]interface{}{
+		"reason":   err.Error(),
+		"clientIP": c.ClientIP(),
+	})
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"t-9/internal/config"
+	"t-9/internal/game"
+	"t-9/internal/render"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// boardFormats are the image formats a board can be rendered in
+var boardFormats = map[string]struct {
+	contentType string
+	render      func(w *bytes.Buffer, state *game.GameState, options render.Options) error
+}{
+	"svg": {"image/svg+xml", func(w *bytes.Buffer, state *game.GameState, options render.Options) error {
+		return render.SVG(w, state, options)
+	}},
+	"png": {"image/png", func(w *bytes.Buffer, state *game.GameState, options render.Options) error {
+		return render.PNG(w, state, options)
+	}},
+}
+
+// boardRequest reads the position and drawing options of a board image
+// request: ply, the current position unless given, size and theme. It
+// reports whether the position is an earlier one, which can no longer change.
+func boardRequest(c *gin.Context, gameState *game.GameState) (*game.GameState, render.Options, bool, error) {
+	options := render.Options{Theme: c.Query("theme")}
+	if value := c.Query("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, options, false, fmt.Errorf("size must be a number of pixels")
+		}
+		options.Size = size
+	}
+	if err := options.Check(); err != nil {
+		return nil, options, false, err
+	}
+
+	value := c.Query("ply")
+	if value == "" {
+		return gameState, options, false, nil
+	}
+	ply, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, options, false, fmt.Errorf("ply must be a number of moves")
+	}
+	position, err := gameState.PositionAt(ply)
+	return position, options, ply < len(gameState.MoveHistory), err
+}
+
+// RenderBoard draws a game's current or an earlier position as an image
+// in format, svg or png. It highlights the boards open for the next move,
+// the last move, won boards and the winning line.
+func RenderBoard(games *service.GameService, format string) gin.HandlerFunc {
+	output := boardFormats[format]
+	return func(c *gin.Context) {
+		gameState, summary, err := games.Get(c.Param("id"))
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+		position, options, historical, err := boardRequest(c, gameState)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+
+		// The current position is drawn the same until the game changes,
+		// and an earlier position never changes
+		etag := gameETag(summary)
+		if historical {
+			etag = `"` + strconv.Itoa(len(position.MoveHistory)) + `"`
+			c.Header("Cache-Control", "public, max-age=86400")
+		} else {
+			c.Header("Cache-Control", "no-cache")
+		}
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		var image bytes.Buffer
+		if err := output.render(&image, position, options); err != nil {
+			c.JSON(http.StatusInternalServerError, NewInternalError("Failed to render board").WithDetails(err.Error()))
+			return
+		}
+		c.Data(http.StatusOK, output.contentType, image.Bytes())
+	}
+}
+
+// sharePage is the page shared game links point at. Chat apps and issue
+// trackers read its Open Graph tags to show the board as a preview.
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="{{.Size}}">
+<meta property="og:image:height" content="{{.Size}}">
+<meta name="twitter:card" content="summary_large_image">
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Description}}</p>
+<img src="{{.Image}}" width="{{.Size}}" height="{{.Size}}" alt="Board after {{.MoveCount}} moves">
+</body>
+</html>
+`))
+
+// sharePreviewSize is the size of the board shown in link previews
+const sharePreviewSize = 600
+
+// ShareGame serves a page describing a game with Open Graph tags, so links
+// to it show the board when pasted. The ply and theme parameters are passed
+// on to the board image.
+func ShareGame(games *service.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+		gameState, summary, err := games.Get(gameID)
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+		position, options, _, err := boardRequest(c, gameState)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+
+		// The image is linked at the configured address rather than the
+		// request's Host, which clients choose and crawlers may not reach
+		image := fmt.Sprintf("%s/api/v1/games/%s/board.png?size=%d&ply=%d",
+			config.DefaultConfig.Server.PublicURL, url.PathEscape(gameID), sharePreviewSize, len(position.MoveHistory))
+		if options.Theme != "" {
+			image += "&theme=" + options.Theme
+		}
+
+		var page bytes.Buffer
+		err = sharePage.Execute(&page, map[string]interface{}{
+			"Title":       shareTitle(summary),
+			"Description": shareDescription(position, summary),
+			"Image":       image,
+			"Size":        sharePreviewSize,
+			"MoveCount":   len(position.MoveHistory),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, NewInternalError("Failed to render page").WithDetails(err.Error()))
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	}
+}
+
+// shareTitle names a game by its players
+func shareTitle(summary service.Summary) string {
+	x, o := summary.Players.X, summary.Players.O
+	if x == "" {
+		x = "X"
+	}
+	if o == "" {
+		o = "O"
+	}
+	return fmt.Sprintf("%s vs %s - Ultimate Tic-Tac-Toe", x, o)
+}
+
+// shareDescription describes the position shown
+func shareDescription(position *game.GameState, summary service.Summary) string {
+	moves := len(position.MoveHistory)
+	switch {
+	case moves < summary.MoveCount:
+		return fmt.Sprintf("Position after %d of %d moves", moves, summary.MoveCount)
+	case summary.Result == "draw":
+		return fmt.Sprintf("Drawn after %d moves", moves)
+	case summary.Result != "":
+		return fmt.Sprintf("%s won after %d moves", map[string]string{"x": "X", "o": "O"}[summary.Result], moves)
+	case summary.Status == service.StateAbandoned:
+		return fmt.Sprintf("Abandoned after %d moves", moves)
+	default:
+		return fmt.Sprintf("%s to move after %d moves", position.CurrentPlayer, moves)
+	}
+}
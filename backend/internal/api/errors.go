@@ -13,6 +13,8 @@ const (
 	ErrorTypeGameLogic     ErrorType = "game_logic"
 	ErrorTypeInternal      ErrorType = "internal"
 	ErrorTypeUnauthorized  ErrorType = "unauthorized"
+	ErrorTypeOverloaded    ErrorType = "overloaded"
+	ErrorTypeConflict      ErrorType = "conflict"
 )
 
 // APIError represents a structured API error
@@ -57,6 +59,34 @@ func NewInternalError(message string) *APIError {
 	return NewAPIError(ErrorTypeInternal, message, http.StatusInternalServerError)
 }
 
+// NewEngineError creates an error for an external engine that failed
+func NewEngineError(message string) *APIError {
+	return NewAPIError(ErrorTypeInternal, message, http.StatusBadGateway)
+}
+
+// NewUnauthorizedError creates an error for a request without a valid token
+func NewUnauthorizedError(message string) *APIError {
+	return NewAPIError(ErrorTypeUnauthorized, message, http.StatusUnauthorized)
+}
+
+// NewForbiddenError creates an error for a token that does not allow the
+// request
+func NewForbiddenError(message string) *APIError {
+	return NewAPIError(ErrorTypeUnauthorized, message, http.StatusForbidden)
+}
+
+// NewOverloadedError creates an error for a request turned away because the
+// server is busy. The code is 429 or 503.
+func NewOverloadedError(message string, code int) *APIError {
+	return NewAPIError(ErrorTypeOverloaded, message, code)
+}
+
+// NewConflictError creates an error for a request made against a game that
+// has changed since the client last saw it
+func NewConflictError(message string) *APIError {
+	return NewAPIError(ErrorTypeConflict, message, http.StatusConflict)
+}
+
 // WithDetails adds details to an API error
 func (e *APIError) WithDetails(details string) *APIError {
 	e.Details = details
@@ -1,10 +1,15 @@
 package api
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,53 +17,132 @@ import (
 	"t-9/internal/config"
 	"t-9/internal/game"
 	"t-9/internal/logging"
+	"t-9/internal/protocol"
+	"t-9/internal/puzzle"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
 	"t-9/internal/ws"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GameManager handles game sessions
+// GameManager handles the REST transport for games held by the game service,
+// and the AI sessions playing in them
 type GameManager struct {
-	games map[string]*game.GameState
-	mu    sync.RWMutex
+	games       *service.GameService
+	opponents   map[string]*aiOpponent
+	seats       map[seatKey]*aiSeat
+	replies     map[string]pendingReply
+	idempotency map[idempotencyKey]*idempotentRequest // Responses kept for retries
+	jobs        *scheduler.Scheduler
+	mu          sync.RWMutex
 }
 
-func NewGameManager() *GameManager {
-	return &GameManager{
-		games: make(map[string]*game.GameState),
+// seatKey identifies one side of a game
+type seatKey struct {
+	gameID string
+	player game.Player
+}
+
+// aiSeat is the built-in AI playing one side of a game. It is kept between
+// moves so it can ponder on the opponent's time.
+type aiSeat struct {
+	settings string // The request settings the session was created for
+	session  *ai.Session
+}
+
+func NewGameManager(games *service.GameService, jobs *scheduler.Scheduler) *GameManager {
+	gm := &GameManager{
+		games:       games,
+		opponents:   make(map[string]*aiOpponent),
+		seats:       make(map[seatKey]*aiSeat),
+		replies:     make(map[string]pendingReply),
+		idempotency: make(map[idempotencyKey]*idempotentRequest),
+		jobs:        jobs,
 	}
+	games.OnEvent(gm.handleEvent)
+	return gm
 }
 
-// CreateGame creates a new game session
+// CreateGame creates a new game session. The creator is given the tokens of
+// the seats it claims; the others are left for players joining later. A game
+// created with an AI opponent that plays X starts with the AI's first move.
 func (gm *GameManager) CreateGame(c *gin.Context) {
-	gameID := generateGameID()
-	newGame := game.NewGame()
+	// The body is optional
+	var request createGameRequest
+	if err := bindValidJSON(c, "CreateGameRequest", &request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+		return
+	}
+	if err := validatePlayerNames(request.Players); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
 
-	gm.mu.Lock()
-	gm.games[gameID] = newGame
-	gm.mu.Unlock()
+	options := service.CreateOptions{
+		Mode:  service.ModeLocal,
+		X:     service.Seat{Name: request.Players.X},
+		O:     service.Seat{Name: request.Players.O},
+		Claim: request.Claim,
+	}
+	if options.Claim == nil {
+		options.Claim = []game.Player{game.X, game.O}
+	}
+	opponent := request.AI
+	if opponent != nil {
+		if err := opponent.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+		options.Mode = service.ModeAI
+		seat := service.Seat{Name: opponent.name(), AI: true}
+		if opponent.Player == game.X {
+			options.X = seat
+		} else {
+			options.O = seat
+		}
+	}
+
+	// The opponent is registered once the game exists. Nobody can move in the
+	// game before then, since its ID is only given out in this response.
+	gameID, newGame, tokens := gm.games.Create(options)
+	if opponent != nil {
+		gm.mu.Lock()
+		gm.opponents[gameID] = opponent
+		gm.mu.Unlock()
+	}
 
 	logging.DefaultLogger.Info("Game created", map[string]interface{}{
 		"gameId":     gameID,
 		"clientIP":   c.ClientIP(),
 		"userAgent":  c.Request.UserAgent(),
+		"aiOpponent": opponent != nil,
 	})
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"gameId": gameID,
 		"game":   newGame,
-	})
+		"tokens": tokens,
+	}
+	if opponent == nil {
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+	response["ai"] = opponent
+	if opponent.Player != newGame.CurrentPlayer {
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+	gm.respondWithReply(c, gameID, opponent, gm.submitReply(gameID, 0, opponent), http.StatusCreated, response)
 }
 
-// GetGame retrieves a game by ID
+// GetGame retrieves a game by ID. The ETag header carries the game's ply and
+// version, to be given back as the precondition of a move.
 func (gm *GameManager) GetGame(c *gin.Context) {
 	gameID := c.Param("id")
 	
-	gm.mu.RLock()
-	gameState, exists := gm.games[gameID]
-	gm.mu.RUnlock()
-	
-	if !exists {
+	gameState, summary, err := gm.games.Get(gameID)
+	if err != nil {
 		logging.DefaultLogger.Warning("Game not found", map[string]interface{}{
 			"gameId":    gameID,
 			"clientIP":  c.ClientIP(),
@@ -72,80 +156,124 @@ func (gm *GameManager) GetGame(c *gin.Context) {
 		"clientIP":  c.ClientIP(),
 	})
 
+	setGameETag(c, summary)
+	if c.GetHeader("If-None-Match") == gameETag(summary) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	c.JSON(http.StatusOK, gameState)
 }
 
-// MakeMove handles a player's move
+// MakeMove handles a player's move, made with the token of the player's
+// seat. A move made with a precondition is only played if the game is still
+// at the expected ply, or has not changed since the ETag given in If-Match,
+// and is otherwise answered with 409 and the current game. In a game with an AI opponent the response also carries the AI's
+// reply.
 func (gm *GameManager) MakeMove(c *gin.Context) {
 	gameID := c.Param("id")
-	
-	// Use read lock for initial lookup
-	gm.mu.RLock()
-	gameState, exists := gm.games[gameID]
-	gm.mu.RUnlock()
-	
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
-		return
-	}
 
 	var move game.Move
-	if err := c.ShouldBindJSON(&move); err != nil {
+	if err := bindValidJSON(c, "Move", &move); err != nil {
 		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid move format: "+err.Error()))
 		return
 	}
+	expected, err := movePrecondition(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
+	if !gm.authorizeSeat(c, gameID, move.Player) {
+		return
+	}
 
-	// Upgrade to write lock only for the actual move operation
-	gm.mu.Lock()
-	defer gm.mu.Unlock()
-	
-	// Re-check existence in case it was deleted
-	gameState, exists = gm.games[gameID]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+	gameState, summary, err := gm.games.MoveAtPly(gameID, expected.ply, expected.version, move, service.Origin{Client: c.ClientIP(), Waits: true})
+	if errors.Is(err, service.ErrStale) {
+		gm.respondWithStale(c, gameID, expected)
 		return
 	}
-	
-	if err := gameState.MakeMove(move); err != nil {
-		c.JSON(http.StatusBadRequest, NewGameLogicError(err.Error()))
+	if err != nil {
+		respondWithGameError(c, err)
 		return
 	}
+	setGameETag(c, summary)
 
-	c.JSON(http.StatusOK, gameState)
+	opponent := gm.opponent(gameID)
+	if opponent == nil {
+		c.JSON(http.StatusOK, gameState)
+		return
+	}
+	response := gin.H{
+		"game": gameState,
+		"move": move,
+	}
+	reply, pending := gm.takeReply(gameID, len(gameState.MoveHistory))
+	if !pending {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	gm.respondWithReply(c, gameID, opponent, reply, http.StatusOK, response)
+}
+
+// respondWithGameError writes the error returned by the game service
+func respondWithGameError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Game"))
+		return
+	}
+	c.JSON(http.StatusBadRequest, NewGameLogicError(err.Error()))
 }
 
-// MakeAIMove handles AI moves for single-player games
+// aiMoveRequest is the body accepted by the AI move endpoint
+type aiMoveRequest struct {
+	Difficulty json.RawMessage `json:"difficulty"` // "easy", "medium", "hard" or a level 1-20
+	Rating     int             `json:"rating"`     // Target rating, overrides difficulty
+	Engine     string          `json:"engine"`     // Configured external engine, overrides both
+	Seed       *int64          `json:"seed"`       // Makes the built-in AI's choice reproducible
+	Style      string          `json:"style"`      // Playing style of the built-in AI, not at easy or a level that plays random moves
+}
+
+// MakeAIMove handles AI moves for single-player games. The search runs on the
+// AI scheduler; for a client that sent Prefer: respond-async, one that takes
+// too long is answered with a job to poll. A game
+// with an AI opponent only takes moves for the opponent's side, and an empty
+// body plays with the opponent's settings. The request needs the token of the
+// seat the AI moves for, or of either seat if the AI plays the side to move.
+// Like a player's move it may carry a precondition on the game.
 func (gm *GameManager) MakeAIMove(c *gin.Context) {
 	gameID := c.Param("id")
 	
-	gm.mu.RLock()
-	gameState, exists := gm.games[gameID]
-	gm.mu.RUnlock()
-	
-	if !exists {
+	expected, err := movePrecondition(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
+	gameState, summary, err := gm.games.Get(gameID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, NewNotFoundError("Game"))
 		return
 	}
+	opponent := gm.opponent(gameID)
+	seat := gameState.CurrentPlayer
+	if opponent != nil && opponent.Player == seat {
+		seat = game.Empty
+	}
+	if !gm.authorizeSeat(c, gameID, seat) {
+		return
+	}
 
-	var request struct {
-		Difficulty string `json:"difficulty"`
+	var request aiMoveRequest
+	err = bindValidJSON(c, "AIMoveRequest", &request)
+	if opponent != nil && errors.Is(err, io.EOF) {
+		request, err = opponent.aiMoveRequest, nil
 	}
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if err != nil {
 		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
 		return
 	}
 
-	// Parse difficulty
-	var difficulty ai.Difficulty
-	switch request.Difficulty {
-	case "easy":
-		difficulty = ai.Easy
-	case "medium":
-		difficulty = ai.Medium
-	case "hard":
-		difficulty = ai.Hard
-	default:
-		difficulty = ai.Medium
+	if !expected.holds(summary) {
+		gm.respondWithStale(c, gameID, expected)
+		return
 	}
 
 	// Check if game is over
@@ -153,39 +281,410 @@ func (gm *GameManager) MakeAIMove(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, NewGameLogicError("Game is already over"))
 		return
 	}
+	if summary.Status == service.StateAbandoned {
+		c.JSON(http.StatusBadRequest, NewGameLogicError("Game has been abandoned"))
+		return
+	}
+	if opponent != nil && gameState.CurrentPlayer != opponent.Player {
+		c.JSON(http.StatusBadRequest, NewGameLogicError("It is not the AI's turn"))
+		return
+	}
+
+	if err := validateAIMoveRequest(request); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
+
+	job, err := gm.jobs.Submit(c.ClientIP(), func(ctx context.Context) (interface{}, error) {
+		return gm.playAIMove(ctx, gameID, expected, request)
+	})
+	if err != nil {
+		respondWithSchedulerError(c, "AI move", err)
+		return
+	}
+	awaitJobWith(c, job, "AI move", func(response *jobResponse) {
+		setResponseETag(c, response)
+		c.JSON(response.Code, response.Body)
+	}, func(err error) {
+		respondWithSchedulerError(c, "AI move", err)
+	})
+}
+
+// validateAIMoveRequest checks an AI move request before it is queued
+func validateAIMoveRequest(request aiMoveRequest) error {
+	if _, err := ai.ParsePersonality(request.Style); err != nil {
+		return err
+	}
+	if request.Engine != "" {
+		if request.Style != "" {
+			return errors.New("Styles apply to the built-in AI only")
+		}
+		if _, ok := config.DefaultConfig.AI.ExternalEngines[request.Engine]; !ok {
+			return errors.New("Unknown engine: " + request.Engine)
+		}
+		return nil
+	}
+	if request.Style != "" && request.Rating == 0 && difficultyValue(request.Difficulty) == "easy" {
+		return errors.New("Styles do not apply to easy, which plays random moves")
+	}
+	aiPlayer, err := newAIPlayerForRequest(request.Difficulty, request.Rating, request.Seed, game.X)
+	if err != nil {
+		return err
+	}
+	if strength, leveled := aiPlayer.Strength(); leveled && request.Style != "" && strength.BlunderChance >= 1 {
+		return fmt.Errorf("Styles do not apply to level %d, which plays random moves", strength.Level)
+	}
+	return nil
+}
+
+// playAIMove searches and plays the AI's move if the game meets the expected
+// precondition. It runs as a scheduler job, so the game may have changed
+// since the request was checked; the move is only played if no move was made
+// during the search either. An error is only returned when ctx is cancelled.
+func (gm *GameManager) playAIMove(ctx context.Context, gameID string, expected precondition, request aiMoveRequest) (*jobResponse, error) {
+	gameState, summary, err := gm.games.Get(gameID)
+	if err != nil {
+		return newJobResponse(http.StatusNotFound, NewNotFoundError("Game")), nil
+	}
+	if !expected.holds(summary) {
+		return gm.staleResponse(gameID, expected), nil
+	}
+	ply := len(gameState.MoveHistory)
+	if gameState.GameOver {
+		return newJobResponse(http.StatusBadRequest, NewGameLogicError("Game is already over")), nil
+	}
+	style, _ := ai.ParsePersonality(request.Style)
 
 	// Create AI player for current player
-	aiPlayer := ai.NewAIPlayer(difficulty, gameState.CurrentPlayer)
+	var engine ai.Engine
+	var seat *aiSeat
+	if request.Engine != "" {
+		command := config.DefaultConfig.AI.ExternalEngines[request.Engine]
+		external, err := startExternalEngine(command)
+		if err != nil {
+			return newJobResponse(http.StatusBadGateway, NewEngineError("External engine "+request.Engine+" failed to start").WithDetails(err.Error())), nil
+		}
+		defer external.Close()
+		engine = external
+	} else {
+		aiPlayer, err := newAIPlayerForRequest(request.Difficulty, request.Rating, request.Seed, gameState.CurrentPlayer)
+		if err != nil {
+			return newJobResponse(http.StatusBadRequest, NewInvalidInputError(err.Error())), nil
+		}
+		aiPlayer.SetPersonality(style)
+		engine = aiPlayer
+
+		// Seeded players start afresh on every move so the seed alone
+		// decides the move
+		if request.Seed == nil {
+			settings := fmt.Sprintf("%s/%d/%s", request.Difficulty, request.Rating, style)
+			seat = gm.aiSeat(gameID, gameState.CurrentPlayer, settings, aiPlayer)
+			engine = seat.session.Player()
+		}
+	}
 	
-	// Get AI move
-	aiMove := aiPlayer.GetBestMove(gameState)
+	// Get AI move, giving up if the job is cancelled
+	var aiMove game.Move
+	var stats ai.SearchStats
+	ponderHit := false
+	if seat != nil {
+		var result ai.SessionMove
+		result, err = seat.session.Move(ctx, gameState)
+		aiMove, stats, ponderHit = result.Move, result.Stats, result.PonderHit
+	} else {
+		aiMove, err = engine.GetBestMoveContext(ctx, gameState)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if request.Engine == "" {
+			return newJobResponse(http.StatusInternalServerError, NewInternalError("AI search failed").WithDetails(err.Error())), nil
+		}
+		return newJobResponse(http.StatusBadGateway, NewEngineError("External engine "+request.Engine+" failed").WithDetails(err.Error())), nil
+	}
 	
 	// Check if AI found a valid move (empty move detection)
 	if aiMove.Player == 0 {
-		c.JSON(http.StatusBadRequest, NewGameLogicError("No valid moves available for AI"))
-		return
+		return newJobResponse(http.StatusBadRequest, NewGameLogicError("No valid moves available for AI")), nil
 	}
 	
 	// Validate and make the move
-	if err := gameState.MakeMove(aiMove); err != nil {
-		c.JSON(http.StatusBadRequest, NewGameLogicError("AI move validation failed: "+err.Error()))
-		return
+	gameState, summary, err = gm.games.MoveAtPly(gameID, ply, service.AnyVersion, aiMove, service.Origin{AI: true, Name: request.name()})
+	if errors.Is(err, service.ErrStale) {
+		return gm.staleResponse(gameID, precondition{ply: ply, version: service.AnyVersion}), nil
+	}
+	if err != nil {
+		return newJobResponse(http.StatusBadRequest, NewGameLogicError("AI move validation failed: "+err.Error())), nil
+	}
+	if seat != nil {
+		seat.session.Ponder(gameState)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"game": gameState,
 		"move": aiMove,
+	}
+	if aiPlayer, ok := engine.(*ai.AIPlayer); ok {
+		if strength, ok := aiPlayer.Strength(); ok {
+			response["strength"] = strength
+		}
+		response["style"] = aiPlayer.Personality().Info()
+
+		message := "AI move searched"
+		if seat == nil {
+			stats = aiPlayer.SearchStats()
+		} else {
+			response["ponderHit"] = ponderHit
+			if ponderHit {
+				message = "AI move pondered"
+			}
+		}
+		response["stats"] = stats
+		logSearchStats(message, gameID, stats)
+	}
+	if request.Seed != nil {
+		response["seed"] = *request.Seed
+	}
+
+	return newJobResponse(http.StatusOK, response).withETag(summary), nil
+}
+
+// runPonder runs a ponder search as a background job, so pondering only
+// uses AI workers no request is waiting for
+func (gm *GameManager) runPonder(search func(ctx context.Context)) bool {
+	var once sync.Once
+	job, err := gm.jobs.SubmitBackground("ponder", func(ctx context.Context) (interface{}, error) {
+		once.Do(func() { search(ctx) })
+		return nil, nil
 	})
+	if err != nil {
+		return false
+	}
+	// A job dropped before it started still owes the search its call
+	go func() {
+		<-job.Done()
+		once.Do(func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			search(ctx)
+		})
+	}()
+	return true
 }
 
-// generateGameID creates a unique game ID using secure random generation
-func generateGameID() string {
-	bytes := make([]byte, 8)
-	if _, err := rand.Read(bytes); err != nil {
-		// Fallback to timestamp if crypto/rand fails
-		return strconv.FormatInt(time.Now().UnixNano(), 36)
+// aiSeat returns the AI session playing one side of a game, creating it from
+// aiPlayer if there is none or the request settings have changed
+func (gm *GameManager) aiSeat(gameID string, player game.Player, settings string, aiPlayer *ai.AIPlayer) *aiSeat {
+	gm.mu.Lock()
+
+	key := seatKey{gameID: gameID, player: player}
+	old, exists := gm.seats[key]
+	if exists && old.settings == settings {
+		gm.mu.Unlock()
+		return old
+	}
+
+	aiConfig := config.DefaultConfig.AI
+	seat := &aiSeat{
+		settings: settings,
+		session: ai.NewSession(aiPlayer, ai.PonderConfig{
+			Enabled: aiConfig.Ponder,
+			MaxTime: time.Duration(aiConfig.PonderTime) * time.Millisecond,
+			Run:     gm.runPonder,
+		}),
+	}
+	gm.seats[key] = seat
+	gm.mu.Unlock()
+
+	// Stopping waits for the old session, so it is done without the lock
+	if exists {
+		old.session.Stop()
 	}
-	return hex.EncodeToString(bytes)
+	return seat
+}
+
+// handleEvent keeps the AI of a game in step with it, whichever transport
+// the change came in on
+func (gm *GameManager) handleEvent(event service.Event) {
+	switch event.Type {
+	case service.EventMove:
+		if event.Origin.AI {
+			return
+		}
+		gm.observeMove(event.GameID, event.Game)
+
+		// The reply is left for the REST handler that made the move to
+		// wait for; moves from elsewhere just get it played. Each move
+		// replaces the reply to the one before, which nobody took.
+		gm.mu.Lock()
+		delete(gm.replies, event.GameID)
+		gm.mu.Unlock()
+		opponent := gm.opponent(event.GameID)
+		if opponent != nil && !event.Game.GameOver && event.Game.CurrentPlayer == opponent.Player {
+			ply := len(event.Game.MoveHistory)
+			if !event.Origin.Waits {
+				go gm.playReply(event.GameID, ply, opponent)
+				return
+			}
+			reply := gm.submitReply(event.GameID, ply, opponent)
+			gm.mu.Lock()
+			gm.replies[event.GameID] = reply
+			gm.mu.Unlock()
+		}
+
+	case service.EventClosed:
+		gm.mu.Lock()
+		var seats []*aiSeat
+		for _, player := range []game.Player{game.X, game.O} {
+			key := seatKey{gameID: event.GameID, player: player}
+			if seat, exists := gm.seats[key]; exists {
+				seats = append(seats, seat)
+				delete(gm.seats, key)
+			}
+		}
+		delete(gm.opponents, event.GameID)
+		delete(gm.replies, event.GameID)
+		for key := range gm.idempotency {
+			if key.gameID == event.GameID {
+				delete(gm.idempotency, key)
+			}
+		}
+		gm.mu.Unlock()
+
+		for _, seat := range seats {
+			seat.session.Stop()
+		}
+	}
+}
+
+// observeMove tells the game's AI sessions about a move played against them,
+// so a wrong guess stops pondering early
+func (gm *GameManager) observeMove(gameID string, gameState *game.GameState) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	for _, player := range []game.Player{game.X, game.O} {
+		if seat, exists := gm.seats[seatKey{gameID: gameID, player: player}]; exists {
+			seat.session.Observe(gameState)
+		}
+	}
+}
+
+// logSearchStats logs the statistics of an AI search
+func logSearchStats(message, gameID string, stats ai.SearchStats) {
+	logging.DefaultLogger.Info(message, map[string]interface{}{
+		"gameId":          gameID,
+		"nodes":           stats.Nodes,
+		"nodesPerSecond":  stats.NodesPerSecond,
+		"depth":           stats.Depth,
+		"selDepth":        stats.SelDepth,
+		"timeMs":          stats.TimeMs,
+		"ttHitRate":       stats.TTHitRate,
+		"branchingFactor": stats.BranchingFactor,
+		"cutoffs":         stats.Cutoffs,
+	})
+}
+
+// ListStrengthLevels returns every AI strength level with its measured rating
+func ListStrengthLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"levels": ai.StrengthLevels(),
+	})
+}
+
+// ListStyles returns every AI playing style with its description
+func ListStyles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"styles": ai.Personalities(),
+	})
+}
+
+// ListExternalEngines returns the names of the configured external engines
+func ListExternalEngines(c *gin.Context) {
+	names := []string{}
+	for name := range config.DefaultConfig.AI.ExternalEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{
+		"engines": names,
+	})
+}
+
+// startExternalEngine starts an external engine command. A new process is
+// started for every move so a crashed engine cannot affect later games.
+func startExternalEngine(command string) (*protocol.ExternalEngine, error) {
+	moveTime := time.Duration(config.DefaultConfig.AI.ExternalMoveTime) * time.Millisecond
+	return protocol.StartExternalEngine(command, protocol.Limits{MoveTime: moveTime})
+}
+
+// newAIPlayerForRequest creates the AI player asked for by an AI move request.
+// Named difficulties keep their original behaviour, numbers select a strength
+// level and a rating picks the level measured closest to it. A seed makes
+// the player's choices reproducible.
+func newAIPlayerForRequest(difficulty json.RawMessage, rating int, seed *int64, player game.Player) (*ai.AIPlayer, error) {
+	if rating > 0 {
+		return newLeveledAIPlayer(ai.StrengthForRating(rating), seed, player), nil
+	}
+
+	value := difficultyValue(difficulty)
+	switch value {
+	case "easy":
+		return newDifficultyAIPlayer(ai.Easy, seed, player), nil
+	case "medium":
+		return newDifficultyAIPlayer(ai.Medium, seed, player), nil
+	case "hard":
+		return newDifficultyAIPlayer(ai.Hard, seed, player), nil
+	}
+
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return newDifficultyAIPlayer(ai.Medium, seed, player), nil
+	}
+	if level < ai.MinLevel || level > ai.MaxLevel {
+		return nil, fmt.Errorf("difficulty level must be between %d and %d", ai.MinLevel, ai.MaxLevel)
+	}
+	return newLeveledAIPlayer(ai.StrengthForLevel(level), seed, player), nil
+}
+
+// difficultyValue returns the difficulty of a request, a name or a level,
+// without JSON quotes
+func difficultyValue(difficulty json.RawMessage) string {
+	return strings.Trim(strings.TrimSpace(string(difficulty)), `"`)
+}
+
+// newDifficultyAIPlayer creates a named-difficulty player, seeded if asked
+func newDifficultyAIPlayer(difficulty ai.Difficulty, seed *int64, player game.Player) *ai.AIPlayer {
+	if seed != nil {
+		return ai.NewSeededAIPlayer(difficulty, player, *seed)
+	}
+	return ai.NewAIPlayer(difficulty, player)
+}
+
+// newLeveledAIPlayer creates a strength-level player, seeded if asked
+func newLeveledAIPlayer(strength ai.Strength, seed *int64, player game.Player) *ai.AIPlayer {
+	if seed != nil {
+		return ai.NewSeededLeveledAIPlayer(strength, player, *seed)
+	}
+	return ai.NewLeveledAIPlayer(strength, player)
+}
+
+// loadPuzzleStore opens the puzzle file. A file that cannot be read is left
+// alone and the server runs with no puzzles.
+func loadPuzzleStore(path string) *puzzle.Store {
+	store, err := puzzle.LoadStore(path)
+	if err != nil {
+		logging.DefaultLogger.Error("Failed to load puzzles", err, map[string]interface{}{
+			"file": path,
+		})
+		return puzzle.NewStore()
+	}
+	logging.DefaultLogger.Info("Loaded puzzles", map[string]interface{}{
+		"file":    path,
+		"puzzles": store.Len(),
+	})
+	return store
 }
 
 var gameManager *GameManager
@@ -203,8 +702,13 @@ func HealthCheck(c *gin.Context) {
 	})
 }
 
-func SetupRoutes(hub *ws.Hub) *gin.Engine {
-	gameManager = NewGameManager()
+func SetupRoutes(hub *ws.Hub, games *service.GameService) *gin.Engine {
+	jobs := newAIScheduler()
+	hub.SetJobs(jobs)
+	gameManager = NewGameManager(games, jobs)
+	go games.RunReaper(newReaperConfig())
+	puzzleManager := NewPuzzleManager(loadPuzzleStore(config.DefaultConfig.Puzzles.File))
+	go puzzleManager.RunUpkeep(puzzleUpkeepInterval)
 	r := gin.Default()
 	
 	// Set trusted proxies to avoid warning
@@ -231,7 +735,8 @@ func SetupRoutes(hub *ws.Hub) *gin.Engine {
 		}
 		
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key, Last-Event-ID, Prefer")
+		c.Header("Access-Control-Expose-Headers", "ETag, Location, Retry-After, Idempotent-Replayed, Preference-Applied")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -247,14 +752,43 @@ func SetupRoutes(hub *ws.Hub) *gin.Engine {
 	// WebSocket endpoint
 	r.GET("/ws", hub.ServeWS)
 
+	// Page shared game links point at, with a preview of the board
+	r.GET("/share/games/:id", ShareGame(games))
+
 	api := r.Group("/api/v1")
 	{
 		api.POST("/games", gameManager.CreateGame)
+		api.GET("/games", ListGames(games))
 		api.GET("/games/:id", gameManager.GetGame)
-		api.POST("/games/:id/moves", gameManager.MakeMove)
-		api.POST("/games/:id/ai-move", gameManager.MakeAIMove)
+		api.DELETE("/games/:id", gameManager.DeleteGame)
+		api.GET("/games/:id/events", StreamGameEvents(games))
+		api.GET("/games/:id/wait", WaitForMove(games))
+		api.GET("/games/:id/board.svg", RenderBoard(games, "svg"))
+		api.GET("/games/:id/board.png", RenderBoard(games, "png"))
+		api.GET("/games/:id/replay.gif", ReplayGame(games, jobs))
+		api.POST("/games/:id/join", gameManager.JoinGame)
+		api.POST("/games/:id/resign", gameManager.ResignGame)
+		api.POST("/games/:id/abandon", gameManager.AbandonGame)
+		api.POST("/games/:id/moves", gameManager.idempotent(gameManager.MakeMove))
+		api.POST("/games/:id/ai-move", gameManager.idempotent(gameManager.MakeAIMove))
+		api.GET("/ai/levels", ListStrengthLevels)
+		api.GET("/ai/engines", ListExternalEngines)
+		api.GET("/ai/styles", ListStyles)
+		api.GET("/ai/jobs/:id", GetAIJob(jobs))
+		api.GET("/puzzles/next", puzzleManager.NextPuzzle)
+		api.POST("/puzzles/players", puzzleManager.RegisterPuzzlePlayer)
+		api.GET("/puzzles/players/:player", puzzleManager.GetPuzzlePlayer)
+		api.GET("/puzzles/:id", puzzleManager.GetPuzzle)
+		api.POST("/puzzles/:id/moves", puzzleManager.SubmitPuzzleMove)
+		api.GET("/puzzles/:id/reply", puzzleManager.GetPuzzleReply)
+		api.GET("/games/:id/analysis", gameManager.AnalyzeGame)
+		api.GET("/games/:id/review", gameManager.ReviewGame)
+		api.GET("/rooms/:id/review", gameManager.ReviewGame)
+		api.POST("/analysis", AnalyzePosition(jobs))
 		api.GET("/health", HealthCheck)
+		api.GET("/openapi.json", GetOpenAPI)
 	}
+	checkOpenAPI(r.Routes())
 
 	return r
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"t-9/internal/ai"
+)
+
+// A style is only accepted where it can change the move, so not at a level
+// that always plays a random move
+func TestStyleNeedsAChosenMove(t *testing.T) {
+	for _, strength := range ai.StrengthLevels() {
+		requests := map[string]struct {
+			request aiMoveRequest
+			plays   ai.Strength
+		}{
+			"level":  {aiMoveRequest{Difficulty: json.RawMessage(strconv.Itoa(strength.Level)), Style: string(ai.Aggressive)}, strength},
+			"rating": {aiMoveRequest{Rating: strength.Rating, Style: string(ai.Aggressive)}, ai.StrengthForRating(strength.Rating)},
+		}
+		for by, tt := range requests {
+			err := validateAIMoveRequest(tt.request)
+			if random := tt.plays.BlunderChance >= 1; random != (err != nil) {
+				t.Errorf("level %d by %s, playing level %d: error = %v", strength.Level, by, tt.plays.Level, err)
+			}
+		}
+	}
+
+	if err := validateAIMoveRequest(aiMoveRequest{Difficulty: json.RawMessage(`"easy"`), Style: string(ai.Aggressive)}); err == nil {
+		t.Error("style accepted at easy")
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+
+	"t-9/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// idempotencyKey identifies a request that may be retried. Keys are scoped to
+// the game and the seat token, so clients cannot replay each other's moves.
+type idempotencyKey struct {
+	gameID string
+	token  string
+	key    string
+}
+
+// idempotentRequest is the first request made with an idempotency key. Its
+// response is kept for retries until the game is closed.
+type idempotentRequest struct {
+	fingerprint [sha256.Size]byte // Hash of the method, path and body
+	done        chan struct{}     // Closed once the response is recorded
+	code        int
+	header      http.Header
+	body        []byte
+}
+
+// recordingWriter keeps a copy of the body written through it
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// replayedHeaders are the headers of a recorded response sent again on replay
+var replayedHeaders = []string{"Content-Type", "ETag", "Location", "Preference-Applied", "Retry-After"}
+
+// idempotent makes a game handler safe to retry with an Idempotency-Key
+// header. A retry gets the first request's response again instead of running
+// the handler, and a retry made while the first is still running waits for
+// it. Only successful responses are kept, so a failed request can be retried
+// for real. Reusing a key for a different request is an error.
+func (gm *GameManager) idempotent(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader("Idempotency-Key")
+		if value == "" {
+			handler(c)
+			return
+		}
+		if len(value) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request body: "+err.Error()))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.String() + "\n" + string(body)))
+		key := idempotencyKey{gameID: c.Param("id"), token: seatToken(c), key: value}
+
+		for {
+			gm.mu.Lock()
+			request, exists := gm.idempotency[key]
+			if !exists {
+				request = &idempotentRequest{fingerprint: fingerprint, done: make(chan struct{})}
+				gm.idempotency[key] = request
+				gm.mu.Unlock()
+				gm.record(c, key, request, handler)
+				return
+			}
+			gm.mu.Unlock()
+
+			if request.fingerprint != fingerprint {
+				c.JSON(http.StatusUnprocessableEntity, NewAPIError(ErrorTypeInvalidInput, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity))
+				return
+			}
+			select {
+			case <-request.done:
+			case <-c.Request.Context().Done():
+				return
+			}
+			if request.code != 0 {
+				replay(c, request)
+				return
+			}
+			// The first request failed and gave the key up; try again
+		}
+	}
+}
+
+// record runs the handler for the first request made with an idempotency
+// key and keeps its response if it succeeded
+func (gm *GameManager) record(c *gin.Context, key idempotencyKey, request *idempotentRequest, handler gin.HandlerFunc) {
+	writer := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	defer func() {
+		if request.code == 0 {
+			gm.mu.Lock()
+			delete(gm.idempotency, key)
+			gm.mu.Unlock()
+		}
+		close(request.done)
+	}()
+
+	handler(c)
+
+	// gin reports 200 before anything is written, so a handler that gave up
+	// without answering, such as for a client that went away, is not kept
+	if !writer.Written() || writer.Status() < 200 || writer.Status() >= 300 {
+		return
+	}
+	request.code = writer.Status()
+	request.header = writer.Header().Clone()
+	request.body = writer.body.Bytes()
+}
+
+// replay writes the response recorded for an idempotency key
+func replay(c *gin.Context, request *idempotentRequest) {
+	for _, name := range replayedHeaders {
+		if value := request.header.Get(name); value != "" {
+			c.Header(name, value)
+		}
+	}
+	c.Header("Idempotent-Replayed", "true")
+	logging.DefaultLogger.Info("Request replayed", map[string]interface{}{
+		"gameId":   c.Param("id"),
+		"path":     c.FullPath(),
+		"clientIP": c.ClientIP(),
+	})
+	c.Data(request.code, request.header.Get("Content-Type"), request.body)
+}
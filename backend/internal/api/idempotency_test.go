@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moveStep is one move request in a test of the move endpoint
+type moveStep struct {
+	player      game.Player // Seat whose token is sent
+	move        game.Move
+	key         string // Idempotency-Key, if any
+	expectedPly string // expectedPly query parameter, if any
+	ifMatch     int    // If-Match is the game's ETag from before step ifMatch-1, if set
+	watch       bool   // A WebSocket client joins to watch just before the move
+
+	code     int
+	replayed bool // Answered with the response recorded for its key
+	sameAs   int  // Body and ETag equal those of step sameAs-1, if set
+}
+
+// newMoveTestRouter serves the game and move endpoints of a new local game,
+// returning the router, the game service, the game's ID and its tokens
+func newMoveTestRouter(t *testing.T) (*gin.Engine, *service.GameService, string, service.Tokens) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	jobs := scheduler.New(scheduler.Config{Workers: 1, QueueSize: 4, Retention: time.Minute})
+	t.Cleanup(jobs.Close)
+	games := service.New()
+	gm := NewGameManager(games, jobs)
+
+	r := gin.New()
+	r.GET("/games/:id", gm.GetGame)
+	r.POST("/games/:id/moves", gm.idempotent(gm.MakeMove))
+
+	gameID, _, tokens := games.Create(service.CreateOptions{
+		Mode:  service.ModeLocal,
+		Claim: []game.Player{game.X, game.O},
+	})
+	return r, games, gameID, tokens
+}
+
+func TestMoveRetries(t *testing.T) {
+	xCenter := game.Move{BigBoardIndex: 4, SmallBoardIndex: 4, Player: game.X}
+	xCorner := game.Move{BigBoardIndex: 0, SmallBoardIndex: 0, Player: game.X}
+	oReply := game.Move{BigBoardIndex: 4, SmallBoardIndex: 0, Player: game.O}
+
+	tests := []struct {
+		name  string
+		steps []moveStep
+		moves int // Moves in the game at the end
+	}{
+		{
+			name: "retry is replayed",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, key: "k", code: http.StatusOK},
+				{player: game.X, move: xCenter, key: "k", code: http.StatusOK, replayed: true, sameAs: 1},
+			},
+			moves: 1,
+		},
+		{
+			name: "retry without a key is played again",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, code: http.StatusOK},
+				{player: game.X, move: xCenter, code: http.StatusBadRequest},
+			},
+			moves: 1,
+		},
+		{
+			name: "key reused for another move",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, key: "k", code: http.StatusOK},
+				{player: game.X, move: xCorner, key: "k", code: http.StatusUnprocessableEntity},
+			},
+			moves: 1,
+		},
+		{
+			name: "keys are kept per seat",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, key: "k", code: http.StatusOK},
+				{player: game.O, move: oReply, key: "k", code: http.StatusOK},
+			},
+			moves: 2,
+		},
+		{
+			name: "failed request is run again",
+			steps: []moveStep{
+				{player: game.O, move: oReply, key: "k", code: http.StatusBadRequest},
+				{player: game.X, move: xCenter, code: http.StatusOK},
+				{player: game.O, move: oReply, key: "k", code: http.StatusOK},
+			},
+			moves: 2,
+		},
+		{
+			name: "expected ply is current",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, expectedPly: "0", code: http.StatusOK},
+				{player: game.O, move: oReply, expectedPly: "1", code: http.StatusOK},
+			},
+			moves: 2,
+		},
+		{
+			name: "expected ply is stale",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, expectedPly: "0", code: http.StatusOK},
+				{player: game.O, move: oReply, expectedPly: "0", code: http.StatusConflict},
+			},
+			moves: 1,
+		},
+		{
+			name: "If-Match is current",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, ifMatch: 1, code: http.StatusOK},
+				{player: game.O, move: oReply, ifMatch: 2, code: http.StatusOK},
+			},
+			moves: 2,
+		},
+		{
+			name: "If-Match is stale",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, ifMatch: 1, code: http.StatusOK},
+				{player: game.O, move: oReply, ifMatch: 1, code: http.StatusConflict},
+			},
+			moves: 1,
+		},
+		{
+			name: "If-Match is stale at the same ply",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, ifMatch: 1, code: http.StatusOK},
+				{player: game.O, move: oReply, ifMatch: 2, watch: true, code: http.StatusConflict},
+			},
+			moves: 1,
+		},
+		{
+			name: "expected ply holds at the same ply",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, expectedPly: "0", code: http.StatusOK},
+				{player: game.O, move: oReply, expectedPly: "1", watch: true, code: http.StatusOK},
+			},
+			moves: 2,
+		},
+		{
+			name: "stale move with a key is not kept",
+			steps: []moveStep{
+				{player: game.X, move: xCenter, code: http.StatusOK},
+				{player: game.O, move: oReply, key: "k", expectedPly: "0", code: http.StatusConflict},
+				{player: game.O, move: oReply, key: "k", expectedPly: "0", code: http.StatusConflict},
+			},
+			moves: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, games, gameID, tokens := newMoveTestRouter(t)
+			tokenOf := map[game.Player]string{game.X: tokens.X, game.O: tokens.O}
+
+			var etags []string // The game's ETag before each step
+			var responses []*httptest.ResponseRecorder
+			for i, step := range tt.steps {
+				etags = append(etags, getGame(t, r, gameID).Header().Get("ETag"))
+				if step.watch {
+					if _, _, err := games.Join(gameID, "watcher", tokens.Observer); err != nil {
+						t.Fatalf("step %d: joining to watch: %v", i+1, err)
+					}
+				}
+
+				body, _ := json.Marshal(step.move)
+				target := "/games/" + gameID + "/moves"
+				if step.expectedPly != "" {
+					target += "?expectedPly=" + step.expectedPly
+				}
+				req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(string(body)))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+tokenOf[step.player])
+				if step.key != "" {
+					req.Header.Set("Idempotency-Key", step.key)
+				}
+				if step.ifMatch > 0 {
+					req.Header.Set("If-Match", etags[step.ifMatch-1])
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				responses = append(responses, w)
+
+				if w.Code != step.code {
+					t.Fatalf("step %d: status = %d, want %d: %s", i+1, w.Code, step.code, w.Body)
+				}
+				if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != step.replayed {
+					t.Errorf("step %d: replayed = %v, want %v", i+1, replayed, step.replayed)
+				}
+				if step.sameAs > 0 {
+					first := responses[step.sameAs-1]
+					if w.Body.String() != first.Body.String() {
+						t.Errorf("step %d: body differs from step %d:\n%s\n%s", i+1, step.sameAs, w.Body, first.Body)
+					}
+					if w.Header().Get("ETag") != first.Header().Get("ETag") {
+						t.Errorf("step %d: ETag = %s, want %s", i+1, w.Header().Get("ETag"), first.Header().Get("ETag"))
+					}
+				}
+				if w.Code == http.StatusConflict {
+					checkConflict(t, i+1, w, getGame(t, r, gameID))
+				}
+			}
+
+			var final game.GameState
+			if err := json.Unmarshal(getGame(t, r, gameID).Body.Bytes(), &final); err != nil {
+				t.Fatalf("reading game: %v", err)
+			}
+			if len(final.MoveHistory) != tt.moves {
+				t.Errorf("game has %d moves, want %d", len(final.MoveHistory), tt.moves)
+			}
+		})
+	}
+}
+
+// getGame fetches a game through the router
+func getGame(t *testing.T, r *gin.Engine, gameID string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/"+gameID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("getting game: status %d: %s", w.Code, w.Body)
+	}
+	return w
+}
+
+// checkConflict checks that a 409 carries the game as it is now, with its
+// ETag
+func checkConflict(t *testing.T, step int, w, current *httptest.ResponseRecorder) {
+	t.Helper()
+	if got, want := w.Header().Get("ETag"), current.Header().Get("ETag"); got != want {
+		t.Errorf("step %d: conflict ETag = %s, want %s", step, got, want)
+	}
+	var conflict struct {
+		Game *game.GameState `json:"game"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &conflict); err != nil || conflict.Game == nil {
+		t.Fatalf("step %d: conflict carries no game: %v: %s", step, err, w.Body)
+	}
+	got, _ := json.Marshal(conflict.Game)
+	var want game.GameState
+	json.Unmarshal(current.Body.Bytes(), &want)
+	if wantJSON, _ := json.Marshal(&want); string(got) != string(wantJSON) {
+		t.Errorf("step %d: conflict game = %s, want %s", step, got, wantJSON)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"t-9/internal/config"
+	"t-9/internal/logging"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jobRetention is how long a finished AI job can still be polled
+const jobRetention = 10 * time.Minute
+
+// jobResponse is the HTTP response an AI job produces. Clients that were
+// given a job ID find it in the job's result.
+type jobResponse struct {
+	Code int         `json:"code"`
+	Body interface{} `json:"body"`
+
+	etag string // Entity tag of the game in the body, if any
+}
+
+func newJobResponse(code int, body interface{}) *jobResponse {
+	return &jobResponse{Code: code, Body: body}
+}
+
+// withETag tags the response with the entity tag of the game it carries
+func (r *jobResponse) withETag(summary service.Summary) *jobResponse {
+	r.etag = gameETag(summary)
+	return r
+}
+
+// newAIScheduler creates the scheduler AI searches run on
+func newAIScheduler() *scheduler.Scheduler {
+	aiConfig := config.DefaultConfig.AI
+	return scheduler.New(scheduler.Config{
+		Workers:    aiConfig.Workers,
+		QueueSize:  aiConfig.QueueSize,
+		MaxWait:    time.Duration(aiConfig.QueueWait) * time.Millisecond,
+		ClientJobs: aiConfig.ClientJobs,
+		Retention:  jobRetention,
+	})
+}
+
+// awaitJob writes a job's response once it is done. A client that sent
+// Prefer: respond-async is answered with 202 and the job's ID if the job is
+// still running after the async threshold, and the job carries on without
+// it. If the client goes away first the job is cancelled.
+func awaitJob(c *gin.Context, job *scheduler.Job, what string) {
+	awaitJobWith(c, job, what, func(response *jobResponse) {
+		c.JSON(response.Code, response.Body)
+	}, func(err error) {
+		respondWithSchedulerError(c, what, err)
+	})
+}
+
+// awaitJobWith is awaitJob with done writing the response of a finished job
+// and failed writing the response for a job that did not run to completion
+func awaitJobWith(c *gin.Context, job *scheduler.Job, what string, done func(response *jobResponse), failed func(err error)) {
+	var accepted func()
+	if prefersAsync(c) {
+		accepted = func() {
+			c.Header("Preference-Applied", "respond-async")
+			respondWithJob(c, job)
+		}
+	}
+	waitForJob(c, job, what, false, accepted, done, failed)
+}
+
+// awaitPolledJob is awaitJob for work clients expect to poll for, such as a
+// game review: a job still running after the async threshold is answered with
+// 202 and its ID whether or not the client asked for it
+func awaitPolledJob(c *gin.Context, job *scheduler.Job, what string) {
+	waitForJob(c, job, what, false, func() {
+		respondWithJob(c, job)
+	}, func(response *jobResponse) {
+		c.JSON(response.Code, response.Body)
+	}, func(err error) {
+		respondWithSchedulerError(c, what, err)
+	})
+}
+
+// awaitDetachedJob is awaitJobWith for a job that must run whether or not
+// anyone waits for it, such as an AI opponent's reply: if the client goes
+// away the job carries on. A job still running after the async threshold is
+// answered by accepted.
+func awaitDetachedJob(c *gin.Context, job *scheduler.Job, what string, accepted func(), done func(response *jobResponse), failed func(err error)) {
+	waitForJob(c, job, what, true, accepted, done, failed)
+}
+
+// waitForJob waits for a job on behalf of a request, cancelling it if the
+// client goes away unless it is detached. With accepted set, a job still
+// running after the async threshold carries on without the client, who is
+// answered by accepted; otherwise the request waits for the job to finish.
+func waitForJob(c *gin.Context, job *scheduler.Job, what string, detached bool, accepted func(), done func(response *jobResponse), failed func(err error)) {
+	var expired <-chan time.Time
+	if accepted != nil {
+		timer := time.NewTimer(time.Duration(config.DefaultConfig.AI.AsyncAfter) * time.Millisecond)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-job.Done():
+		result, err := job.Result()
+		if err != nil {
+			failed(err)
+			return
+		}
+		done(result.(*jobResponse))
+
+	case <-c.Request.Context().Done():
+		if detached {
+			return
+		}
+		job.Cancel()
+		logSearchCancelled(c, what, c.Request.Context().Err())
+
+	case <-expired:
+		logging.DefaultLogger.Info(what+" continues as a job", map[string]interface{}{
+			"jobId":    job.ID(),
+			"clientIP": c.ClientIP(),
+		})
+		accepted()
+	}
+}
+
+// respondWithJob answers with 202 and the ID of a job the client is to poll
+func respondWithJob(c *gin.Context, job *scheduler.Job) {
+	c.Header("Location", "/api/v1/ai/jobs/"+job.ID())
+	c.JSON(http.StatusAccepted, gin.H{
+		"jobId": job.ID(),
+		"job":   job.View(),
+	})
+}
+
+// prefersAsync reports whether the client asked, with a Prefer header, to be
+// answered before a long job is done
+func prefersAsync(c *gin.Context) bool {
+	for _, header := range c.Request.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			preference, _, _ = strings.Cut(preference, ";")
+			name, _, _ := strings.Cut(preference, "=")
+			if strings.EqualFold(strings.TrimSpace(name), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// respondWithSchedulerError writes the error for a job that could not run
+func respondWithSchedulerError(c *gin.Context, what string, err error) {
+	logSchedulerError(c.ClientIP(), what, err)
+
+	apiErr, retryAfter := schedulerError(what, err)
+	if retryAfter != "" {
+		c.Header("Retry-After", retryAfter)
+	}
+	c.JSON(apiErr.Code, apiErr)
+}
+
+// logSchedulerError logs a job that could not run
+func logSchedulerError(client, what string, err error) {
+	logging.DefaultLogger.Warning(what+" turned away", map[string]interface{}{
+		"error":  err.Error(),
+		"client": client,
+	})
+}
+
+// schedulerError maps the error of a job that could not run to an API error,
+// along with the Retry-After value for errors that are worth retrying
+func schedulerError(what string, err error) (*APIError, string) {
+	switch {
+	case errors.Is(err, scheduler.ErrClientBusy):
+		return NewOverloadedError("Too many AI requests in progress", http.StatusTooManyRequests).WithDetails(err.Error()), "1"
+	case errors.Is(err, scheduler.ErrQueueFull), errors.Is(err, scheduler.ErrQueueTimeout), errors.Is(err, scheduler.ErrClosed):
+		return NewOverloadedError("AI is busy, try again later", http.StatusServiceUnavailable).WithDetails(err.Error()), "5"
+	default:
+		return NewInternalError(what + " failed").WithDetails(err.Error()), ""
+	}
+}
+
+// GetAIJob returns an AI job's status, and its response once it is done
+func GetAIJob(jobs *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, exists := jobs.Job(c.Param("id"))
+		if !exists {
+			c.JSON(http.StatusNotFound, NewNotFoundError("Job"))
+			return
+		}
+		c.JSON(http.StatusOK, job.View())
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"t-9/internal/config"
+	"t-9/internal/game"
+	"t-9/internal/logging"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newReaperConfig sets the game reaper up from the configured TTLs
+func newReaperConfig() service.ReaperConfig {
+	games := config.DefaultConfig.Games
+	return service.ReaperConfig{
+		Interval:     time.Duration(games.ReapInterval) * time.Second,
+		CreatedTTL:   time.Duration(games.CreatedTTL) * time.Second,
+		ActiveTTL:    time.Duration(games.ActiveTTL) * time.Second,
+		FinishedTTL:  time.Duration(games.FinishedTTL) * time.Second,
+		AbandonedTTL: time.Duration(games.AbandonedTTL) * time.Second,
+		ArchiveFile:  games.ArchiveFile,
+	}
+}
+
+// DeleteGame removes a game at once, without archiving it. Either player
+// may delete it.
+func (gm *GameManager) DeleteGame(c *gin.Context) {
+	gameID := c.Param("id")
+	if !gm.authorizeSeat(c, gameID, game.Empty) {
+		return
+	}
+
+	if err := gm.games.Delete(gameID, service.Origin{Client: c.ClientIP()}); err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+
+	logging.DefaultLogger.Info("Game deleted", map[string]interface{}{
+		"gameId":   gameID,
+		"clientIP": c.ClientIP(),
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResignGame ends a game with the resigning player's opponent as the winner
+func (gm *GameManager) ResignGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var request struct {
+		Player game.Player `json:"player"`
+	}
+	if err := bindValidJSON(c, "ResignRequest", &request); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+		return
+	}
+	if !gm.authorizeSeat(c, gameID, request.Player) {
+		return
+	}
+
+	gameState, err := gm.games.Resign(gameID, request.Player, service.Origin{Client: c.ClientIP()})
+	if err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+
+	logging.DefaultLogger.Info("Game resigned", map[string]interface{}{
+		"gameId":   gameID,
+		"player":   request.Player.String(),
+		"clientIP": c.ClientIP(),
+	})
+
+	gm.respondWithSummary(c, gameID, gameState)
+}
+
+// AbandonGame stops a game without a result. It is archived once past the
+// abandoned TTL. Either player may abandon it.
+func (gm *GameManager) AbandonGame(c *gin.Context) {
+	gameID := c.Param("id")
+	if !gm.authorizeSeat(c, gameID, game.Empty) {
+		return
+	}
+
+	gameState, err := gm.games.Abandon(gameID, service.Origin{Client: c.ClientIP()})
+	if err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+
+	logging.DefaultLogger.Info("Game abandoned", map[string]interface{}{
+		"gameId":   gameID,
+		"clientIP": c.ClientIP(),
+	})
+
+	gm.respondWithSummary(c, gameID, gameState)
+}
+
+// respondWithSummary writes a game along with its summary
+func (gm *GameManager) respondWithSummary(c *gin.Context, gameID string, gameState *game.GameState) {
+	_, summary, err := gm.games.Get(gameID)
+	if err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"game":    gameState,
+		"summary": summary,
+	})
+}
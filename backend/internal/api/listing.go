@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// gameFilter selects the games a listing returns
+type gameFilter struct {
+	status        string
+	mode          service.Mode
+	participant   string
+	result        string
+	createdAfter  time.Time
+	createdBefore time.Time
+	updatedAfter  time.Time
+	updatedBefore time.Time
+}
+
+// parseGameFilter reads a filter from the query string
+func parseGameFilter(c *gin.Context) (gameFilter, error) {
+	filter := gameFilter{
+		status:      c.Query("status"),
+		mode:        service.Mode(c.Query("mode")),
+		participant: c.Query("participant"),
+		result:      c.Query("result"),
+	}
+	if !oneOf(filter.status, "", service.StateCreated, service.StateActive, service.StateFinished, service.StateAbandoned) {
+		return filter, fmt.Errorf("status must be created, active, finished or abandoned")
+	}
+	if !oneOf(string(filter.mode), "", string(service.ModeLocal), string(service.ModeAI), string(service.ModeMultiplayer)) {
+		return filter, fmt.Errorf("mode must be local, ai or multiplayer")
+	}
+	if !oneOf(filter.result, "", "x", "o", "draw") {
+		return filter, fmt.Errorf("result must be x, o or draw")
+	}
+
+	times := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"createdAfter", &filter.createdAfter},
+		{"createdBefore", &filter.createdBefore},
+		{"updatedAfter", &filter.updatedAfter},
+		{"updatedBefore", &filter.updatedBefore},
+	}
+	for _, t := range times {
+		value := c.Query(t.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 time", t.name)
+		}
+		*t.value = parsed
+	}
+	return filter, nil
+}
+
+// matches reports whether a game passes the filter
+func (f gameFilter) matches(summary service.Summary) bool {
+	switch {
+	case f.status != "" && summary.Status != f.status:
+		return false
+	case f.mode != "" && summary.Mode != f.mode:
+		return false
+	case f.result != "" && summary.Result != f.result:
+		return false
+	case f.participant != "" && !summary.Players.Has(f.participant):
+		return false
+	case !f.createdAfter.IsZero() && !summary.CreatedAt.After(f.createdAfter):
+		return false
+	case !f.createdBefore.IsZero() && !summary.CreatedAt.Before(f.createdBefore):
+		return false
+	case !f.updatedAfter.IsZero() && !summary.UpdatedAt.After(f.updatedAfter):
+		return false
+	case !f.updatedBefore.IsZero() && !summary.UpdatedAt.Before(f.updatedBefore):
+		return false
+	}
+	return true
+}
+
+// sortKeys gives the value each sort field orders games by
+var sortKeys = map[string]func(service.Summary) int64{
+	"createdAt": func(s service.Summary) int64 { return s.CreatedAt.UnixNano() },
+	"updatedAt": func(s service.Summary) int64 { return s.UpdatedAt.UnixNano() },
+	"moveCount": func(s service.Summary) int64 { return int64(s.MoveCount) },
+}
+
+// gameOrder is the order a listing is sorted in. Ties are broken by game ID
+// so every game has a fixed place for cursors to point at.
+type gameOrder struct {
+	field      string
+	descending bool
+}
+
+// parseGameOrder reads the sort parameter, a field prefixed with - for
+// descending order. The default is most recently updated first.
+func parseGameOrder(value string) (gameOrder, error) {
+	if value == "" {
+		value = "-updatedAt"
+	}
+	order := gameOrder{field: strings.TrimPrefix(value, "-"), descending: strings.HasPrefix(value, "-")}
+	if _, ok := sortKeys[order.field]; !ok {
+		return order, fmt.Errorf("sort must be createdAt, updatedAt or moveCount, optionally prefixed with -")
+	}
+	return order, nil
+}
+
+// String returns the order as the sort parameter gives it
+func (o gameOrder) String() string {
+	if o.descending {
+		return "-" + o.field
+	}
+	return o.field
+}
+
+// before reports whether the game with key a and ID idA comes before the game
+// with key b and ID idB
+func (o gameOrder) before(a int64, idA string, b int64, idB string) bool {
+	if a == b {
+		return idA < idB
+	}
+	return (a < b) != o.descending
+}
+
+// gameCursor marks the last game of a page
+type gameCursor struct {
+	Sort   string `json:"s"`
+	Key    int64  `json:"k"`
+	GameID string `json:"id"`
+}
+
+// encode turns a cursor into the opaque string given to clients
+func (c gameCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseGameCursor reads a cursor given back by a client
+func parseGameCursor(value string, order gameOrder) (*gameCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor gameCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Sort != order.String() {
+		return nil, fmt.Errorf("cursor was made for sort %s", cursor.Sort)
+	}
+	return &cursor, nil
+}
+
+// ListGames lists games, filtered, sorted and paginated by cursor
+func ListGames(games *service.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseGameFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+		order, err := parseGameOrder(c.Query("sort"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+		cursor, err := parseGameCursor(c.Query("cursor"), order)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+		limit := defaultListLimit
+		if value := c.Query("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				c.JSON(http.StatusBadRequest, NewInvalidInputError(fmt.Sprintf("limit must be between 1 and %d", maxListLimit)))
+				return
+			}
+		}
+
+		key := sortKeys[order.field]
+		listed := []service.Summary{}
+		for _, summary := range games.List() {
+			if !filter.matches(summary) {
+				continue
+			}
+			if cursor != nil && !order.before(cursor.Key, cursor.GameID, key(summary), summary.GameID) {
+				continue
+			}
+			listed = append(listed, summary)
+		}
+		sort.Slice(listed, func(i, j int) bool {
+			return order.before(key(listed[i]), listed[i].GameID, key(listed[j]), listed[j].GameID)
+		})
+
+		response := gin.H{}
+		if len(listed) > limit {
+			listed = listed[:limit]
+			last := listed[limit-1]
+			response["nextCursor"] = gameCursor{Sort: order.String(), Key: key(last), GameID: last.GameID}.encode()
+		}
+		response["games"] = listed
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// oneOf reports whether value is one of the allowed values
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,699 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"t-9/internal/ai"
+	"t-9/internal/logging"
+	"t-9/internal/render"
+	"t-9/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIDocument is an OpenAPI 3.0 document
+type openAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Servers    []openAPIServer                  `json:"servers"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components openAPIComponents                `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// operation is one method of a path
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// newOperation starts describing an operation
+func newOperation(id, summary, tag string) *operation {
+	return &operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{tag},
+		Responses:   map[string]*response{},
+	}
+}
+
+// with adds parameters
+func (o *operation) with(params ...parameter) *operation {
+	o.Parameters = append(o.Parameters, params...)
+	return o
+}
+
+// body sets the JSON request body to the named schema
+func (o *operation) body(schema string, required bool) *operation {
+	o.RequestBody = &requestBody{
+		Required: required,
+		Content:  map[string]mediaType{"application/json": {Schema: ref(schema)}},
+	}
+	return o
+}
+
+// returns adds a JSON response, or one without a body if schema is nil
+func (o *operation) returns(code int, description string, schema *Schema) *operation {
+	r := &response{Description: description}
+	if schema != nil {
+		r.Content = map[string]mediaType{"application/json": {Schema: schema}}
+	}
+	o.Responses[strconv.Itoa(code)] = r
+	return o
+}
+
+// returnsContent adds a response of another content type
+func (o *operation) returnsContent(code int, description, contentType string, schema *Schema) *operation {
+	o.Responses[strconv.Itoa(code)] = &response{
+		Description: description,
+		Content:     map[string]mediaType{contentType: {Schema: schema}},
+	}
+	return o
+}
+
+// errorDescriptions describe the error responses operations share
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:          "Invalid input or a move the rules do not allow",
+	http.StatusUnauthorized:        "Missing or unknown token",
+	http.StatusForbidden:           "The token does not allow the request",
+	http.StatusNotFound:            "Not found",
+	http.StatusUnprocessableEntity: "Idempotency-Key reused for a different request",
+	http.StatusTooManyRequests:     "Too many AI jobs for this client",
+	http.StatusInternalServerError: "The server failed",
+	http.StatusBadGateway:          "An external engine failed",
+	http.StatusServiceUnavailable:  "The AI queue is full",
+}
+
+// fails adds APIError responses
+func (o *operation) fails(codes ...int) *operation {
+	for _, code := range codes {
+		o.returns(code, errorDescriptions[code], ref("APIError"))
+	}
+	return o
+}
+
+// seated marks the operation as needing a seat token
+func (o *operation) seated() *operation {
+	o.Security = []map[string][]string{{"seatToken": {}}}
+	return o.fails(http.StatusUnauthorized, http.StatusForbidden)
+}
+
+// registered marks the operation as needing a puzzle player's token
+func (o *operation) registered() *operation {
+	o.Security = []map[string][]string{{"puzzleToken": {}}}
+	return o.fails(http.StatusUnauthorized)
+}
+
+func pathParam(name, description string) parameter {
+	return parameter{Name: name, In: "path", Description: description, Required: true, Schema: &Schema{Type: "string"}}
+}
+
+func queryParam(name, description string, schema *Schema) parameter {
+	return parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func headerParam(name, description string) parameter {
+	return parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+var (
+	gameIDParam      = pathParam("id", "Game ID")
+	preferAsyncParam = headerParam("Prefer", "respond-async to be given a job to poll if the search takes long")
+	depthParam       = queryParam("depth", "Search depth in plies, the default depth unless given", integerRange(0, ai.MaxAnalysisDepth, ""))
+	preconditions    = []parameter{
+		headerParam("If-Match", "ETag of the game the move was made on; the move fails with 409 if the game has changed since"),
+		queryParam("expectedPly", "Number of moves the game must have, as an alternative to If-Match", integerRange(0, 81, "")),
+		headerParam("Idempotency-Key", "Key making the request safe to retry; a retry gets the first response again"),
+	}
+	timestamp = &Schema{Type: "string", Format: "date-time"}
+)
+
+// errorTypes lists every ErrorType
+var errorTypes = []string{
+	string(ErrorTypeNotFound),
+	string(ErrorTypeInvalidInput),
+	string(ErrorTypeGameLogic),
+	string(ErrorTypeInternal),
+	string(ErrorTypeUnauthorized),
+	string(ErrorTypeOverloaded),
+	string(ErrorTypeConflict),
+}
+
+// openAPISchemas are the named schemas of the OpenAPI document. Request
+// bodies are validated against them.
+var openAPISchemas = newOpenAPISchemas()
+
+func newOpenAPISchemas() map[string]*Schema {
+	styles := []string{}
+	for _, style := range ai.Personalities() {
+		styles = append(styles, string(style.Name))
+	}
+	nineItems := 9
+	maxName := maxPlayerIDLength
+	twoSeats := 2
+
+	aiSettings := func() map[string]*Schema {
+		return map[string]*Schema{
+			"difficulty": ref("Difficulty"),
+			"rating":     {Type: "integer", Minimum: new(float64), Description: "Target rating, overrides difficulty"},
+			"engine":     {Type: "string", Description: "Configured external engine, overrides both; see /api/v1/ai/engines"},
+			"seed":       {Type: "integer", Format: "int64", Description: "Makes the built-in AI's choice reproducible"},
+			"style":      ref("Style"),
+		}
+	}
+	opponent := aiSettings()
+	opponent["player"] = ref("Seat")
+
+	return map[string]*Schema{
+		"Player": {
+			Type:        "integer",
+			Enum:        []interface{}{0, 1, 2},
+			Description: "0 is empty, 1 is X and 2 is O",
+		},
+		"Seat": {
+			Type:        "integer",
+			Enum:        []interface{}{1, 2},
+			Description: "A side of the game: 1 is X and 2 is O",
+		},
+		"Move": strictObject("A move", map[string]*Schema{
+			"bigBoardIndex":   integerRange(0, 8, "Small board played in, 0-8 row by row"),
+			"smallBoardIndex": integerRange(0, 8, "Cell of the small board, 0-8 row by row"),
+			"player":          ref("Seat"),
+		}, "bigBoardIndex", "smallBoardIndex", "player"),
+		"GameState": strictObject("The complete state of a game", map[string]*Schema{
+			"bigBoard": {
+				Type:        "array",
+				Description: "The nine small boards, each nine cells",
+				MinItems:    &nineItems,
+				MaxItems:    &nineItems,
+				Items:       &Schema{Type: "array", MinItems: &nineItems, MaxItems: &nineItems, Items: ref("Player")},
+			},
+			"bigBoardWins": {
+				Type:        "array",
+				Description: "Who won each small board",
+				MinItems:    &nineItems,
+				MaxItems:    &nineItems,
+				Items:       ref("Player"),
+			},
+			"activeBoard":   integerRange(-1, 8, "Small board the next move must be played in, -1 for any"),
+			"currentPlayer": ref("Player"),
+			"gameWon":       ref("Player"),
+			"gameOver":      {Type: "boolean"},
+			"moveHistory":   {Type: "array", Items: ref("Move"), Nullable: true, Description: "Moves played so far, in order"},
+		}, "bigBoard", "bigBoardWins", "activeBoard", "currentPlayer"),
+		"ErrorType": stringEnum("Kind of error", errorTypes...),
+		"APIError": object("An error", map[string]*Schema{
+			"type":    ref("ErrorType"),
+			"message": {Type: "string"},
+			"details": {Type: "string"},
+			"code":    {Type: "integer", Description: "HTTP status code"},
+		}, "type", "message", "code"),
+		"ConflictError": object("A move made on an old position, with the game as it is now", map[string]*Schema{
+			"type":    ref("ErrorType"),
+			"message": {Type: "string"},
+			"code":    {Type: "integer"},
+			"game":    ref("GameState"),
+		}, "type", "message", "code", "game"),
+		"Difficulty": {
+			Description: "easy, medium, hard or a level from 1 to 20",
+			OneOf: []*Schema{
+				stringEnum("", "easy", "medium", "hard"),
+				integerRange(ai.MinLevel, ai.MaxLevel, "Strength level"),
+			},
+		},
+		"Style":         stringEnum("Playing style of the built-in AI, at any difficulty but easy and the levels that only play random moves", styles...),
+		"AIMoveRequest": strictObject("Settings of the AI making a move", aiSettings()),
+		"AIOpponent":    strictObject("AI playing one side of a new game", opponent),
+		"Players": strictObject("Names of the people playing", map[string]*Schema{
+			"x": {Type: "string", MaxLength: &maxName},
+			"o": {Type: "string", MaxLength: &maxName},
+		}),
+		"CreateGameRequest": strictObject("A new game", map[string]*Schema{
+			"ai":      ref("AIOpponent"),
+			"players": ref("Players"),
+			"claim":   {Type: "array", Items: ref("Seat"), MaxItems: &twoSeats, Description: "Seats the creator takes, both unless given"},
+		}),
+		"ResignRequest": strictObject("A resignation", map[string]*Schema{
+			"player": ref("Seat"),
+		}, "player"),
+		"JoinRequest": strictObject("A player joining a game", map[string]*Schema{
+			"name": {Type: "string", MaxLength: &maxName, Description: "How the player is listed"},
+		}),
+		"PuzzlePlayerRequest": strictObject("A puzzle player to register", map[string]*Schema{
+			"player": {Type: "string", MaxLength: &maxName, Description: "Player ID to register"},
+		}, "player"),
+		"PuzzleMoveRequest": strictObject("A move in a puzzle", map[string]*Schema{
+			"player": {Type: "string", MaxLength: &maxName, Description: "Player solving the puzzle"},
+			"ply":    integerRange(0, 81, "Position in the solution, 0 for the first move"),
+			"move":   ref("Move"),
+		}, "player", "move"),
+		"AnalysisRequest": strictObject("A position to analyze, given by exactly one of notation or game", map[string]*Schema{
+			"notation": {Type: "string"},
+			"game":     ref("GameState"),
+			"depth":    integerRange(0, ai.MaxAnalysisDepth, "Search depth in plies"),
+		}),
+		"SeatTokens": object("Seat tokens, sent as Bearer tokens to act for a seat", map[string]*Schema{
+			"x":        {Type: "string"},
+			"o":        {Type: "string"},
+			"observer": {Type: "string", Description: "Read-only token"},
+		}, "observer"),
+		"CreatedGame": object("A new game", map[string]*Schema{
+			"gameId":     {Type: "string"},
+			"game":       ref("GameState"),
+			"tokens":     ref("SeatTokens"),
+			"reply":      {Type: "object", Description: "The AI opponent's first move, if it plays X"},
+			"replyError": ref("APIError"),
+			"replyJobId": {Type: "string", Description: "AI job to poll for the first move, if it is still being searched"},
+		}, "gameId", "game", "tokens"),
+		"MoveWithReply": object("A move in a game with an AI opponent", map[string]*Schema{
+			"game":       ref("GameState"),
+			"move":       ref("Move"),
+			"reply":      {Type: "object", Description: "The AI opponent's reply"},
+			"replyError": ref("APIError"),
+			"replyJobId": {Type: "string", Description: "AI job to poll for the reply, if it is still being searched"},
+		}, "game", "move"),
+		"AIMove": object("A move made by the AI", map[string]*Schema{
+			"game":      ref("GameState"),
+			"move":      ref("Move"),
+			"strength":  {Type: "object"},
+			"style":     {Type: "object"},
+			"stats":     {Type: "object"},
+			"ponderHit": {Type: "boolean"},
+			"seed":      {Type: "integer", Format: "int64"},
+		}, "game", "move"),
+		"AcceptedJob": object("An AI search still running, to be polled", map[string]*Schema{
+			"jobId": {Type: "string"},
+			"job":   ref("Job"),
+		}, "jobId", "job"),
+		"Job": object("An AI job", map[string]*Schema{
+			"id":         {Type: "string"},
+			"status":     stringEnum("", string(scheduler.StatusQueued), string(scheduler.StatusRunning), string(scheduler.StatusDone), string(scheduler.StatusFailed)),
+			"result":     {Type: "object", Description: "Code and body of the job's response once it is done"},
+			"error":      {Type: "string"},
+			"waitMs":     {Type: "integer", Description: "Time spent queued"},
+			"runMs":      {Type: "integer"},
+			"createdAt":  timestamp,
+			"finishedAt": timestamp,
+		}, "id", "status", "waitMs", "runMs", "createdAt"),
+		"Summary": object("A game without its board", map[string]*Schema{
+			"gameId":    {Type: "string"},
+			"mode":      stringEnum("", "local", "ai", "multiplayer"),
+			"status":    stringEnum("Lifecycle state", "created", "active", "finished", "abandoned"),
+			"result":    stringEnum("", "x", "o", "draw"),
+			"players":   ref("Players"),
+			"moveCount": {Type: "integer"},
+			"createdAt": timestamp,
+			"updatedAt": timestamp,
+			"version":   {Type: "integer", Description: "Number of the latest event; changes with every change to the game"},
+		}, "gameId", "mode", "status", "moveCount", "createdAt", "updatedAt", "version"),
+		"GameWithSummary": object("A game and its summary", map[string]*Schema{
+			"game":    ref("GameState"),
+			"summary": ref("Summary"),
+		}, "game", "summary"),
+		"GameList": object("A page of games", map[string]*Schema{
+			"games":      arrayOf(ref("Summary"), ""),
+			"nextCursor": {Type: "string", Description: "Cursor of the next page, if there is one"},
+		}, "games"),
+		"JoinedGame": object("A seat claimed in a game", map[string]*Schema{
+			"player":   ref("Player"),
+			"observer": {Type: "boolean"},
+			"token":    {Type: "string"},
+			"game":     ref("GameState"),
+		}, "player", "observer", "token", "game"),
+		"WaitResult": object("A game after a long poll", map[string]*Schema{
+			"game":     ref("GameState"),
+			"summary":  ref("Summary"),
+			"timedOut": {Type: "boolean", Description: "The game did not advance before the timeout"},
+			"aiError":  {Type: "string", Description: "Why the AI to move could not, if that ended the wait"},
+		}, "game", "summary", "timedOut"),
+		"Event": object("A change to a game, the data of a Server-Sent Event", map[string]*Schema{
+			"id":     {Type: "integer"},
+			"type":   stringEnum("", "state", "move", "result", "abandon", "join", "leave", "closed", "aiError"),
+			"gameId": {Type: "string"},
+			"move":   ref("Move"),
+			"player": ref("Player"),
+			"error":  {Type: "string", Description: "Why the AI could not move, for aiError events"},
+			"game":   ref("GameState"),
+			"status": {Type: "string"},
+			"time":   timestamp,
+		}, "id", "type", "gameId", "game", "status", "time"),
+	}
+}
+
+// strictObject is object for request bodies, which may not carry other
+// properties
+func strictObject(description string, properties map[string]*Schema, required ...string) *Schema {
+	schema := object(description, properties, required...)
+	closed := false
+	schema.AdditionalProperties = &closed
+	return schema
+}
+
+// openAPI is the document served at /api/v1/openapi.json
+var openAPI = newOpenAPIDocument()
+
+func newOpenAPIDocument() *openAPIDocument {
+	anyObject := &Schema{Type: "object"}
+	maxBanner := render.MaxBannerLength
+	boardParams := []parameter{
+		gameIDParam,
+		queryParam("ply", "Number of moves into the game to draw, the current position unless given", integerRange(0, 81, "")),
+		queryParam("size", "Width and height in pixels", integerRange(render.MinSize, render.MaxSize, "")),
+		queryParam("theme", "", stringEnum("", render.ThemeNames()...)),
+	}
+	playerParam := pathParam("player", "Puzzle player ID")
+
+	paths := map[string]map[string]*operation{
+		"/health": {
+			"get": newOperation("healthCheck", "Check the server is up", "health").
+				returns(http.StatusOK, "Healthy", anyObject),
+		},
+		"/ws": {
+			"get": newOperation("connectWebSocket", "Open a WebSocket for multiplayer games", "games").
+				returns(http.StatusSwitchingProtocols, "Switched to WebSocket", nil),
+		},
+		"/api/v1/health": {
+			"get": newOperation("apiHealthCheck", "Check the server is up", "health").
+				returns(http.StatusOK, "Healthy", anyObject),
+		},
+		"/api/v1/openapi.json": {
+			"get": newOperation("getOpenAPI", "This document", "health").
+				returns(http.StatusOK, "OpenAPI 3 document", anyObject),
+		},
+		"/api/v1/games": {
+			"post": newOperation("createGame", "Create a game", "games").
+				body("CreateGameRequest", false).
+				returns(http.StatusCreated, "Game created, with the tokens of the claimed seats", ref("CreatedGame")).
+				fails(http.StatusBadRequest),
+			"get": newOperation("listGames", "List games", "games").
+				with(
+					queryParam("status", "", stringEnum("", "created", "active", "finished", "abandoned")),
+					queryParam("mode", "", stringEnum("", "local", "ai", "multiplayer")),
+					queryParam("participant", "Name playing either side", &Schema{Type: "string"}),
+					queryParam("result", "", stringEnum("", "x", "o", "draw")),
+					queryParam("createdAfter", "", timestamp),
+					queryParam("createdBefore", "", timestamp),
+					queryParam("updatedAfter", "", timestamp),
+					queryParam("updatedBefore", "", timestamp),
+					queryParam("sort", "Field to sort by, prefixed with - for descending; -updatedAt unless given",
+						stringEnum("", "createdAt", "-createdAt", "updatedAt", "-updatedAt", "moveCount", "-moveCount")),
+					queryParam("limit", "", integerRange(1, maxListLimit, "")),
+					queryParam("cursor", "nextCursor of the previous page", &Schema{Type: "string"}),
+				).
+				returns(http.StatusOK, "A page of games", ref("GameList")).
+				fails(http.StatusBadRequest),
+		},
+		"/api/v1/games/{id}": {
+			"get": newOperation("getGame", "Get a game; the ETag is its ply and version", "games").
+				with(gameIDParam, headerParam("If-None-Match", "ETag the client has")).
+				returns(http.StatusOK, "The game", ref("GameState")).
+				returns(http.StatusNotModified, "The game has not changed", nil).
+				fails(http.StatusNotFound),
+			"delete": newOperation("deleteGame", "Delete a game without archiving it", "games").
+				with(gameIDParam).
+				returns(http.StatusNoContent, "Deleted", nil).
+				seated().
+				fails(http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/events": {
+			"get": newOperation("streamGameEvents", "Stream the events of a game", "games").
+				with(gameIDParam,
+					headerParam("Last-Event-ID", "ID of the last event seen, to resume"),
+					queryParam("lastEventId", "Last-Event-ID for clients that cannot set headers", &Schema{Type: "integer", Minimum: new(float64)})).
+				returnsContent(http.StatusOK, "Server-Sent Events, each named by type with an Event as data", "text/event-stream", ref("Event")).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/wait": {
+			"get": newOperation("waitForMove", "Wait for the game to advance past a ply", "games").
+				with(gameIDParam,
+					queryParam("afterPly", "Ply to wait past, the current ply unless given", &Schema{Type: "integer", Minimum: new(float64)}),
+					queryParam("timeout", "How long to wait, such as 30s; at most 60s", &Schema{Type: "string"})).
+				returns(http.StatusOK, "The game once it advanced, or as it is at the timeout", ref("WaitResult")).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/board.svg": {
+			"get": newOperation("renderBoardSVG", "Draw a position of the game as SVG", "games").
+				with(boardParams...).
+				returnsContent(http.StatusOK, "The board", "image/svg+xml", &Schema{Type: "string"}).
+				returns(http.StatusNotModified, "The position has not changed", nil).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/board.png": {
+			"get": newOperation("renderBoardPNG", "Draw a position of the game as PNG", "games").
+				with(boardParams...).
+				returnsContent(http.StatusOK, "The board", "image/png", &Schema{Type: "string", Format: "binary"}).
+				returns(http.StatusNotModified, "The position has not changed", nil).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/replay.gif": {
+			"get": newOperation("replayGameGIF", "Animate the game as a GIF, a frame per move, ending on the result", "games").
+				with(gameIDParam,
+					queryParam("size", "Width and height in pixels. Every move adds a frame, so long games allow less.",
+						integerRange(render.MinSize, render.MaxReplaySize, "")),
+					queryParam("theme", "", stringEnum("", render.ThemeNames()...)),
+					queryParam("delay", "How long each move is shown, such as 800ms", &Schema{Type: "string"}),
+					queryParam("finalDelay", "How long the final position is shown before looping, such as 3s", &Schema{Type: "string"}),
+					queryParam("banner", "Whether the final position carries a banner; true unless given", &Schema{Type: "boolean"}),
+					queryParam("bannerText", "Text of the banner, the result unless given",
+						&Schema{Type: "string", MaxLength: &maxBanner})).
+				returnsContent(http.StatusOK, "The replay", "image/gif", &Schema{Type: "string", Format: "binary"}).
+				returns(http.StatusNotModified, "The finished game has not changed", nil).
+				fails(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusServiceUnavailable),
+		},
+		"/share/games/{id}": {
+			"get": newOperation("shareGame", "Page for sharing a game, with an Open Graph preview of the board", "games").
+				with(boardParams...).
+				returnsContent(http.StatusOK, "HTML page", "text/html", &Schema{Type: "string"}).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/join": {
+			"post": newOperation("joinGame", "Claim a free seat, or the observer token if there is none", "games").
+				with(gameIDParam).
+				body("JoinRequest", false).
+				returns(http.StatusOK, "The seat and its token", ref("JoinedGame")).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/resign": {
+			"post": newOperation("resignGame", "Resign a game", "games").
+				with(gameIDParam).
+				body("ResignRequest", true).
+				returns(http.StatusOK, "The finished game", ref("GameWithSummary")).
+				seated().
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/abandon": {
+			"post": newOperation("abandonGame", "Stop a game without a result", "games").
+				with(gameIDParam).
+				returns(http.StatusOK, "The abandoned game", ref("GameWithSummary")).
+				seated().
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/games/{id}/moves": {
+			"post": newOperation("makeMove", "Play a move", "games").
+				with(gameIDParam).
+				with(preconditions...).
+				body("Move", true).
+				returns(http.StatusOK, "The game after the move, with the AI opponent's reply if it has one",
+					&Schema{OneOf: []*Schema{ref("GameState"), ref("MoveWithReply")}}).
+				returns(http.StatusConflict, "The game has moved on", ref("ConflictError")).
+				seated().
+				fails(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity),
+		},
+		"/api/v1/games/{id}/ai-move": {
+			"post": newOperation("makeAIMove", "Have the AI play the side to move", "games").
+				with(gameIDParam, preferAsyncParam).
+				with(preconditions...).
+				body("AIMoveRequest", false).
+				returns(http.StatusOK, "The game after the AI's move", ref("AIMove")).
+				returns(http.StatusAccepted, "The search is still running, for clients preferring async", ref("AcceptedJob")).
+				returns(http.StatusConflict, "The game has moved on", ref("ConflictError")).
+				seated().
+				fails(http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity,
+					http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable),
+		},
+		"/api/v1/games/{id}/analysis": {
+			"get": newOperation("analyzeGame", "Evaluate every legal move of a game", "analysis").
+				with(gameIDParam, depthParam, preferAsyncParam).
+				returns(http.StatusOK, "The analysis", anyObject).
+				returns(http.StatusAccepted, "The search is still running, for clients preferring async", ref("AcceptedJob")).
+				fails(http.StatusBadRequest, http.StatusNotFound,
+					http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable),
+		},
+		"/api/v1/games/{id}/review": {
+			"get": newOperation("reviewGame", "Review a finished game", "analysis").
+				with(gameIDParam, depthParam).
+				returns(http.StatusOK, "The review", anyObject).
+				returns(http.StatusAccepted, "The review is still running", ref("AcceptedJob")).
+				fails(http.StatusBadRequest, http.StatusNotFound,
+					http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable),
+		},
+		"/api/v1/rooms/{id}/review": {
+			"get": newOperation("reviewRoom", "Review a finished multiplayer game", "analysis").
+				with(gameIDParam, depthParam).
+				returns(http.StatusOK, "The review", anyObject).
+				returns(http.StatusAccepted, "The review is still running", ref("AcceptedJob")).
+				fails(http.StatusBadRequest, http.StatusNotFound,
+					http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable),
+		},
+		"/api/v1/analysis": {
+			"post": newOperation("analyzePosition", "Evaluate every legal move of a position", "analysis").
+				with(preferAsyncParam).
+				body("AnalysisRequest", true).
+				returns(http.StatusOK, "The analysis", anyObject).
+				returns(http.StatusAccepted, "The search is still running, for clients preferring async", ref("AcceptedJob")).
+				fails(http.StatusBadRequest,
+					http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable),
+		},
+		"/api/v1/ai/levels": {
+			"get": newOperation("listStrengthLevels", "List the AI's strength levels", "ai").
+				returns(http.StatusOK, "The levels", anyObject),
+		},
+		"/api/v1/ai/engines": {
+			"get": newOperation("listExternalEngines", "List the configured external engines", "ai").
+				returns(http.StatusOK, "The engine names", anyObject),
+		},
+		"/api/v1/ai/styles": {
+			"get": newOperation("listStyles", "List the AI's playing styles", "ai").
+				returns(http.StatusOK, "The styles", anyObject),
+		},
+		"/api/v1/ai/jobs/{id}": {
+			"get": newOperation("getAIJob", "Poll an AI job", "ai").
+				with(pathParam("id", "Job ID")).
+				returns(http.StatusOK, "The job, with its response once done", ref("Job")).
+				fails(http.StatusNotFound),
+		},
+		"/api/v1/puzzles/next": {
+			"get": newOperation("nextPuzzle", "Get the next puzzle for a player", "puzzles").
+				with(queryParam("player", "Puzzle player ID", &Schema{Type: "string"})).
+				returns(http.StatusOK, "A puzzle", anyObject).
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/puzzles/players": {
+			"post": newOperation("registerPuzzlePlayer", "Register a puzzle player and get its token", "puzzles").
+				body("PuzzlePlayerRequest", true).
+				returns(http.StatusCreated, "The player and its token", anyObject).
+				returns(http.StatusConflict, "The player ID is already registered", ref("APIError")).
+				returns(http.StatusTooManyRequests, "The client has registered too many players lately", ref("APIError")).
+				fails(http.StatusBadRequest),
+		},
+		"/api/v1/puzzles/players/{player}": {
+			"get": newOperation("getPuzzlePlayer", "Get a player's puzzle rating", "puzzles").
+				with(playerParam).
+				returns(http.StatusOK, "The player's rating and record", anyObject).
+				fails(http.StatusBadRequest),
+		},
+		"/api/v1/puzzles/{id}": {
+			"get": newOperation("getPuzzle", "Get a puzzle", "puzzles").
+				with(pathParam("id", "Puzzle ID")).
+				returns(http.StatusOK, "The puzzle", anyObject).
+				fails(http.StatusNotFound),
+		},
+		"/api/v1/puzzles/{id}/moves": {
+			"post": newOperation("submitPuzzleMove", "Play a move of a puzzle's solution", "puzzles").
+				with(pathParam("id", "Puzzle ID")).
+				body("PuzzleMoveRequest", true).
+				returns(http.StatusOK, "Whether the move was right, and the reply if the puzzle goes on", anyObject).
+				returns(http.StatusConflict, "The solver's previous move has not been played correctly", ref("APIError")).
+				registered().
+				fails(http.StatusBadRequest, http.StatusNotFound),
+		},
+		"/api/v1/puzzles/{id}/reply": {
+			"get": newOperation("getPuzzleReply", "Get the reply to a solver move already played correctly", "puzzles").
+				with(pathParam("id", "Puzzle ID"),
+					queryParam("player", "Puzzle player ID", &Schema{Type: "string"}),
+					queryParam("ply", "Ply of the solver's move", integerRange(0, 81, ""))).
+				returns(http.StatusOK, "The reply and the ply of the solver's next move", anyObject).
+				registered().
+				fails(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound),
+		},
+	}
+
+	return &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "T-9 Ultimate Tic-Tac-Toe API",
+			Version:     "1.0.0",
+			Description: "Errors are returned as APIError. Moves are made with the seat token issued when a game is created or joined, and puzzle moves with the token issued when a puzzle player registers. Games are public to read: anyone with a game's ID can get it, stream its events and render it without a token. The observer token, issued when a game is created or when no seat is left to join, is read-only: moves made with it are forbidden.",
+		},
+		Servers: []openAPIServer{{URL: "/"}},
+		Paths:   paths,
+		Components: openAPIComponents{
+			Schemas: openAPISchemas,
+			SecuritySchemes: map[string]securityScheme{
+				"seatToken":   {Type: "http", Scheme: "bearer", Description: "Token of a seat in the game"},
+				"puzzleToken": {Type: "http", Scheme: "bearer", Description: "Token issued when a puzzle player registered"},
+			},
+		},
+	}
+}
+
+// GetOpenAPI serves the OpenAPI document
+func GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPI)
+}
+
+// routeParam matches the parameters of gin route paths
+var routeParam = regexp.MustCompile(`:([A-Za-z]+)`)
+
+// checkOpenAPI logs the routes the OpenAPI document does not describe
+func checkOpenAPI(routes gin.RoutesInfo) {
+	for _, route := range routes {
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		if openAPI.Paths[path][strings.ToLower(route.Method)] == nil {
+			logging.DefaultLogger.Warning("Route missing from the OpenAPI document", map[string]interface{}{
+				"method": route.Method,
+				"path":   route.Path,
+			})
+		}
+	}
+}
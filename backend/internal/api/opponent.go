@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/logging"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createGameRequest is the optional body accepted when creating a game
+type createGameRequest struct {
+	AI      *aiOpponent     `json:"ai"`      // AI playing one side, for single-player games
+	Players service.Players `json:"players"` // Names of the people playing
+	Claim   []game.Player   `json:"claim"`   // Seats the creator takes, both unless given
+}
+
+// aiOpponent is the AI set up when a game is created to play one side. The
+// server plays its moves itself, replying to each human move.
+type aiOpponent struct {
+	aiMoveRequest
+	Player game.Player `json:"player"` // Side the AI plays, O unless given
+}
+
+// validate fills in the opponent's defaults and checks its settings
+func (o *aiOpponent) validate() error {
+	if o.Player == game.Empty {
+		o.Player = game.O
+	}
+	if o.Player != game.X && o.Player != game.O {
+		return errors.New("AI player must be 1 (X) or 2 (O)")
+	}
+	return validateAIMoveRequest(o.aiMoveRequest)
+}
+
+// name is how the opponent is listed among a game's players
+func (r aiMoveRequest) name() string {
+	if r.Engine != "" {
+		return r.Engine
+	}
+	return "AI"
+}
+
+// validatePlayerNames checks the player names given when a game is created
+func validatePlayerNames(players service.Players) error {
+	if len(players.X) > maxPlayerIDLength || len(players.O) > maxPlayerIDLength {
+		return fmt.Errorf("player names must be at most %d characters", maxPlayerIDLength)
+	}
+	return nil
+}
+
+// pendingReply is the AI opponent's reply to a move, queued on the scheduler
+type pendingReply struct {
+	ply int // Moves in the game before the reply
+	job *scheduler.Job
+	err error // Why the reply could not be queued
+}
+
+// opponent returns the AI opponent of a game, or nil if it has none
+func (gm *GameManager) opponent(gameID string) *aiOpponent {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.opponents[gameID]
+}
+
+// replyRetries is how many more times a reply the scheduler turned away is
+// tried when nobody waits for it. The first retry comes after
+// replyRetryDelay, and each one after waits twice as long.
+const (
+	replyRetries    = 4
+	replyRetryDelay = time.Second
+)
+
+// replyClient is who the scheduler charges a game's AI replies to. Replies
+// are charged to the game rather than to the player who moved, so the
+// player's own analyses and reviews cannot keep their opponent from moving.
+// A game waits for one reply at a time, so the client limit never holds it
+// up.
+func replyClient(gameID string) string {
+	return "game/" + gameID
+}
+
+// submitReply queues the AI opponent's move at ply
+func (gm *GameManager) submitReply(gameID string, ply int, opponent *aiOpponent) pendingReply {
+	client := replyClient(gameID)
+	job, err := gm.jobs.Submit(client, func(ctx context.Context) (interface{}, error) {
+		return gm.playAIMove(ctx, gameID, precondition{ply: ply, version: service.AnyVersion}, opponent.aiMoveRequest)
+	})
+	if err != nil {
+		logSchedulerError(client, "AI reply", err)
+	}
+	return pendingReply{ply: ply, job: job, err: err}
+}
+
+// playReply plays the AI opponent's move at ply for a client that is not
+// waiting for it, such as a WebSocket player. A reply the scheduler turns
+// away is tried again a few times. If it still cannot be played the error is
+// published to the game's subscribers, so they are not left waiting.
+func (gm *GameManager) playReply(gameID string, ply int, opponent *aiOpponent) {
+	delay := replyRetryDelay
+	for attempt := 0; ; attempt++ {
+		reply := gm.submitReply(gameID, ply, opponent)
+		err := reply.err
+		if err == nil {
+			var result interface{}
+			result, err = reply.job.Result()
+			if err == nil {
+				if response := result.(*jobResponse); replyFailed(response) {
+					gm.reportReplyError(gameID, opponent, response.Body)
+				}
+				return
+			}
+		}
+
+		retryable := errors.Is(err, scheduler.ErrClientBusy) || errors.Is(err, scheduler.ErrQueueFull) || errors.Is(err, scheduler.ErrQueueTimeout)
+		if !retryable || attempt == replyRetries {
+			apiErr, _ := schedulerError("AI reply", err)
+			gm.reportReplyError(gameID, opponent, apiErr)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// replyFailed reports whether the AI opponent's reply could not be played.
+// A reply is not needed if the game moved on or went away.
+func replyFailed(response *jobResponse) bool {
+	switch response.Code {
+	case http.StatusOK, http.StatusConflict, http.StatusNotFound:
+		return false
+	}
+	return true
+}
+
+// watchReply waits for a reply a client asked for and publishes why it
+// failed, if it did. The client may have gone away or been told to poll the
+// job, so the game's subscribers are told rather than left waiting.
+func (gm *GameManager) watchReply(gameID string, opponent *aiOpponent, job *scheduler.Job) {
+	result, err := job.Result()
+	if err != nil {
+		apiErr, _ := schedulerError("AI reply", err)
+		gm.reportReplyError(gameID, opponent, apiErr)
+		return
+	}
+	if response := result.(*jobResponse); replyFailed(response) {
+		gm.reportReplyError(gameID, opponent, response.Body)
+	}
+}
+
+// reportReplyError publishes why the AI opponent could not reply
+func (gm *GameManager) reportReplyError(gameID string, opponent *aiOpponent, body interface{}) {
+	reason := "AI reply failed"
+	if err, ok := body.(error); ok {
+		reason = err.Error()
+	}
+	origin := service.Origin{AI: true, Name: opponent.name()}
+	if err := gm.games.ReportAIError(gameID, opponent.Player, reason, origin); err != nil {
+		return
+	}
+	logging.DefaultLogger.Warning("AI reply failed", map[string]interface{}{
+		"gameId": gameID,
+		"error":  reason,
+	})
+}
+
+// takeReply returns the reply queued by the move that left a game at ply,
+// if any. A reply to any other move is dropped.
+func (gm *GameManager) takeReply(gameID string, ply int) (pendingReply, bool) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	reply, exists := gm.replies[gameID]
+	delete(gm.replies, gameID)
+	return reply, exists && reply.ply == ply
+}
+
+// respondWithReply waits for the AI opponent's reply and writes body with the
+// reply added. If the reply cannot be played body is written with the reason
+// instead, which is also published to the game's subscribers, and the client
+// can ask for the move again with an empty AI move request. The reply is
+// played even if the client does not wait for it. A reply still being
+// searched after the async threshold is left out, and body is written with
+// the ID of its job to poll instead.
+func (gm *GameManager) respondWithReply(c *gin.Context, gameID string, opponent *aiOpponent, reply pendingReply, code int, body gin.H) {
+	failed := func(err error) {
+		body["replyError"], _ = schedulerError("AI reply", err)
+		c.JSON(code, body)
+	}
+	if reply.err != nil {
+		failed(reply.err)
+		gm.reportReplyError(gameID, opponent, body["replyError"])
+		return
+	}
+	go gm.watchReply(gameID, opponent, reply.job)
+
+	awaitDetachedJob(c, reply.job, "AI reply", func() {
+		body["replyJobId"] = reply.job.ID()
+		c.JSON(code, body)
+	}, func(response *jobResponse) {
+		if response.Code != http.StatusOK {
+			body["replyError"] = response.Body
+			c.JSON(code, body)
+			return
+		}
+		body["reply"] = response.Body
+		if replyBody, ok := response.Body.(gin.H); ok {
+			body["game"] = replyBody["game"]
+		}
+		setResponseETag(c, response)
+		c.JSON(code, body)
+	}, func(err error) {
+		logSchedulerError(c.ClientIP(), "AI reply", err)
+		failed(err)
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A reply that cannot be played over REST is published to the game's
+// subscribers as well as written into the response
+func TestReplyErrorIsPublished(t *testing.T) {
+	tests := []struct {
+		name string
+		ai   game.Player // Side the AI plays
+		move bool        // X moves after the game is created
+	}{
+		{name: "reply when the game is created", ai: game.X},
+		{name: "reply to a move", ai: game.O, move: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			jobs := scheduler.New(scheduler.Config{Workers: 1, QueueSize: 4, Retention: time.Minute})
+			games := service.New()
+			gm := NewGameManager(games, jobs)
+			r := gin.New()
+			r.POST("/games", gm.CreateGame)
+			r.POST("/games/:id/moves", gm.MakeMove)
+
+			var mu sync.Mutex
+			var aiErrors []service.Event
+			games.OnEvent(func(event service.Event) {
+				if event.Type == service.EventAIError {
+					mu.Lock()
+					aiErrors = append(aiErrors, event)
+					mu.Unlock()
+				}
+			})
+
+			if !tt.move {
+				// No reply can be queued from here on
+				jobs.Close()
+			}
+			w := httptest.NewRecorder()
+			body := fmt.Sprintf(`{"ai":{"difficulty":1,"player":%d}}`, tt.ai)
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/games", strings.NewReader(body)))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("creating: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+			}
+			var created struct {
+				GameID     string          `json:"gameId"`
+				Tokens     service.Tokens  `json:"tokens"`
+				ReplyError json.RawMessage `json:"replyError"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+				t.Fatalf("reading created game: %v", err)
+			}
+			replyError := created.ReplyError
+
+			if tt.move {
+				jobs.Close()
+				move := `{"bigBoardIndex":4,"smallBoardIndex":4,"player":1}`
+				req := httptest.NewRequest(http.MethodPost, "/games/"+created.GameID+"/moves", strings.NewReader(move))
+				req.Header.Set("Authorization", "Bearer "+created.Tokens.X)
+				w = httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("moving: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+				}
+				var moved struct {
+					ReplyError json.RawMessage `json:"replyError"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &moved); err != nil {
+					t.Fatalf("reading move: %v", err)
+				}
+				replyError = moved.ReplyError
+			}
+
+			if len(replyError) == 0 {
+				t.Errorf("response has no replyError: %s", w.Body)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(aiErrors) != 1 {
+				t.Fatalf("published %d AI errors, want 1", len(aiErrors))
+			}
+			if event := aiErrors[0]; event.GameID != created.GameID || event.Player != tt.ai || event.Error == "" {
+				t.Errorf("AI error event = %+v, want one for player %v of game %s", event, tt.ai, created.GameID)
+			}
+		})
+	}
+}
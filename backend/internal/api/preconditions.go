@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"t-9/internal/game"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conflictError is a move made on an old position, sent with the game as it
+// is now
+type conflictError struct {
+	*APIError
+	Game *game.GameState `json:"game"`
+}
+
+// gameETag returns the entity tag of a game: its ply, the number of moves
+// played so far, and its version, which changes with every change to the
+// game, so a resignation or abandonment gets a new tag too
+func gameETag(summary service.Summary) string {
+	return fmt.Sprintf(`"%d.%d"`, summary.MoveCount, summary.Version)
+}
+
+// setGameETag sets the ETag header to the game's entity tag
+func setGameETag(c *gin.Context, summary service.Summary) {
+	c.Header("ETag", gameETag(summary))
+}
+
+// setResponseETag sets the ETag header to the entity tag of the game a job
+// response carries, if it has one
+func setResponseETag(c *gin.Context, response *jobResponse) {
+	if response.etag != "" {
+		c.Header("ETag", response.etag)
+	}
+}
+
+// precondition is what a move request expects of the game before its move:
+// the ply, and with If-Match also the version. Either may be service.AnyPly
+// or service.AnyVersion.
+type precondition struct {
+	ply, version int
+}
+
+// anyPosition is the precondition of a move request that has none
+var anyPosition = precondition{ply: service.AnyPly, version: service.AnyVersion}
+
+// holds reports whether a game with summary meets the precondition
+func (p precondition) holds(summary service.Summary) bool {
+	return (p.ply == service.AnyPly || p.ply == summary.MoveCount) &&
+		(p.version == service.AnyVersion || p.version == summary.Version)
+}
+
+// movePrecondition reads the precondition of a move request, given as the
+// game's ETag in If-Match or as the expectedPly query parameter. The whole tag
+// is compared, so a move is turned away after any change to the game, even
+// one that added no move.
+func movePrecondition(c *gin.Context) (precondition, error) {
+	expected := anyPosition
+	if value := c.Query("expectedPly"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return precondition{}, errors.New("expectedPly must be a number of moves")
+		}
+		expected.ply = n
+	}
+
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return expected, nil
+	}
+	invalid := errors.New("If-Match must be a single ETag of the game")
+	if len(header) < 2 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		return precondition{}, invalid
+	}
+	plyTag, versionTag, found := strings.Cut(header[1:len(header)-1], ".")
+	ply, plyErr := strconv.Atoi(plyTag)
+	version, versionErr := strconv.Atoi(versionTag)
+	if !found || plyErr != nil || versionErr != nil || ply < 0 || version < 0 {
+		return precondition{}, invalid
+	}
+	if expected.ply != service.AnyPly && expected.ply != ply {
+		return precondition{}, errors.New("If-Match and expectedPly disagree")
+	}
+	return precondition{ply: ply, version: version}, nil
+}
+
+// staleResponse answers a move made with a precondition the game no longer
+// meets
+func (gm *GameManager) staleResponse(gameID string, expected precondition) *jobResponse {
+	gameState, summary, err := gm.games.Get(gameID)
+	if err != nil {
+		return newJobResponse(http.StatusNotFound, NewNotFoundError("Game"))
+	}
+	message := fmt.Sprintf("Game has moved on: expected %d moves, it has %d", expected.ply, summary.MoveCount)
+	if expected.ply == service.AnyPly || expected.ply == summary.MoveCount {
+		message = fmt.Sprintf("Game has changed: expected version %d, it is at %d", expected.version, summary.Version)
+	}
+	return newJobResponse(http.StatusConflict, &conflictError{
+		APIError: NewConflictError(message),
+		Game:     gameState,
+	}).withETag(summary)
+}
+
+// respondWithStale writes the response to a move made with a precondition
+// the game no longer meets
+func (gm *GameManager) respondWithStale(c *gin.Context, gameID string, expected precondition) {
+	response := gm.staleResponse(gameID, expected)
+	setResponseETag(c, response)
+	c.JSON(response.Code, response.Body)
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/logging"
+	"t-9/internal/puzzle"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxPlayerIDLength bounds the player IDs puzzle ratings are kept under
+	maxPlayerIDLength = 64
+
+	// One client may register registrationLimit puzzle players in each
+	// registrationWindow
+	registrationLimit  = 5
+	registrationWindow = time.Hour
+
+	// puzzleUpkeepInterval is how often the puzzle store is saved, if it
+	// changed, and abandoned attempts are dropped
+	puzzleUpkeepInterval = 10 * time.Second
+)
+
+// PuzzleManager serves puzzles and keeps players' puzzle ratings
+type PuzzleManager struct {
+	store *puzzle.Store
+
+	mu            sync.Mutex
+	registrations map[string]registrations // Recent registrations of each client
+}
+
+// registrations counts a client's registrations in the window that started
+// at start
+type registrations struct {
+	start time.Time
+	count int
+}
+
+func NewPuzzleManager(store *puzzle.Store) *PuzzleManager {
+	return &PuzzleManager{store: store, registrations: map[string]registrations{}}
+}
+
+// RunUpkeep saves the puzzle store when it has changed, and forgets
+// abandoned attempts and old registration counts, until the process exits
+func (pm *PuzzleManager) RunUpkeep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		pm.store.ExpireAttempts(now)
+		if err := pm.store.SaveIfChanged(); err != nil {
+			logging.DefaultLogger.Error("Failed to save puzzles", err, nil)
+		}
+
+		pm.mu.Lock()
+		for client, recent := range pm.registrations {
+			if now.Sub(recent.start) > registrationWindow {
+				delete(pm.registrations, client)
+			}
+		}
+		pm.mu.Unlock()
+	}
+}
+
+// allowRegistration counts a registration by a client and reports whether
+// it is within the client's limit
+func (pm *PuzzleManager) allowRegistration(client string, now time.Time) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	recent := pm.registrations[client]
+	if now.Sub(recent.start) > registrationWindow {
+		recent = registrations{start: now}
+	}
+	if recent.count >= registrationLimit {
+		return false
+	}
+	recent.count++
+	pm.registrations[client] = recent
+	return true
+}
+
+// puzzleView is a puzzle as shown to a player, without its solution
+type puzzleView struct {
+	ID       string          `json:"id"`
+	Position string          `json:"position"`
+	Game     *game.GameState `json:"game"`
+	Player   game.Player     `json:"player"` // The side the solver plays
+	Moves    int             `json:"moves"`  // Moves the solver has to find
+	Rating   int             `json:"rating"`
+	Plays    int             `json:"plays"`
+}
+
+// newPuzzleView prepares a puzzle for a player
+func newPuzzleView(p puzzle.Puzzle) (puzzleView, error) {
+	state, err := p.State()
+	if err != nil {
+		return puzzleView{}, err
+	}
+	return puzzleView{
+		ID:       p.ID,
+		Position: p.Position,
+		Game:     state,
+		Player:   state.CurrentPlayer,
+		Moves:    p.SolverMoves(),
+		Rating:   p.Rating,
+		Plays:    p.Plays,
+	}, nil
+}
+
+// NextPuzzle returns the unplayed puzzle closest to the player's rating
+func (pm *PuzzleManager) NextPuzzle(c *gin.Context) {
+	playerID, ok := puzzlePlayerID(c, c.Query("player"))
+	if !ok {
+		return
+	}
+
+	next, found := pm.store.Next(playerID)
+	if !found {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Puzzle").WithDetails("No unplayed puzzles left"))
+		return
+	}
+	pm.respondWithPuzzle(c, next)
+}
+
+// GetPuzzle returns a puzzle by ID
+func (pm *PuzzleManager) GetPuzzle(c *gin.Context) {
+	found, exists := pm.store.Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Puzzle"))
+		return
+	}
+	pm.respondWithPuzzle(c, found)
+}
+
+// RegisterPuzzlePlayer registers a player ID and issues the token its
+// puzzle moves are made with. Each ID can be registered once, and each
+// client may only register a few an hour.
+func (pm *PuzzleManager) RegisterPuzzlePlayer(c *gin.Context) {
+	var request struct {
+		Player string `json:"player"`
+	}
+	if err := bindValidJSON(c, "PuzzlePlayerRequest", &request); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+		return
+	}
+	playerID, ok := puzzlePlayerID(c, request.Player)
+	if !ok {
+		return
+	}
+	if !pm.allowRegistration(c.ClientIP(), time.Now()) {
+		c.Header("Retry-After", strconv.Itoa(int(registrationWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, NewOverloadedError("Too many puzzle players registered", http.StatusTooManyRequests).
+			WithDetails(fmt.Sprintf("a client may register %d players per %v", registrationLimit, registrationWindow)))
+		return
+	}
+
+	token, err := pm.store.Register(playerID)
+	if errors.Is(err, puzzle.ErrPlayerTaken) {
+		c.JSON(http.StatusConflict, NewConflictError(err.Error()))
+		return
+	}
+
+	logging.DefaultLogger.Info("Puzzle player registered", map[string]interface{}{
+		"player":   playerID,
+		"clientIP": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"player": playerID,
+		"token":  token,
+	})
+}
+
+// SubmitPuzzleMove checks a solver move, made with the player's token. The
+// solver's moves are played in order from ply 0. A correct move is answered
+// with the opponent's expected reply; a wrong move or the last correct move
+// finishes the puzzle and updates the ratings.
+func (pm *PuzzleManager) SubmitPuzzleMove(c *gin.Context) {
+	var request struct {
+		Player string    `json:"player" binding:"required"`
+		Ply    int       `json:"ply"` // Position in the solution, 0 for the first move
+		Move   game.Move `json:"move"`
+	}
+	if err := bindValidJSON(c, "PuzzleMoveRequest", &request); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+		return
+	}
+	playerID, ok := pm.authorizePuzzlePlayer(c, request.Player)
+	if !ok {
+		return
+	}
+
+	found, exists := pm.store.Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Puzzle"))
+		return
+	}
+	if request.Ply > 0 {
+		if answered, ok := pm.store.Progress(playerID, found.ID); !ok || answered != request.Ply-2 {
+			c.JSON(http.StatusConflict, NewConflictError(fmt.Sprintf("Ply %d comes after a correct move at ply %d", request.Ply, request.Ply-2)))
+			return
+		}
+	}
+
+	attempt, err := found.Check(request.Ply, request.Move)
+	if errors.Is(err, puzzle.ErrInvalidPuzzle) {
+		c.JSON(http.StatusInternalServerError, NewInternalError("Stored puzzle is invalid").WithDetails(err.Error()))
+		return
+	}
+	if errors.Is(err, puzzle.ErrWrongPly) {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewGameLogicError(err.Error()))
+		return
+	}
+
+	response := gin.H{"attempt": attempt}
+	if attempt.Correct && !attempt.Solved {
+		pm.store.Answered(playerID, found.ID, request.Ply)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	result, _ := pm.store.Finish(playerID, found.ID, attempt.Solved)
+	if !attempt.Solved {
+		response["solution"] = found.Solution
+	}
+	response["result"] = result
+
+	logging.DefaultLogger.Info("Puzzle finished", map[string]interface{}{
+		"puzzleId": found.ID,
+		"player":   playerID,
+		"solved":   attempt.Solved,
+		"clientIP": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPuzzleReply returns the opponent's expected reply to the solver's move
+// at a ply, for a client that lost the answer to the move. It needs the
+// player's token and is only given once that move was played correctly in
+// the attempt under way.
+func (pm *PuzzleManager) GetPuzzleReply(c *gin.Context) {
+	playerID, ok := pm.authorizePuzzlePlayer(c, c.Query("player"))
+	if !ok {
+		return
+	}
+	ply, err := strconv.Atoi(c.Query("ply"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("ply must be a number"))
+		return
+	}
+
+	found, exists := pm.store.Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Puzzle"))
+		return
+	}
+	answered, ok := pm.store.Progress(playerID, found.ID)
+	if !ok || ply < 0 || ply%2 != 0 || ply > answered {
+		c.JSON(http.StatusForbidden, NewForbiddenError(fmt.Sprintf("The move at ply %d has not been played correctly", ply)))
+		return
+	}
+
+	moves, err := found.Moves()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError("Stored puzzle is invalid").WithDetails(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ply":     ply,
+		"reply":   moves[ply+1],
+		"nextPly": ply + 2,
+	})
+}
+
+// GetPuzzlePlayer returns a player's puzzle rating and record
+func (pm *PuzzleManager) GetPuzzlePlayer(c *gin.Context) {
+	playerID, ok := puzzlePlayerID(c, c.Param("player"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pm.store.Player(playerID))
+}
+
+// respondWithPuzzle writes a puzzle without its solution
+func (pm *PuzzleManager) respondWithPuzzle(c *gin.Context, p puzzle.Puzzle) {
+	view, err := newPuzzleView(p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError("Stored puzzle is invalid").WithDetails(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// authorizePuzzlePlayer validates a player ID and checks that the request
+// carries the player's token as "Bearer <token>". Otherwise it writes the
+// error and returns false.
+func (pm *PuzzleManager) authorizePuzzlePlayer(c *gin.Context, playerID string) (string, bool) {
+	playerID, ok := puzzlePlayerID(c, playerID)
+	if !ok {
+		return "", false
+	}
+	if err := pm.store.Authenticate(playerID, seatToken(c)); err != nil {
+		logging.DefaultLogger.Warning("Puzzle token rejected", map[string]interface{}{
+			"player":   playerID,
+			"clientIP": c.ClientIP(),
+		})
+		c.Header("WWW-Authenticate", `Bearer realm="t-9"`)
+		c.JSON(http.StatusUnauthorized, NewUnauthorizedError("The player's puzzle token is required").WithDetails(err.Error()))
+		return "", false
+	}
+	return playerID, true
+}
+
+// puzzlePlayerID validates a player ID, writing an error if it is invalid
+func puzzlePlayerID(c *gin.Context, playerID string) (string, bool) {
+	if playerID == "" || len(playerID) > maxPlayerIDLength {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("player must be 1-64 characters"))
+		return "", false
+	}
+	return playerID, true
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"t-9/internal/logging"
+	"t-9/internal/render"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseFrameDelay reads how long a replay frame is shown, as a duration
+// such as "800ms" or a number of milliseconds. Zero leaves the default.
+func parseFrameDelay(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		milliseconds, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, fmt.Errorf("%s must be a duration such as 800ms", name)
+		}
+		delay = time.Duration(milliseconds) * time.Millisecond
+	}
+	return delay, nil
+}
+
+// replayRequest reads the options of a replay request: delay, finalDelay,
+// banner, bannerText, size and theme
+func replayRequest(c *gin.Context) (render.ReplayOptions, error) {
+	options := render.ReplayOptions{
+		Options:    render.Options{Theme: c.Query("theme")},
+		BannerText: c.Query("bannerText"),
+	}
+	if value := c.Query("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fmt.Errorf("size must be a number of pixels")
+		}
+		options.Size = size
+	}
+	var err error
+	if options.Delay, err = parseFrameDelay("delay", c.Query("delay")); err != nil {
+		return options, err
+	}
+	if options.FinalDelay, err = parseFrameDelay("finalDelay", c.Query("finalDelay")); err != nil {
+		return options, err
+	}
+	if value := c.Query("banner"); value != "" {
+		banner, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("banner must be true or false")
+		}
+		options.NoBanner = !banner
+	}
+	return options, options.Check()
+}
+
+// ReplayGame animates a game as a GIF, a frame per move from the empty
+// board, ending on a banner with the result. Drawing a long game takes a
+// while, so it runs as a job on the AI scheduler.
+func ReplayGame(games *service.GameService, jobs *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+		gameState, summary, err := games.Get(gameID)
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+		options, err := replayRequest(c)
+		if err == nil {
+			err = options.CheckFor(len(gameState.MoveHistory))
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+
+		// A finished game replays the same forever
+		setGameETag(c, summary)
+		if gameState.GameOver {
+			c.Header("Cache-Control", "public, max-age=86400")
+		} else {
+			c.Header("Cache-Control", "no-cache")
+		}
+		if c.GetHeader("If-None-Match") == gameETag(summary) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		job, err := jobs.Submit(c.ClientIP(), func(ctx context.Context) (interface{}, error) {
+			var image bytes.Buffer
+			if err := render.GIF(&image, gameState, options); err != nil {
+				logging.DefaultLogger.Error("Replay failed", err, map[string]interface{}{
+					"gameId": gameID,
+				})
+				return newJobResponse(http.StatusInternalServerError, NewInternalError("Failed to render replay").WithDetails(err.Error())), nil
+			}
+			return newJobResponse(http.StatusOK, image.Bytes()), nil
+		})
+		if err != nil {
+			respondWithSchedulerError(c, "Replay", err)
+			return
+		}
+		waitForJob(c, job, "Replay", false, nil, func(response *jobResponse) {
+			if image, ok := response.Body.([]byte); ok {
+				c.Data(response.Code, "image/gif", image)
+				return
+			}
+			c.JSON(response.Code, response.Body)
+		}, func(err error) {
+			respondWithSchedulerError(c, "Replay", err)
+		})
+	}
+}
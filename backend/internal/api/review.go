@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"t-9/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewGame runs a post-game review of a finished game. The review is a job
+// on the AI scheduler; one still running after the async threshold is
+// answered with the job's ID to poll.
+func (gm *GameManager) ReviewGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	finished, _, err := gm.games.Get(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("Game"))
+		return
+	}
+
+	gm.respondWithReview(c, gameID, finished)
+}
+
+// respondWithReview reviews a finished game as a job on the AI scheduler and
+// writes the result
+func (gm *GameManager) respondWithReview(c *gin.Context, gameID string, finished *game.GameState) {
+	if !finished.GameOver {
+		c.JSON(http.StatusBadRequest, NewGameLogicError("Game is not finished yet"))
+		return
+	}
+
+	depth := 0
+	if value := c.Query("depth"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError("depth must be a number"))
+			return
+		}
+		depth = parsed
+	}
+
+	client := c.ClientIP()
+	job, err := gm.jobs.Submit(client, func(ctx context.Context) (interface{}, error) {
+		review, err := ai.ReviewGame(ctx, finished.MoveHistory, finished.GameWon, depth)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			logging.DefaultLogger.Error("Game review failed", err, map[string]interface{}{
+				"gameId": gameID,
+			})
+			return newJobResponse(http.StatusInternalServerError, NewInternalError("Failed to review game").WithDetails(err.Error())), nil
+		}
+
+		logging.DefaultLogger.Info("Game reviewed", map[string]interface{}{
+			"gameId":   gameID,
+			"clientIP": client,
+		})
+		return newJobResponse(http.StatusOK, review), nil
+	})
+	if err != nil {
+		respondWithSchedulerError(c, "Game review", err)
+		return
+	}
+	awaitPolledJob(c, job, "Game review")
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReviewResignedGame(t *testing.T) {
+	tests := []struct {
+		name     string
+		moves    []game.Move
+		resigner game.Player
+		want     game.Player
+	}{
+		{
+			name:     "X resigns before moving",
+			resigner: game.X,
+			want:     game.O,
+		},
+		{
+			name: "O resigns after two moves",
+			moves: []game.Move{
+				{BigBoardIndex: 4, SmallBoardIndex: 4, Player: game.X},
+				{BigBoardIndex: 4, SmallBoardIndex: 0, Player: game.O},
+			},
+			resigner: game.O,
+			want:     game.X,
+		},
+		{
+			name: "X resigns with O to move",
+			moves: []game.Move{
+				{BigBoardIndex: 0, SmallBoardIndex: 8, Player: game.X},
+			},
+			resigner: game.X,
+			want:     game.O,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			jobs := scheduler.New(scheduler.Config{Workers: 1, QueueSize: 4, Retention: time.Minute})
+			t.Cleanup(jobs.Close)
+			games := service.New()
+			gm := NewGameManager(games, jobs)
+			r := gin.New()
+			r.GET("/games/:id/review", gm.ReviewGame)
+
+			gameID, _, _ := games.Create(service.CreateOptions{Mode: service.ModeLocal})
+			for _, move := range tt.moves {
+				if _, err := games.Move(gameID, move, service.Origin{}); err != nil {
+					t.Fatalf("playing %+v: %v", move, err)
+				}
+			}
+			if _, err := games.Resign(gameID, tt.resigner, service.Origin{}); err != nil {
+				t.Fatalf("resigning: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/"+gameID+"/review?depth=1", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			var review ai.GameReview
+			if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil {
+				t.Fatalf("reading review: %v", err)
+			}
+			if review.Result != tt.want {
+				t.Errorf("result = %v, want %v", review.Result, tt.want)
+			}
+			if len(review.Moves) != len(tt.moves) {
+				t.Errorf("reviewed %d moves, want %d", len(review.Moves), len(tt.moves))
+			}
+		})
+	}
+}
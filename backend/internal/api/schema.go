@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Schema is the part of an OpenAPI 3.0 schema object the API uses. The
+// schemas are both published in the OpenAPI document and used to validate
+// request bodies.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// ref returns a schema referring to a named schema of the document
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// integerRange returns an integer schema bounded by min and max
+func integerRange(min, max int, description string) *Schema {
+	low, high := float64(min), float64(max)
+	return &Schema{Type: "integer", Minimum: &low, Maximum: &high, Description: description}
+}
+
+// stringEnum returns a string schema taking one of values
+func stringEnum(description string, values ...string) *Schema {
+	enum := make([]interface{}, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	return &Schema{Type: "string", Enum: enum, Description: description}
+}
+
+// object returns an object schema with the given properties, of which
+// required must be present. Other properties are rejected.
+func object(description string, properties map[string]*Schema, required ...string) *Schema {
+	closed := false
+	return &Schema{
+		Type:                 "object",
+		Description:          description,
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &closed,
+	}
+}
+
+// arrayOf returns an array schema of items
+func arrayOf(items *Schema, description string) *Schema {
+	return &Schema{Type: "array", Items: items, Description: description}
+}
+
+// validate checks a decoded JSON value against the schema. Named schemas are
+// looked up in schemas. The error names the offending field by its path.
+func (s *Schema) validate(value interface{}, path string, schemas map[string]*Schema) error {
+	if s.Ref != "" {
+		named, ok := schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", describePath(path), s.Ref)
+		}
+		return named.validate(value, path, schemas)
+	}
+	if value == nil {
+		if s.Nullable || (s.Type == "" && len(s.OneOf) == 0) {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", describePath(path))
+	}
+
+	for _, part := range s.AllOf {
+		if err := part.validate(value, path, schemas); err != nil {
+			return err
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		var firstErr error
+		for _, option := range s.OneOf {
+			if err := option.validate(value, path, schemas); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			matched++
+		}
+		if matched != 1 {
+			if s.Description != "" {
+				return fmt.Errorf("%s must be %s", describePath(path), s.Description)
+			}
+			return firstErr
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		return s.validateObject(value, path, schemas)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", describePath(path))
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			return fmt.Errorf("%s must have at least %d items", describePath(path), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			return fmt.Errorf("%s must have at most %d items", describePath(path), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), schemas); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", describePath(path))
+		}
+		if s.MinLength != nil && len(text) < *s.MinLength {
+			return fmt.Errorf("%s must be at least %d characters", describePath(path), *s.MinLength)
+		}
+		if s.MaxLength != nil && len(text) > *s.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters", describePath(path), *s.MaxLength)
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s must be a number", describePath(path))
+		}
+		if s.Type == "integer" && number != math.Trunc(number) {
+			return fmt.Errorf("%s must be an integer", describePath(path))
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return fmt.Errorf("%s must be at least %v", describePath(path), *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fmt.Errorf("%s must be at most %v", describePath(path), *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", describePath(path))
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if enumEqual(allowed, value) {
+				return nil
+			}
+		}
+		options := make([]string, len(s.Enum))
+		for i, allowed := range s.Enum {
+			options[i] = fmt.Sprint(allowed)
+		}
+		return fmt.Errorf("%s must be one of %s", describePath(path), strings.Join(options, ", "))
+	}
+	return nil
+}
+
+// validateObject checks the properties of an object value
+func (s *Schema) validateObject(value interface{}, path string, schemas map[string]*Schema) error {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be an object", describePath(path))
+	}
+	for _, name := range s.Required {
+		if _, exists := fields[name]; !exists {
+			return fmt.Errorf("%s is required", describePath(joinPath(path, name)))
+		}
+	}
+
+	// Check fields in a fixed order so the same body gives the same error
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, known := s.Properties[name]
+		if !known {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s is not a known field", describePath(joinPath(path, name)))
+			}
+			continue
+		}
+		if err := property.validate(fields[name], joinPath(path, name), schemas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enumEqual compares an enum value with a decoded JSON value, in which
+// every number is a float64
+func enumEqual(allowed, value interface{}) bool {
+	if n, ok := allowed.(int); ok {
+		allowed = float64(n)
+	}
+	return allowed == value
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
+
+// bindValidJSON validates the request body against the named schema of the
+// OpenAPI document and decodes it into target. An empty body is reported as
+// io.EOF, as ShouldBindJSON does, for handlers where the body is optional.
+func bindValidJSON(c *gin.Context, schema string, target interface{}) error {
+	body, err := c.GetRawData()
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return io.EOF
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return err
+	}
+	if err := ref(schema).validate(value, "", openAPISchemas); err != nil {
+		return err
+	}
+	return json.Unmarshal(body, target)
+}
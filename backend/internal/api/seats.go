@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"t-9/internal/game"
+	"t-9/internal/logging"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// seatToken returns the token sent in the Authorization header as
+// "Bearer <token>"
+func seatToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(header[len("Bearer "):])
+	}
+	return ""
+}
+
+// authorizeSeat checks that the request carries the token of player's seat,
+// or of either seat if player is Empty. Otherwise it writes the error and
+// returns false.
+func (gm *GameManager) authorizeSeat(c *gin.Context, gameID string, player game.Player) bool {
+	seat, err := gm.games.Authorize(gameID, seatToken(c))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewNotFoundError("Game"))
+		return false
+	case err != nil:
+		logging.DefaultLogger.Warning("Seat token rejected", map[string]interface{}{
+			"gameId":   gameID,
+			"error":    err.Error(),
+			"clientIP": c.ClientIP(),
+		})
+		c.Header("WWW-Authenticate", `Bearer realm="t-9"`)
+		c.JSON(http.StatusUnauthorized, NewUnauthorizedError("A valid seat token is required").WithDetails(err.Error()))
+		return false
+	case seat == game.Empty:
+		c.JSON(http.StatusForbidden, NewForbiddenError("Observer tokens are read-only"))
+		return false
+	case player != game.Empty && seat != player:
+		c.JSON(http.StatusForbidden, NewForbiddenError("Token is for player "+seat.String()+", not "+player.String()))
+		return false
+	}
+	return true
+}
+
+// JoinGame claims the first free seat of a game and returns its token.
+// Without a free seat the read-only observer token is returned.
+func (gm *GameManager) JoinGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	// The body is optional
+	var request struct {
+		Name string `json:"name"` // How the player is listed
+	}
+	if err := bindValidJSON(c, "JoinRequest", &request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError("Invalid request format: "+err.Error()))
+		return
+	}
+	if err := validatePlayerNames(service.Players{X: request.Name}); err != nil {
+		c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+		return
+	}
+
+	player, token, err := gm.games.Claim(gameID, request.Name, service.Origin{Client: c.ClientIP()})
+	if err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+	gameState, _, err := gm.games.Get(gameID)
+	if err != nil {
+		respondWithGameError(c, err)
+		return
+	}
+
+	logging.DefaultLogger.Info("Game joined", map[string]interface{}{
+		"gameId":   gameID,
+		"player":   player.String(),
+		"clientIP": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"player":   player,
+		"observer": player == game.Empty,
+		"token":    token,
+		"game":     gameState,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The observer token is handed out with a game and to a client that finds no
+// free seat. It is accepted to read the game, and refused for anything that
+// acts for a seat.
+func TestObserverToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jobs := scheduler.New(scheduler.Config{Workers: 1, QueueSize: 4, Retention: time.Minute})
+	t.Cleanup(jobs.Close)
+	games := service.New()
+	gm := NewGameManager(games, jobs)
+
+	r := gin.New()
+	r.GET("/games/:id", gm.GetGame)
+	r.POST("/games/:id/join", gm.JoinGame)
+	r.POST("/games/:id/moves", gm.MakeMove)
+	r.POST("/games/:id/ai-move", gm.MakeAIMove)
+	r.POST("/games/:id/resign", gm.ResignGame)
+	r.POST("/games/:id/abandon", gm.AbandonGame)
+	r.DELETE("/games/:id", gm.DeleteGame)
+
+	gameID, _, tokens := games.Create(service.CreateOptions{
+		Mode:  service.ModeLocal,
+		Claim: []game.Player{game.X, game.O},
+	})
+	if tokens.Observer == "" {
+		t.Fatal("no observer token issued")
+	}
+	if player, err := games.Authorize(gameID, tokens.Observer); err != nil || player != game.Empty {
+		t.Fatalf("Authorize(observer) = %v, %v; want Empty, nil", player, err)
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokens.Observer)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(http.MethodGet, "/games/"+gameID, ""); w.Code != http.StatusOK {
+		t.Errorf("reading: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// Both seats are taken, so joining gives the observer token
+	w := request(http.MethodPost, "/games/"+gameID+"/join", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("joining: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var joined struct {
+		Observer bool   `json:"observer"`
+		Token    string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &joined); err != nil {
+		t.Fatalf("reading join: %v", err)
+	}
+	if !joined.Observer || joined.Token != tokens.Observer {
+		t.Errorf("joined as observer %v with token %q, want the observer token %q", joined.Observer, joined.Token, tokens.Observer)
+	}
+
+	writes := []struct {
+		name, method, path, body string
+	}{
+		{"move", http.MethodPost, "/moves", `{"bigBoardIndex":4,"smallBoardIndex":4,"player":1}`},
+		{"AI move", http.MethodPost, "/ai-move", `{"difficulty":"easy"}`},
+		{"resign", http.MethodPost, "/resign", `{"player":1}`},
+		{"abandon", http.MethodPost, "/abandon", ""},
+		{"delete", http.MethodDelete, "", ""},
+	}
+	for _, write := range writes {
+		if w := request(write.method, "/games/"+gameID+write.path, write.body); w.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want %d: %s", write.name, w.Code, http.StatusForbidden, w.Body)
+		}
+	}
+
+	gameState, _, err := games.Get(gameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gameState.MoveHistory) != 0 || gameState.GameOver {
+		t.Errorf("observer changed the game: %d moves, over %v", len(gameState.MoveHistory), gameState.GameOver)
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"t-9/internal/logging"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle event stream sends a comment, so
+// proxies do not close it
+const streamKeepAlive = 15 * time.Second
+
+// lastEventID reads the ID of the last event a reconnecting client saw, from
+// the Last-Event-ID header or the lastEventId query parameter for clients
+// that cannot set headers. It is -1 for a client that saw none.
+func lastEventID(c *gin.Context) (int, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	if value == "" {
+		return -1, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("Last-Event-ID must be an event ID")
+	}
+	return id, nil
+}
+
+// StreamGameEvents streams the events of a game as Server-Sent Events. A new
+// client first gets the game's state; a client resuming with Last-Event-ID
+// gets the events it missed, or if they are no longer kept the moves it
+// missed and then the state. The stream ends after the game is closed.
+func StreamGameEvents(games *service.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+		after, err := lastEventID(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+
+		sub, missed, err := games.SubscribeAfter(gameID, after)
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+		defer sub.Close()
+
+		logging.DefaultLogger.Info("Event stream opened", map[string]interface{}{
+			"gameId":      gameID,
+			"lastEventId": after,
+			"clientIP":    c.ClientIP(),
+		})
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // Stop nginx buffering the stream
+		c.Status(http.StatusOK)
+
+		for _, event := range missed {
+			if err := writeEvent(c.Writer, event); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Dropped for falling behind, or the game was closed;
+					// a client that reconnects picks up where it left off
+					return
+				}
+				if err := writeEvent(c.Writer, event); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				if event.Type == service.EventClosed {
+					return
+				}
+
+			case <-keepAlive.C:
+				if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format, named by its
+// type and with its ID for resuming
+func writeEvent(w io.Writer, event service.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
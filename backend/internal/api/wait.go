@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 60 * time.Second
+)
+
+// parseWaitTimeout reads how long a long poll may block, as a duration such
+// as "30s" or a number of seconds
+func parseWaitTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(value)
+		if atoiErr != nil {
+			return 0, fmt.Errorf("timeout must be a duration such as 30s")
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout < 0 || timeout > maxWaitTimeout {
+		return 0, fmt.Errorf("timeout must be between 0s and %s", maxWaitTimeout)
+	}
+	return timeout, nil
+}
+
+// waitOver reports whether a long poll waiting for a move after ply can
+// return: the game has advanced past it, or no more moves will come
+func waitOver(gameState *game.GameState, summary service.Summary, ply int) bool {
+	return len(gameState.MoveHistory) > ply || gameState.GameOver || summary.Status == service.StateAbandoned
+}
+
+// WaitForMove long-polls a game. It blocks until the game advances past
+// afterPly, the current ply unless given, or the timeout expires, and then
+// returns the game. The game is also returned at once if it is over, since
+// no more moves will come, or if the AI to move reports it cannot, with
+// aiError saying why; timedOut tells a client whether anything changed.
+func WaitForMove(games *service.GameService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID := c.Param("id")
+		timeout, err := parseWaitTimeout(c.Query("timeout"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewInvalidInputError(err.Error()))
+			return
+		}
+
+		// Subscribe before looking at the game so no move is missed
+		sub, err := games.Subscribe(gameID)
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+		defer func() { sub.Close() }()
+		gameState, summary, err := games.Get(gameID)
+		if err != nil {
+			respondWithGameError(c, err)
+			return
+		}
+
+		ply := len(gameState.MoveHistory)
+		if value := c.Query("afterPly"); value != "" {
+			ply, err = strconv.Atoi(value)
+			if err != nil || ply < 0 {
+				c.JSON(http.StatusBadRequest, NewInvalidInputError("afterPly must be a number of moves"))
+				return
+			}
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut := false
+		aiError := ""
+		for !timedOut && aiError == "" && !waitOver(gameState, summary, ply) {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Dropped for falling behind or closed with the game;
+					// start again from the game as it is now
+					renewed, err := games.Subscribe(gameID)
+					if err != nil {
+						respondWithGameError(c, err)
+						return
+					}
+					sub = renewed
+				} else if event.Type == service.EventJoin || event.Type == service.EventLeave {
+					continue
+				} else if event.Type == service.EventAIError {
+					// The move waited for will not come
+					aiError = event.Error
+				}
+				gameState, summary, err = games.Get(gameID)
+				if err != nil {
+					respondWithGameError(c, err)
+					return
+				}
+			case <-timer.C:
+				timedOut = true
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+
+		setGameETag(c, summary)
+		response := gin.H{
+			"game":     gameState,
+			"summary":  summary,
+			"timedOut": timedOut,
+		}
+		if aiError != "" {
+			response["aiError"] = aiError
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,302 @@
+package arena
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"t-9/internal/nn"
+	"t-9/internal/protocol"
+	"time"
+)
+
+// EngineSpec describes an engine configuration that can play either colour
+type EngineSpec struct {
+	Name string
+	New  func(player game.Player, seed int64) (ai.Engine, error)
+}
+
+// ParseEngineSpec reads an engine configuration: "easy", "medium", "hard",
+// "level:N" for a strength level, "rating:N" for the level closest to a
+// measured rating, "nn:FILE" or "nn:FILE@N" for a neural network evaluator
+// searching at level N (default 20), "weights:FILE" or "weights:FILE@N" for
+// evaluation weights loaded from a file, or "ext:COMMAND" for an external
+// protocol engine. External engines get moveTime per move.
+func ParseEngineSpec(spec string, moveTime time.Duration) (EngineSpec, error) {
+	kind, value, _ := strings.Cut(strings.TrimSpace(spec), ":")
+
+	switch kind {
+	case "easy", "medium", "hard":
+		difficulty := map[string]ai.Difficulty{"easy": ai.Easy, "medium": ai.Medium, "hard": ai.Hard}[kind]
+		return EngineSpec{
+			Name: kind,
+			New: func(player game.Player, seed int64) (ai.Engine, error) {
+				return ai.NewSeededAIPlayer(difficulty, player, seed), nil
+			},
+		}, nil
+	case "level", "rating":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return EngineSpec{}, fmt.Errorf("engine %q: %s must be a number", spec, kind)
+		}
+		strength := ai.StrengthForRating(number)
+		if kind == "level" {
+			if number < ai.MinLevel || number > ai.MaxLevel {
+				return EngineSpec{}, fmt.Errorf("engine %q: level must be between %d and %d", spec, ai.MinLevel, ai.MaxLevel)
+			}
+			strength = ai.StrengthForLevel(number)
+		}
+		return EngineSpec{
+			Name: fmt.Sprintf("level %d", strength.Level),
+			New: func(player game.Player, seed int64) (ai.Engine, error) {
+				return ai.NewSeededLeveledAIPlayer(strength, player, seed), nil
+			},
+		}, nil
+	case "nn":
+		path, level, err := parseFileLevel(spec, value)
+		if err != nil {
+			return EngineSpec{}, err
+		}
+		network, err := nn.LoadFile(path)
+		if err != nil {
+			return EngineSpec{}, fmt.Errorf("engine %q: %w", spec, err)
+		}
+		strength := ai.StrengthForLevel(level)
+		return EngineSpec{
+			Name: fmt.Sprintf("nn %s level %d", filepath.Base(path), level),
+			New: func(player game.Player, seed int64) (ai.Engine, error) {
+				aiPlayer := ai.NewSeededLeveledAIPlayer(strength, player, seed)
+				aiPlayer.SetEvaluator(network)
+				return aiPlayer, nil
+			},
+		}, nil
+	case "weights":
+		path, level, err := parseFileLevel(spec, value)
+		if err != nil {
+			return EngineSpec{}, err
+		}
+		weights, err := ai.LoadWeightsFile(path)
+		if err != nil {
+			return EngineSpec{}, fmt.Errorf("engine %q: %w", spec, err)
+		}
+		strength := ai.StrengthForLevel(level)
+		return EngineSpec{
+			Name: fmt.Sprintf("weights %s level %d", filepath.Base(path), level),
+			New: func(player game.Player, seed int64) (ai.Engine, error) {
+				aiPlayer := ai.NewSeededLeveledAIPlayer(strength, player, seed)
+				aiPlayer.SetWeights(weights)
+				return aiPlayer, nil
+			},
+		}, nil
+	case "ext":
+		if strings.TrimSpace(value) == "" {
+			return EngineSpec{}, fmt.Errorf("engine %q: missing command", spec)
+		}
+		return EngineSpec{
+			Name: filepath.Base(strings.Fields(value)[0]),
+			New: func(player game.Player, seed int64) (ai.Engine, error) {
+				return protocol.StartExternalEngine(value, protocol.Limits{MoveTime: moveTime})
+			},
+		}, nil
+	default:
+		return EngineSpec{}, fmt.Errorf("unknown engine %q", spec)
+	}
+}
+
+// parseFileLevel reads the "FILE" or "FILE@N" value of an engine spec. The
+// level defaults to the strongest.
+func parseFileLevel(spec, value string) (string, int, error) {
+	path, levelText, hasLevel := strings.Cut(value, "@")
+	if path == "" {
+		return "", 0, fmt.Errorf("engine %q: missing file", spec)
+	}
+	if !hasLevel {
+		return path, ai.MaxLevel, nil
+	}
+
+	level, err := strconv.Atoi(levelText)
+	if err != nil || level < ai.MinLevel || level > ai.MaxLevel {
+		return "", 0, fmt.Errorf("engine %q: level must be between %d and %d", spec, ai.MinLevel, ai.MaxLevel)
+	}
+	return path, level, nil
+}
+
+// Config describes a match between two engines
+type Config struct {
+	Engine1     EngineSpec
+	Engine2     EngineSpec
+	Games       int
+	Openings    [][]game.Move
+	Concurrency int
+	Seed        int64
+	SPRT        *SPRT
+
+	// OnGame is called after every finished game with its record and the
+	// running result. Calls are serialised.
+	OnGame func(record *game.Record, result Result)
+}
+
+// Run plays the match. Each opening is played twice with colours swapped.
+// With an SPRT configured the match stops early once the test is decided.
+// Cancelling ctx stops the match and returns the result so far.
+func Run(ctx context.Context, config Config) (Result, error) {
+	if len(config.Openings) == 0 {
+		config.Openings = DefaultOpenings()
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		result   Result
+		firstErr error
+		stopped  bool
+		wg       sync.WaitGroup
+	)
+
+	jobs := make(chan int)
+	for w := 0; w < config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				record, points, err := playArenaGame(ctx, config, index)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+					stopped = true
+				case !stopped:
+					switch points {
+					case 1:
+						result.Wins++
+					case 0.5:
+						result.Draws++
+					default:
+						result.Losses++
+					}
+					if config.OnGame != nil {
+						config.OnGame(record, result)
+					}
+					if config.SPRT != nil && config.SPRT.Decide(result) != "" {
+						stopped = true
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for index := 0; index < config.Games; index++ {
+		mu.Lock()
+		done := stopped
+		mu.Unlock()
+		if done || ctx.Err() != nil {
+			break
+		}
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, firstErr
+}
+
+// SelfPlay plays an engine against itself over the default openings and
+// returns the game records. onGame, if not nil, is called after every game.
+func SelfPlay(ctx context.Context, engine EngineSpec, games, concurrency int, seed int64, onGame func(record *game.Record, played int)) ([]*game.Record, error) {
+	var records []*game.Record
+	_, err := Run(ctx, Config{
+		Engine1:     engine,
+		Engine2:     engine,
+		Games:       games,
+		Concurrency: concurrency,
+		Seed:        seed,
+		OnGame: func(record *game.Record, result Result) {
+			records = append(records, record)
+			if onGame != nil {
+				onGame(record, result.Games())
+			}
+		},
+	})
+	return records, err
+}
+
+// playArenaGame plays one game of a match and returns its record and the
+// first engine's points
+func playArenaGame(ctx context.Context, config Config, index int) (*game.Record, float64, error) {
+	opening := config.Openings[(index/2)%len(config.Openings)]
+	seed := config.Seed + int64(index)*2
+
+	// Engine 1 plays X in even games and O in odd games
+	xSpec, oSpec := config.Engine1, config.Engine2
+	if index%2 == 1 {
+		xSpec, oSpec = config.Engine2, config.Engine1
+	}
+
+	x, err := xSpec.New(game.X, seed)
+	if err != nil {
+		return nil, 0, fmt.Errorf("start %s: %w", xSpec.Name, err)
+	}
+	defer closeEngine(x)
+	o, err := oSpec.New(game.O, seed+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("start %s: %w", oSpec.Name, err)
+	}
+	defer closeEngine(o)
+
+	state, err := ai.PlayGame(ctx, x, o, opening)
+	if err != nil {
+		return nil, 0, fmt.Errorf("game %d (%s vs %s): %w", index+1, xSpec.Name, oSpec.Name, err)
+	}
+
+	record := game.NewRecord(state, map[string]string{
+		"Event":   "arena",
+		"Round":   strconv.Itoa(index + 1),
+		"X":       xSpec.Name,
+		"O":       oSpec.Name,
+		"Opening": formatMoves(opening),
+	})
+
+	engine1 := game.X
+	if index%2 == 1 {
+		engine1 = game.O
+	}
+
+	switch state.GameWon {
+	case engine1:
+		return record, 1, nil
+	case game.Empty:
+		return record, 0.5, nil
+	default:
+		return record, 0, nil
+	}
+}
+
+// closeEngine stops engines that hold resources, such as external processes
+func closeEngine(engine ai.Engine) {
+	if closer, ok := engine.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// formatMoves writes moves in record notation
+func formatMoves(moves []game.Move) string {
+	parts := make([]string, len(moves))
+	for i, move := range moves {
+		parts[i] = game.FormatMove(move)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,80 @@
+package arena
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"t-9/internal/game"
+)
+
+// defaultOpenings is a fixed suite of two-move openings covering the main
+// first moves: center, corner and edge boards, each answered a few ways
+var defaultOpenings = []string{
+	"44 40", "44 41", "44 43",
+	"40 00", "40 04", "40 08",
+	"41 14", "41 10", "41 11",
+	"04 44", "04 40",
+	"00 04", "00 08",
+	"01 14", "10 04", "14 44",
+}
+
+// DefaultOpenings returns the built-in opening suite
+func DefaultOpenings() [][]game.Move {
+	openings := make([][]game.Move, 0, len(defaultOpenings))
+	for _, line := range defaultOpenings {
+		moves, err := parseOpening(line)
+		if err != nil {
+			panic(err)
+		}
+		openings = append(openings, moves)
+	}
+	return openings
+}
+
+// LoadOpenings reads one opening per line in record move notation. Blank
+// lines and lines starting with # are skipped.
+func LoadOpenings(r io.Reader) ([][]game.Move, error) {
+	var openings [][]game.Move
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		moves, err := parseOpening(line)
+		if err != nil {
+			return nil, fmt.Errorf("opening on line %d: %w", lineNumber, err)
+		}
+		openings = append(openings, moves)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return openings, nil
+}
+
+// parseOpening parses an opening and checks that its moves are legal and do
+// not finish the game
+func parseOpening(line string) ([]game.Move, error) {
+	moves, err := game.ParseMoves(line)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &game.Record{Moves: moves}
+	state, err := record.Replay()
+	if err != nil {
+		return nil, err
+	}
+	if state.GameOver {
+		return nil, fmt.Errorf("opening %q finishes the game", line)
+	}
+
+	return moves, nil
+}
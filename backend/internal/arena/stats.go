@@ -0,0 +1,107 @@
+package arena
+
+import (
+	"math"
+	"t-9/internal/ai"
+)
+
+// Result counts the games of a match from the first engine's point of view
+type Result struct {
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+}
+
+// Games returns the number of games played
+func (r Result) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Score returns the first engine's average score per game
+func (r Result) Score() float64 {
+	if r.Games() == 0 {
+		return 0.5
+	}
+	return (float64(r.Wins) + 0.5*float64(r.Draws)) / float64(r.Games())
+}
+
+// variance returns the per-game variance of the score. A match whose games
+// all ended the same way has none, which would leave the LLR undefined just
+// when the match is most one-sided, so it is regularised with a pseudo win
+// and a pseudo loss, as other SPRT tools do.
+func (r Result) variance() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	if r.Wins == r.Games() || r.Draws == r.Games() || r.Losses == r.Games() {
+		r.Wins++
+		r.Losses++
+	}
+	n := float64(r.Games())
+	score := r.Score()
+	w, d, l := float64(r.Wins)/n, float64(r.Draws)/n, float64(r.Losses)/n
+	return w*math.Pow(1-score, 2) + d*math.Pow(0.5-score, 2) + l*math.Pow(0-score, 2)
+}
+
+// Elo returns the rating difference implied by the score and the margin of
+// its 95% confidence interval
+func (r Result) Elo() (float64, float64) {
+	score := r.Score()
+	elo := ai.EloDifference(score)
+	if r.Games() == 0 {
+		return elo, math.Inf(1)
+	}
+
+	deviation := math.Sqrt(r.variance() / float64(r.Games()))
+	low := ai.EloDifference(score - 1.96*deviation)
+	high := ai.EloDifference(score + 1.96*deviation)
+	return elo, (high - low) / 2
+}
+
+// LLR returns the log-likelihood ratio of H1 (elo1) against H0 (elo0) using
+// the normal approximation to the trinomial game outcome model
+func (r Result) LLR(elo0, elo1 float64) float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	variance := r.variance()
+
+	s0 := eloToScore(elo0)
+	s1 := eloToScore(elo1)
+	return (s1 - s0) * (2*r.Score() - s0 - s1) / (2 * variance / float64(r.Games()))
+}
+
+// SPRT is a sequential probability ratio test that stops a match once it is
+// clear whether the first engine is elo1 stronger (H1) rather than elo0 (H0)
+type SPRT struct {
+	Elo0  float64
+	Elo1  float64
+	Alpha float64
+	Beta  float64
+}
+
+// Bounds returns the lower and upper LLR bounds of the test
+func (s SPRT) Bounds() (float64, float64) {
+	lower := math.Log(s.Beta / (1 - s.Alpha))
+	upper := math.Log((1 - s.Beta) / s.Alpha)
+	return lower, upper
+}
+
+// Decide returns "H1" or "H0" once the test is decided, or "" while it is not
+func (s SPRT) Decide(r Result) string {
+	llr := r.LLR(s.Elo0, s.Elo1)
+	lower, upper := s.Bounds()
+	switch {
+	case llr >= upper:
+		return "H1"
+	case llr <= lower:
+		return "H0"
+	default:
+		return ""
+	}
+}
+
+// eloToScore converts a rating difference into an expected score
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config represents the application configuration
@@ -11,6 +12,9 @@ type Config struct {
 	Database DatabaseConfig
 	Logging  LoggingConfig
 	CORS     CORSConfig
+	AI       AIConfig
+	Puzzles  PuzzleConfig
+	Games    GameConfig
 }
 
 // ServerConfig contains server-related configuration
@@ -19,6 +23,7 @@ type ServerConfig struct {
 	ReadTimeout  int
 	WriteTimeout int
 	IdleTimeout  int
+	PublicURL    string // Address clients reach the server at, for links the server hands out
 }
 
 // DatabaseConfig contains database-related configuration
@@ -41,14 +46,45 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
+// AIConfig contains AI-related configuration
+type AIConfig struct {
+	ExternalEngines  map[string]string // Engine name to command line
+	ExternalMoveTime int               // Milliseconds per external engine move
+	WeightsFile      string            // Evaluation weights, see cmd/tune
+	Ponder           bool              // Search on the opponent's time in REST games
+	PonderTime       int               // Milliseconds a single ponder search may run
+	Workers          int               // AI searches run at once
+	QueueSize        int               // AI searches waiting for a worker
+	QueueWait        int               // Milliseconds a search may wait for a worker
+	ClientJobs       int               // AI searches one client may have queued or running
+	AsyncAfter       int               // Milliseconds before a search is answered with a job ID, for clients preferring async
+}
+
+// PuzzleConfig contains puzzle-related configuration
+type PuzzleConfig struct {
+	File string // Puzzles and player ratings, see cmd/puzzles
+}
+
+// GameConfig contains configuration for the lifecycle of REST games
+type GameConfig struct {
+	CreatedTTL   int    // Seconds a game with no moves is kept before it is abandoned
+	ActiveTTL    int    // Seconds a game in progress may sit idle before it is abandoned
+	FinishedTTL  int    // Seconds a finished game is kept before it is archived
+	AbandonedTTL int    // Seconds an abandoned game is kept before it is archived
+	ReapInterval int    // Seconds between checks for games past their TTL
+	ArchiveFile  string // Archived games are appended here, empty to drop them
+}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
+	port := getEnv("SERVER_PORT", "8080")
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
+			Port:         port,
 			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 15),
 			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 15),
 			IdleTimeout:  getEnvAsInt("SERVER_IDLE_TIMEOUT", 60),
+			PublicURL:    strings.TrimSuffix(getEnv("SERVER_PUBLIC_URL", "http://localhost:"+port), "/"),
 		},
 		Database: DatabaseConfig{
 			URL:     getEnv("DATABASE_URL", ""),
@@ -68,6 +104,29 @@ func LoadConfig() *Config {
 			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
 			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
 		},
+		AI: AIConfig{
+			ExternalEngines:  getEnvAsMap("AI_EXTERNAL_ENGINES"),
+			ExternalMoveTime: getEnvAsInt("AI_EXTERNAL_MOVE_TIME", 1000),
+			WeightsFile:      getEnv("AI_WEIGHTS_FILE", ""),
+			Ponder:           getEnvAsBool("AI_PONDER", false),
+			PonderTime:       getEnvAsInt("AI_PONDER_TIME", 10000),
+			Workers:          getEnvAsInt("AI_WORKERS", 4),
+			QueueSize:        getEnvAsInt("AI_QUEUE_SIZE", 32),
+			QueueWait:        getEnvAsInt("AI_QUEUE_WAIT", 10000),
+			ClientJobs:       getEnvAsInt("AI_CLIENT_JOBS", 2),
+			AsyncAfter:       getEnvAsInt("AI_ASYNC_AFTER", 2000),
+		},
+		Puzzles: PuzzleConfig{
+			File: getEnv("PUZZLE_FILE", "puzzles.json"),
+		},
+		Games: GameConfig{
+			CreatedTTL:   getEnvAsInt("GAME_CREATED_TTL", 3600),
+			ActiveTTL:    getEnvAsInt("GAME_ACTIVE_TTL", 86400),
+			FinishedTTL:  getEnvAsInt("GAME_FINISHED_TTL", 3600),
+			AbandonedTTL: getEnvAsInt("GAME_ABANDONED_TTL", 3600),
+			ReapInterval: getEnvAsInt("GAME_REAP_INTERVAL", 60),
+			ArchiveFile:  getEnv("GAME_ARCHIVE_FILE", "games-archive.jsonl"),
+		},
 	}
 }
 
@@ -89,6 +148,16 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsBool gets environment variable as boolean with default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvAsSlice gets environment variable as string slice with default value
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
@@ -99,5 +168,18 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// getEnvAsMap gets environment variable as a map from "key=value" pairs
+// separated by semicolons
+func getEnvAsMap(key string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		name, value, found := strings.Cut(pair, "=")
+		if found && strings.TrimSpace(name) != "" {
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
 // Default configuration instance
 var DefaultConfig = LoadConfig()
\ No newline at end of file
@@ -1,12 +1,16 @@
 package game
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidMove     = errors.New("invalid move")
 	ErrGameOver        = errors.New("game is over")
 	ErrWrongBoard      = errors.New("must play in specified board")
 	ErrPositionTaken   = errors.New("position already taken")
+	ErrOutOfRange      = errors.New("board index must be between 0 and 8")
 )
 
 // NewGame creates a new Ultimate Tic-Tac-Toe game
@@ -18,7 +22,32 @@ func NewGame() *GameState {
 		CurrentPlayer: X,
 		GameWon:       Empty,
 		GameOver:      false,
+		MoveHistory:   []Move{},
+	}
+}
+
+// Clone returns a deep copy of the game state
+func (g *GameState) Clone() *GameState {
+	clone := *g
+	clone.MoveHistory = append([]Move{}, g.MoveHistory...)
+	return &clone
+}
+
+// Resign ends the game with player's opponent as the winner
+func (g *GameState) Resign(player Player) error {
+	if g.GameOver {
+		return ErrGameOver
+	}
+	switch player {
+	case X:
+		g.GameWon = O
+	case O:
+		g.GameWon = X
+	default:
+		return ErrInvalidMove
 	}
+	g.GameOver = true
+	return nil
 }
 
 // IsValidMove checks if a move is valid
@@ -27,6 +56,10 @@ func (g *GameState) IsValidMove(move Move) error {
 		return ErrGameOver
 	}
 
+	if move.BigBoardIndex < 0 || move.BigBoardIndex > 8 || move.SmallBoardIndex < 0 || move.SmallBoardIndex > 8 {
+		return ErrOutOfRange
+	}
+
 	if move.Player != g.CurrentPlayer {
 		return ErrInvalidMove
 	}
@@ -71,8 +104,18 @@ func (g *GameState) findLegalMove(move Move) error {
 	return ErrInvalidMove
 }
 
-// MakeMove applies a move to the game state
+// MakeMove applies a move to the game state and records it in the history
 func (g *GameState) MakeMove(move Move) error {
+	if err := g.PlayMove(move); err != nil {
+		return err
+	}
+	g.MoveHistory = append(g.MoveHistory, move)
+	return nil
+}
+
+// PlayMove applies a move without recording it in the history, for searches
+// that only need the position
+func (g *GameState) PlayMove(move Move) error {
 	if err := g.IsValidMove(move); err != nil {
 		return err
 	}
@@ -115,6 +158,53 @@ func (g *GameState) MakeMove(move Move) error {
 	return nil
 }
 
+// Validate checks that a game state is internally consistent, so that states
+// received from clients can be used safely
+func (g *GameState) Validate() error {
+	for i := 0; i < 9; i++ {
+		for j := 0; j < 9; j++ {
+			if g.BigBoard[i][j] < Empty || g.BigBoard[i][j] > O {
+				return fmt.Errorf("board %d cell %d has invalid value %d", i, j, g.BigBoard[i][j])
+			}
+		}
+		if g.BigBoardWins[i] != checkSmallBoardWin(&g.BigBoard[i]) {
+			return fmt.Errorf("bigBoardWins[%d] does not match board", i)
+		}
+	}
+
+	if g.CurrentPlayer != X && g.CurrentPlayer != O {
+		return errors.New("currentPlayer must be X or O")
+	}
+
+	if g.ActiveBoard < -1 || g.ActiveBoard > 8 {
+		return errors.New("activeBoard must be between -1 and 8")
+	}
+
+	winner := checkBigBoardWin(&g.BigBoardWins)
+	if g.GameWon != winner {
+		return errors.New("gameWon does not match board")
+	}
+	if g.GameOver != (winner != Empty || g.isBoardFull()) {
+		return errors.New("gameOver does not match board")
+	}
+
+	if !g.GameOver && g.ActiveBoard != -1 &&
+		(g.BigBoardWins[g.ActiveBoard] != Empty || g.isSmallBoardFull(g.ActiveBoard)) {
+		return errors.New("activeBoard must be an open board")
+	}
+
+	return nil
+}
+
+// refreshResults recomputes board wins and the game result from the cells
+func (g *GameState) refreshResults() {
+	for i := 0; i < 9; i++ {
+		g.BigBoardWins[i] = checkSmallBoardWin(&g.BigBoard[i])
+	}
+	g.GameWon = checkBigBoardWin(&g.BigBoardWins)
+	g.GameOver = g.GameWon != Empty || g.isBoardFull()
+}
+
 // checkSmallBoardWin checks if a 3x3 board has a winner
 func checkSmallBoardWin(board *SmallBoard) Player {
 	// Check rows
@@ -169,6 +259,45 @@ func checkBigBoardWin(wins *BigBoardWins) Player {
 	return Empty
 }
 
+// WinningLines are the rows, columns and diagonals of a 3x3 board
+var WinningLines = [8][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+// WinningLine returns the small boards forming the line that won the game,
+// or nil if the game was not won on the board
+func (g *GameState) WinningLine() []int {
+	if g.GameWon == Empty {
+		return nil
+	}
+	for _, line := range WinningLines {
+		if g.BigBoardWins[line[0]] == g.GameWon && g.BigBoardWins[line[1]] == g.GameWon && g.BigBoardWins[line[2]] == g.GameWon {
+			return line[:]
+		}
+	}
+	return nil
+}
+
+// PositionAt returns the game as it was after its first ply moves. The
+// current game is returned as it is, keeping a resignation.
+func (g *GameState) PositionAt(ply int) (*GameState, error) {
+	if ply < 0 || ply > len(g.MoveHistory) {
+		return nil, fmt.Errorf("ply must be between 0 and %d", len(g.MoveHistory))
+	}
+	if ply == len(g.MoveHistory) {
+		return g.Clone(), nil
+	}
+	position := NewGame()
+	for _, move := range g.MoveHistory[:ply] {
+		if err := position.MakeMove(move); err != nil {
+			return nil, err
+		}
+	}
+	return position, nil
+}
+
 // isBoardFull checks if all legal moves are exhausted
 func (g *GameState) isBoardFull() bool {
 	for i := 0; i < 9; i++ {
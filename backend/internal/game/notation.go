@@ -0,0 +1,124 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidNotation = errors.New("invalid position notation")
+
+// Position notation describes a game state in a single line:
+//
+//	<board 0>/<board 1>/.../<board 8> <side to move> <active board>
+//
+// Each board is nine cells in reading order using 'x', 'o' and '.', the side
+// to move is 'x' or 'o' and the active board is a digit 0-8 or '-' for any.
+// The starting position is:
+//
+//	........./........./........./........./........./........./........./........./......... x -
+const StartNotation = "........./........./........./........./........./........./........./........./......... x -"
+
+// ParseNotation builds a game state from position notation
+func ParseNotation(notation string) (*GameState, error) {
+	fields := strings.Fields(notation)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 fields, got %d", ErrInvalidNotation, len(fields))
+	}
+
+	boards := strings.Split(fields[0], "/")
+	if len(boards) != 9 {
+		return nil, fmt.Errorf("%w: expected 9 boards, got %d", ErrInvalidNotation, len(boards))
+	}
+
+	g := NewGame()
+	for i, board := range boards {
+		if len(board) != 9 {
+			return nil, fmt.Errorf("%w: board %d must have 9 cells", ErrInvalidNotation, i)
+		}
+		for j := 0; j < 9; j++ {
+			player, ok := parseCell(board[j])
+			if !ok {
+				return nil, fmt.Errorf("%w: unexpected cell %q in board %d", ErrInvalidNotation, board[j], i)
+			}
+			g.BigBoard[i][j] = player
+		}
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "x":
+		g.CurrentPlayer = X
+	case "o":
+		g.CurrentPlayer = O
+	default:
+		return nil, fmt.Errorf("%w: side to move must be x or o", ErrInvalidNotation)
+	}
+
+	if fields[2] == "-" {
+		g.ActiveBoard = -1
+	} else {
+		active, err := strconv.Atoi(fields[2])
+		if err != nil || active < 0 || active > 8 {
+			return nil, fmt.Errorf("%w: active board must be 0-8 or -", ErrInvalidNotation)
+		}
+		g.ActiveBoard = active
+	}
+
+	g.refreshResults()
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNotation, err)
+	}
+
+	return g, nil
+}
+
+// Notation returns the position notation for the game state
+func (g *GameState) Notation() string {
+	var sb strings.Builder
+	for i := 0; i < 9; i++ {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		for j := 0; j < 9; j++ {
+			sb.WriteByte(formatCell(g.BigBoard[i][j]))
+		}
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteByte(formatCell(g.CurrentPlayer))
+	sb.WriteByte(' ')
+	if g.ActiveBoard == -1 {
+		sb.WriteByte('-')
+	} else {
+		sb.WriteString(strconv.Itoa(g.ActiveBoard))
+	}
+
+	return sb.String()
+}
+
+// parseCell converts a notation character to a player
+func parseCell(c byte) (Player, bool) {
+	switch c {
+	case 'x', 'X':
+		return X, true
+	case 'o', 'O':
+		return O, true
+	case '.':
+		return Empty, true
+	default:
+		return Empty, false
+	}
+}
+
+// formatCell converts a player to its notation character
+func formatCell(p Player) byte {
+	switch p {
+	case X:
+		return 'x'
+	case O:
+		return 'o'
+	default:
+		return '.'
+	}
+}
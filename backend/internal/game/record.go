@@ -0,0 +1,280 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidRecord = errors.New("invalid game record")
+
+// Game records store a whole game as text, similar to chess PGN:
+//
+//	[Event "arena"]
+//	[X "level 12"]
+//	[O "hard"]
+//	[Result "1-0"]
+//
+//	44 40 04 41 14 ... 1-0
+//
+// Each move is written as two digits, the big board then the small board.
+// Moves alternate starting with X. Records in a file are separated by a
+// blank line.
+const (
+	ResultXWins      = "1-0"
+	ResultOWins      = "0-1"
+	ResultDraw       = "1/2-1/2"
+	ResultInProgress = "*"
+)
+
+// movesPerLine controls how record move text is wrapped
+const movesPerLine = 20
+
+// Record is a game together with descriptive tags
+type Record struct {
+	Tags  map[string]string
+	Moves []Move
+}
+
+// NewRecord creates a record of a game, setting the Result tag from its state
+func NewRecord(state *GameState, tags map[string]string) *Record {
+	record := &Record{
+		Tags:  map[string]string{},
+		Moves: append([]Move{}, state.MoveHistory...),
+	}
+	for key, value := range tags {
+		record.Tags[key] = value
+	}
+	record.Tags["Result"] = ResultString(state)
+	return record
+}
+
+// ResultString returns the record result token for a game state
+func ResultString(state *GameState) string {
+	switch {
+	case !state.GameOver:
+		return ResultInProgress
+	case state.GameWon == X:
+		return ResultXWins
+	case state.GameWon == O:
+		return ResultOWins
+	default:
+		return ResultDraw
+	}
+}
+
+// Replay plays the record's moves from the start and returns the final state
+func (r *Record) Replay() (*GameState, error) {
+	state := NewGame()
+	for i, move := range r.Moves {
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("%w: move %d: %v", ErrInvalidRecord, i+1, err)
+		}
+	}
+	return state, nil
+}
+
+// String formats the record as text
+func (r *Record) String() string {
+	var sb strings.Builder
+
+	keys := make([]string, 0, len(r.Tags))
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "[%s %q]\n", key, r.Tags[key])
+	}
+	sb.WriteByte('\n')
+
+	for i, move := range r.Moves {
+		if i > 0 {
+			if i%movesPerLine == 0 {
+				sb.WriteByte('\n')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(FormatMove(move))
+	}
+
+	result := r.Tags["Result"]
+	if result == "" {
+		result = ResultInProgress
+	}
+	if len(r.Moves) > 0 {
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(result)
+	sb.WriteByte('\n')
+
+	return sb.String()
+}
+
+// FormatMove writes a move as its big and small board digits
+func FormatMove(move Move) string {
+	return fmt.Sprintf("%d%d", move.BigBoardIndex, move.SmallBoardIndex)
+}
+
+// ParseMove reads a move written by FormatMove
+func ParseMove(text string, player Player) (Move, error) {
+	if len(text) != 2 || text[0] < '0' || text[0] > '8' || text[1] < '0' || text[1] > '8' {
+		return Move{}, fmt.Errorf("%w: %q is not a move", ErrInvalidRecord, text)
+	}
+	return Move{
+		BigBoardIndex:   int(text[0] - '0'),
+		SmallBoardIndex: int(text[1] - '0'),
+		Player:          player,
+	}, nil
+}
+
+// ParseMoves reads a space separated list of moves, alternating from X
+func ParseMoves(text string) ([]Move, error) {
+	moves := []Move{}
+	player := X
+	for _, token := range strings.Fields(text) {
+		move, err := ParseMove(token, player)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+		if player == X {
+			player = O
+		} else {
+			player = X
+		}
+	}
+	return moves, nil
+}
+
+// WriteRecords writes records separated by blank lines
+func WriteRecords(w io.Writer, records ...*Record) error {
+	for _, record := range records {
+		if _, err := io.WriteString(w, record.String()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRecords parses every record in a reader
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	var current *Record
+	var moveText strings.Builder
+	inMoves := false
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		moves, err := ParseMoves(moveText.String())
+		if err != nil {
+			return err
+		}
+		current.Moves = moves
+		records = append(records, current)
+		current = nil
+		inMoves = false
+		moveText.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			// A blank line ends the move text of a record
+			if inMoves {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+		case strings.HasPrefix(line, "["):
+			if inMoves {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			if current == nil {
+				current = &Record{Tags: map[string]string{}}
+			}
+			key, value, err := parseTag(line)
+			if err != nil {
+				return nil, err
+			}
+			current.Tags[key] = value
+		default:
+			if current == nil {
+				current = &Record{Tags: map[string]string{}}
+			}
+			inMoves = true
+			for _, token := range strings.Fields(line) {
+				if !isResultToken(token) {
+					moveText.WriteString(token + " ")
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ReadRecordFiles reads every record in a list of files
+func ReadRecordFiles(paths []string) ([]*Record, error) {
+	var records []*Record
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		fileRecords, err := ReadRecords(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		records = append(records, fileRecords...)
+	}
+	return records, nil
+}
+
+// parseTag reads a [Key "value"] tag line
+func parseTag(line string) (string, string, error) {
+	if !strings.HasSuffix(line, "]") {
+		return "", "", fmt.Errorf("%w: malformed tag %q", ErrInvalidRecord, line)
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	key, quoted, found := strings.Cut(body, " ")
+	if !found {
+		return "", "", fmt.Errorf("%w: malformed tag %q", ErrInvalidRecord, line)
+	}
+
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: malformed tag %q", ErrInvalidRecord, line)
+	}
+	return key, value, nil
+}
+
+// isResultToken reports whether a move text token is a result marker
+func isResultToken(token string) bool {
+	switch token {
+	case ResultXWins, ResultOWins, ResultDraw, ResultInProgress:
+		return true
+	}
+	return false
+}
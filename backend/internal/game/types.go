@@ -37,6 +37,7 @@ type GameState struct {
 	CurrentPlayer Player       `json:"currentPlayer"`
 	GameWon       Player       `json:"gameWon"`
 	GameOver      bool         `json:"gameOver"`
+	MoveHistory   []Move       `json:"moveHistory"` // Moves played so far, in order
 }
 
 // Move represents a player's move
@@ -1,8 +1,10 @@
 package logging
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -111,16 +113,18 @@ func toString(v interface{}) string {
 	case string:
 		return v
 	case int:
-		return string(rune(v))
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
 	case float64:
-		return string(rune(int(v)))
+		return strconv.FormatFloat(v, 'g', 6, 64)
 	case bool:
 		if v {
 			return "T"
 		}
 		return "F"
 	default:
-		return "unknown"
+		return fmt.Sprint(v)
 	}
 }
 
@@ -0,0 +1,70 @@
+// Package nn is a small multilayer perceptron that evaluates Ultimate
+// Tic-Tac-Toe positions on the CPU. It has a value head, the expected result
+// for the side to move, and a policy head, a prior over the 81 cells.
+package nn
+
+import "t-9/internal/game"
+
+const (
+	// InputSize is the number of input features: the side to move's and the
+	// opponent's stones on every cell, the side to move's, the opponent's and
+	// drawn small boards, and the active board (nine boards or any)
+	InputSize = 81*2 + 9*3 + 10
+
+	// PolicySize is the number of policy outputs, one per cell
+	PolicySize = 81
+)
+
+// Features encodes a position from the point of view of the side to move
+func Features(gameState *game.GameState) []float64 {
+	features := make([]float64, InputSize)
+
+	us := gameState.CurrentPlayer
+	for big := 0; big < 9; big++ {
+		for small := 0; small < 9; small++ {
+			switch gameState.BigBoard[big][small] {
+			case game.Empty:
+			case us:
+				features[big*9+small] = 1
+			default:
+				features[81+big*9+small] = 1
+			}
+		}
+	}
+
+	for big := 0; big < 9; big++ {
+		switch gameState.BigBoardWins[big] {
+		case us:
+			features[162+big] = 1
+		case game.Empty:
+			if isFull(&gameState.BigBoard[big]) {
+				features[180+big] = 1
+			}
+		default:
+			features[171+big] = 1
+		}
+	}
+
+	if gameState.ActiveBoard >= 0 && gameState.ActiveBoard < 9 {
+		features[189+gameState.ActiveBoard] = 1
+	} else {
+		features[198] = 1
+	}
+
+	return features
+}
+
+// MoveIndex returns the policy index of a move
+func MoveIndex(move game.Move) int {
+	return move.BigBoardIndex*9 + move.SmallBoardIndex
+}
+
+// isFull reports whether every cell of a small board is taken
+func isFull(board *game.SmallBoard) bool {
+	for _, cell := range board {
+		if cell == game.Empty {
+			return false
+		}
+	}
+	return true
+}
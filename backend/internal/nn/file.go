@@ -0,0 +1,137 @@
+package nn
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Weights files start with a header of four little-endian uint32 values after
+// the magic bytes: format version, input size, hidden size and policy size.
+// The parameters follow as little-endian float32 in the order W1, B1, WV, BV,
+// WP, BP.
+const (
+	fileMagic   = "T9NN"
+	fileVersion = 1
+)
+
+var ErrInvalidWeights = errors.New("invalid weights file")
+
+// Save writes the network in the weights file format
+func (n *Network) Save(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	if _, err := out.WriteString(fileMagic); err != nil {
+		return err
+	}
+
+	header := []uint32{fileVersion, InputSize, uint32(n.Hidden), PolicySize}
+	if err := binary.Write(out, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	bias := []float64{n.BV}
+	for _, values := range [][]float64{n.W1, n.B1, n.WV, bias, n.WP, n.BP} {
+		if err := writeFloats(out, values); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// Load reads a network in the weights file format
+func Load(r io.Reader) (*Network, error) {
+	in := bufio.NewReader(r)
+
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || string(magic) != fileMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidWeights)
+	}
+
+	var header [4]uint32
+	if err := binary.Read(in, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWeights, err)
+	}
+	version, inputs, hidden, policy := header[0], header[1], header[2], header[3]
+	if version != fileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidWeights, version)
+	}
+	if inputs != InputSize || policy != PolicySize {
+		return nil, fmt.Errorf("%w: expected %d inputs and %d policy outputs, got %d and %d",
+			ErrInvalidWeights, InputSize, PolicySize, inputs, policy)
+	}
+	if hidden == 0 || hidden > 4096 {
+		return nil, fmt.Errorf("%w: hidden size %d", ErrInvalidWeights, hidden)
+	}
+
+	n := &Network{
+		Hidden: int(hidden),
+		W1:     make([]float64, int(hidden)*InputSize),
+		B1:     make([]float64, hidden),
+		WV:     make([]float64, hidden),
+		WP:     make([]float64, PolicySize*int(hidden)),
+		BP:     make([]float64, PolicySize),
+	}
+
+	bias := make([]float64, 1)
+	for _, values := range [][]float64{n.W1, n.B1, n.WV, bias, n.WP, n.BP} {
+		if err := readFloats(in, values); err != nil {
+			return nil, err
+		}
+	}
+	n.BV = bias[0]
+
+	return n, nil
+}
+
+// SaveFile writes the network to a weights file
+func (n *Network) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := n.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFile reads a network from a weights file
+func LoadFile(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// writeFloats writes values as little-endian float32
+func writeFloats(w io.Writer, values []float64) error {
+	buffer := make([]float32, len(values))
+	for i, value := range values {
+		buffer[i] = float32(value)
+	}
+	return binary.Write(w, binary.LittleEndian, buffer)
+}
+
+// readFloats fills values from little-endian float32 and rejects weights that
+// are not finite
+func readFloats(r io.Reader, values []float64) error {
+	buffer := make([]float32, len(values))
+	if err := binary.Read(r, binary.LittleEndian, buffer); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWeights, err)
+	}
+	for i, value := range buffer {
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return fmt.Errorf("%w: non-finite weight", ErrInvalidWeights)
+		}
+		values[i] = float64(value)
+	}
+	return nil
+}
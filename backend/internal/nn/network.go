@@ -0,0 +1,167 @@
+package nn
+
+import (
+	"math"
+	"math/rand"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+)
+
+// Network is a multilayer perceptron with one ReLU hidden layer feeding a
+// tanh value head and a softmax policy head. It is safe for concurrent use
+// once trained.
+type Network struct {
+	Hidden int
+
+	W1 []float64 // Hidden x InputSize
+	B1 []float64 // Hidden
+	WV []float64 // Hidden
+	BV float64
+	WP []float64 // PolicySize x Hidden
+	BP []float64 // PolicySize
+}
+
+// NewNetwork creates a network with random initial weights
+func NewNetwork(hidden int, rng *rand.Rand) *Network {
+	n := &Network{
+		Hidden: hidden,
+		W1:     make([]float64, hidden*InputSize),
+		B1:     make([]float64, hidden),
+		WV:     make([]float64, hidden),
+		WP:     make([]float64, PolicySize*hidden),
+		BP:     make([]float64, PolicySize),
+	}
+
+	// He initialisation for the ReLU layer, smaller weights for the heads
+	inputScale := math.Sqrt(2 / float64(InputSize))
+	for i := range n.W1 {
+		n.W1[i] = rng.NormFloat64() * inputScale
+	}
+	headScale := math.Sqrt(1 / float64(hidden))
+	for i := range n.WV {
+		n.WV[i] = rng.NormFloat64() * headScale
+	}
+	for i := range n.WP {
+		n.WP[i] = rng.NormFloat64() * headScale
+	}
+
+	return n
+}
+
+// activations holds the intermediate values of a forward pass, which
+// training needs for backpropagation
+type activations struct {
+	hidden []float64 // After ReLU
+	value  float64   // After tanh
+	logits []float64
+}
+
+// forward runs the network on a feature vector
+func (n *Network) forward(features []float64) activations {
+	a := activations{
+		hidden: make([]float64, n.Hidden),
+		logits: make([]float64, PolicySize),
+	}
+
+	for h := 0; h < n.Hidden; h++ {
+		sum := n.B1[h]
+		weights := n.W1[h*InputSize : (h+1)*InputSize]
+		for i, x := range features {
+			if x != 0 {
+				sum += weights[i] * x
+			}
+		}
+		if sum > 0 {
+			a.hidden[h] = sum
+		}
+	}
+
+	value := n.BV
+	for h, x := range a.hidden {
+		value += n.WV[h] * x
+	}
+	a.value = math.Tanh(value)
+
+	for p := 0; p < PolicySize; p++ {
+		sum := n.BP[p]
+		weights := n.WP[p*n.Hidden : (p+1)*n.Hidden]
+		for h, x := range a.hidden {
+			sum += weights[h] * x
+		}
+		a.logits[p] = sum
+	}
+
+	return a
+}
+
+// Predict returns the expected result for the side to move, between -1 and
+// 1, and the policy over all 81 cells
+func (n *Network) Predict(gameState *game.GameState) (float64, [PolicySize]float64) {
+	a := n.forward(Features(gameState))
+	return a.value, softmax(a.logits, legalMask(gameState))
+}
+
+// Evaluate implements ai.Evaluator
+func (n *Network) Evaluate(gameState *game.GameState, player game.Player) float64 {
+	a := n.forward(Features(gameState))
+	score := ai.ValueToScore(a.value)
+	if gameState.CurrentPlayer != player {
+		score = -score
+	}
+	return score
+}
+
+// Policy implements ai.PolicyEvaluator. Illegal moves get zero probability.
+func (n *Network) Policy(gameState *game.GameState) [PolicySize]float64 {
+	_, policy := n.Predict(gameState)
+	return policy
+}
+
+// legalMask marks the cells the side to move may play
+func legalMask(gameState *game.GameState) [PolicySize]bool {
+	var mask [PolicySize]bool
+	if gameState.GameOver {
+		return mask
+	}
+	for big := 0; big < 9; big++ {
+		if gameState.ActiveBoard != -1 && big != gameState.ActiveBoard {
+			continue
+		}
+		if gameState.BigBoardWins[big] != game.Empty {
+			continue
+		}
+		for small := 0; small < 9; small++ {
+			if gameState.BigBoard[big][small] == game.Empty {
+				mask[big*9+small] = true
+			}
+		}
+	}
+	return mask
+}
+
+// softmax turns logits into probabilities over the allowed entries
+func softmax(logits []float64, allowed [PolicySize]bool) [PolicySize]float64 {
+	var probabilities [PolicySize]float64
+
+	max := math.Inf(-1)
+	for i, logit := range logits {
+		if allowed[i] && logit > max {
+			max = logit
+		}
+	}
+
+	total := 0.0
+	for i, logit := range logits {
+		if allowed[i] {
+			probabilities[i] = math.Exp(logit - max)
+			total += probabilities[i]
+		}
+	}
+	if total > 0 {
+		for i := range probabilities {
+			probabilities[i] /= total
+		}
+	}
+
+	return probabilities
+}
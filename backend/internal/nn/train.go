@@ -0,0 +1,219 @@
+package nn
+
+import (
+	"math"
+	"math/rand"
+	"t-9/internal/game"
+)
+
+// Sample is one training position: its features, the final result for the
+// side to move and the move that was played
+type Sample struct {
+	Features []float64
+	Value    float64
+	Move     int
+}
+
+// TrainConfig controls a training run
+type TrainConfig struct {
+	Epochs       int
+	LearningRate float64
+	PolicyWeight float64 // Weight of the policy loss relative to the value loss
+	Rng          *rand.Rand
+
+	// OnEpoch is called after every epoch with the mean training loss
+	OnEpoch func(epoch int, loss float64)
+}
+
+// symmetries maps every 3x3 index under the eight rotations and reflections
+// of the board. The same map applies to small boards and to cells.
+var symmetries = [8][9]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8}, // identity
+	{6, 3, 0, 7, 4, 1, 8, 5, 2}, // rotate 90
+	{8, 7, 6, 5, 4, 3, 2, 1, 0}, // rotate 180
+	{2, 5, 8, 1, 4, 7, 0, 3, 6}, // rotate 270
+	{2, 1, 0, 5, 4, 3, 8, 7, 6}, // mirror
+	{0, 3, 6, 1, 4, 7, 2, 5, 8}, // transpose
+	{6, 7, 8, 3, 4, 5, 0, 1, 2}, // flip
+	{8, 5, 2, 7, 4, 1, 6, 3, 0}, // anti-transpose
+}
+
+// SamplesFromRecord turns every position of a finished game into samples.
+// With augment set each position is added under all eight board symmetries.
+func SamplesFromRecord(record *game.Record, augment bool) ([]Sample, error) {
+	final, err := record.Replay()
+	if err != nil {
+		return nil, err
+	}
+	if !final.GameOver {
+		return nil, nil
+	}
+
+	transforms := symmetries[:1]
+	if augment {
+		transforms = symmetries[:]
+	}
+
+	var samples []Sample
+	state := game.NewGame()
+	for _, move := range record.Moves {
+		value := 0.0
+		switch final.GameWon {
+		case state.CurrentPlayer:
+			value = 1
+		case game.Empty:
+		default:
+			value = -1
+		}
+
+		for _, symmetry := range transforms {
+			transformed, transformedMove := transform(state, move, symmetry)
+			samples = append(samples, Sample{
+				Features: Features(transformed),
+				Value:    value,
+				Move:     MoveIndex(transformedMove),
+			})
+		}
+
+		if err := state.MakeMove(move); err != nil {
+			return nil, err
+		}
+	}
+
+	return samples, nil
+}
+
+// transform applies a board symmetry to a position and a move
+func transform(state *game.GameState, move game.Move, symmetry [9]int) (*game.GameState, game.Move) {
+	transformed := &game.GameState{
+		ActiveBoard:   state.ActiveBoard,
+		CurrentPlayer: state.CurrentPlayer,
+		GameWon:       state.GameWon,
+		GameOver:      state.GameOver,
+	}
+	if state.ActiveBoard >= 0 {
+		transformed.ActiveBoard = symmetry[state.ActiveBoard]
+	}
+	for big := 0; big < 9; big++ {
+		transformed.BigBoardWins[symmetry[big]] = state.BigBoardWins[big]
+		for small := 0; small < 9; small++ {
+			transformed.BigBoard[symmetry[big]][symmetry[small]] = state.BigBoard[big][small]
+		}
+	}
+
+	move.BigBoardIndex = symmetry[move.BigBoardIndex]
+	move.SmallBoardIndex = symmetry[move.SmallBoardIndex]
+	return transformed, move
+}
+
+// Train fits the network to samples with stochastic gradient descent on the
+// squared value error plus the policy cross-entropy
+func (n *Network) Train(samples []Sample, config TrainConfig) {
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+
+	for epoch := 1; epoch <= config.Epochs; epoch++ {
+		config.Rng.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+
+		total := 0.0
+		for _, i := range order {
+			total += n.step(samples[i], config.LearningRate, config.PolicyWeight)
+		}
+
+		if config.OnEpoch != nil && len(samples) > 0 {
+			config.OnEpoch(epoch, total/float64(len(samples)))
+		}
+	}
+}
+
+// Loss returns the mean loss over samples without training
+func (n *Network) Loss(samples []Sample, policyWeight float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, sample := range samples {
+		a := n.forward(sample.Features)
+		total += loss(a, sample, policyWeight)
+	}
+	return total / float64(len(samples))
+}
+
+// loss is the training loss of one sample. The policy softmax runs over all
+// cells, since samples do not record which moves were legal.
+func loss(a activations, sample Sample, policyWeight float64) float64 {
+	policy := fullSoftmax(a.logits)
+	valueError := a.value - sample.Value
+	return valueError*valueError - policyWeight*math.Log(math.Max(policy[sample.Move], 1e-12))
+}
+
+// step runs one sample forward and backward and updates the weights. It
+// returns the sample's loss before the update.
+func (n *Network) step(sample Sample, learningRate, policyWeight float64) float64 {
+	a := n.forward(sample.Features)
+	sampleLoss := loss(a, sample, policyWeight)
+
+	// Output gradients
+	valueGradient := 2 * (a.value - sample.Value) * (1 - a.value*a.value)
+	policyGradient := fullSoftmax(a.logits)
+	policyGradient[sample.Move]--
+	for p := range policyGradient {
+		policyGradient[p] *= policyWeight
+	}
+
+	// Hidden layer gradient, taken before the heads are updated
+	hiddenGradient := make([]float64, n.Hidden)
+	for h := 0; h < n.Hidden; h++ {
+		if a.hidden[h] <= 0 {
+			continue
+		}
+		gradient := n.WV[h] * valueGradient
+		for p := 0; p < PolicySize; p++ {
+			gradient += n.WP[p*n.Hidden+h] * policyGradient[p]
+		}
+		hiddenGradient[h] = gradient
+	}
+
+	// Heads
+	for h, x := range a.hidden {
+		n.WV[h] -= learningRate * valueGradient * x
+	}
+	n.BV -= learningRate * valueGradient
+	for p := 0; p < PolicySize; p++ {
+		weights := n.WP[p*n.Hidden : (p+1)*n.Hidden]
+		for h, x := range a.hidden {
+			weights[h] -= learningRate * policyGradient[p] * x
+		}
+		n.BP[p] -= learningRate * policyGradient[p]
+	}
+
+	// Input layer, skipping the many zero features
+	for h, gradient := range hiddenGradient {
+		if gradient == 0 {
+			continue
+		}
+		weights := n.W1[h*InputSize : (h+1)*InputSize]
+		for i, x := range sample.Features {
+			if x != 0 {
+				weights[i] -= learningRate * gradient * x
+			}
+		}
+		n.B1[h] -= learningRate * gradient
+	}
+
+	return sampleLoss
+}
+
+// fullSoftmax turns logits into probabilities over every cell
+func fullSoftmax(logits []float64) []float64 {
+	var allowed [PolicySize]bool
+	for i := range allowed {
+		allowed[i] = true
+	}
+	probabilities := softmax(logits, allowed)
+	return probabilities[:]
+}
@@ -0,0 +1,197 @@
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+	"time"
+)
+
+// EngineOptions are the settings of the built-in engine. They can be changed
+// by the GUI with setoption.
+type EngineOptions struct {
+	Name     string
+	Author   string
+	Depth    int           // Used when go has no depth
+	MoveTime time.Duration // Used when go has neither depth nor movetime
+
+	// Evaluator replaces the built-in evaluation when set
+	Evaluator ai.Evaluator
+}
+
+// DefaultEngineOptions returns the built-in engine's default settings
+func DefaultEngineOptions() EngineOptions {
+	return EngineOptions{
+		Name:     "T-9",
+		Author:   "T-9 contributors",
+		Depth:    8,
+		MoveTime: time.Second,
+	}
+}
+
+// RunEngine runs the built-in engine over the protocol until quit is received
+// or the input ends. Searches run in the background so stop, isready and quit
+// are answered while the engine thinks.
+func RunEngine(r io.Reader, w io.Writer, options EngineOptions) error {
+	out := bufio.NewWriter(w)
+	var outMu sync.Mutex
+	send := func(format string, args ...interface{}) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		fmt.Fprintf(out, format+"\n", args...)
+		out.Flush()
+	}
+
+	state := game.NewGame()
+
+	// The running search, if any
+	var cancel context.CancelFunc
+	var done chan struct{}
+	stopSearch := func() {
+		if cancel != nil {
+			cancel()
+			<-done
+			cancel = nil
+		}
+	}
+	defer stopSearch()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, args := fields[0], fields[1:]
+		switch command {
+		case "uti":
+			send("id name %s", options.Name)
+			send("id author %s", options.Author)
+			send("option name Depth type spin default %d min 1 max 20", options.Depth)
+			send("option name MoveTime type spin default %d min 0 max 60000", options.MoveTime.Milliseconds())
+			send("utiok")
+		case "isready":
+			send("readyok")
+		case "setoption":
+			stopSearch()
+			if err := setOption(&options, args); err != nil {
+				send("info string %v", err)
+			}
+		case "newgame":
+			stopSearch()
+			state = game.NewGame()
+		case "position":
+			stopSearch()
+			position, err := ParsePosition(args)
+			if err != nil {
+				send("info string %v", err)
+				continue
+			}
+			state = position
+		case "go":
+			stopSearch()
+			limits, err := ParseGo(args)
+			if err != nil {
+				send("info string %v", err)
+				continue
+			}
+
+			ctx, searchCancel := context.WithCancel(context.Background())
+			cancel, done = searchCancel, make(chan struct{})
+			go func(state *game.GameState, options EngineOptions, done chan struct{}) {
+				defer close(done)
+				send("bestmove %s", think(ctx, state, limits, options, func(info Info) {
+					send("%s", FormatInfo(info))
+				}))
+			}(state, options, done)
+		case "stop":
+			stopSearch()
+		case "quit":
+			return nil
+		default:
+			send("info string unknown command %q", command)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// think searches the position and returns the best move in record notation,
+// or "none" when the game is over. A search stopped before its first
+// iteration plays the first legal move.
+func think(ctx context.Context, state *game.GameState, limits Limits, options EngineOptions, report func(Info)) string {
+	if state.GameOver {
+		return "none"
+	}
+
+	if limits.Infinite {
+		limits.Depth = maxInfiniteDepth
+		limits.MoveTime = 0
+	}
+	if limits.Depth == 0 && limits.MoveTime == 0 {
+		limits.MoveTime = options.MoveTime
+	}
+	if limits.Depth == 0 {
+		limits.Depth = options.Depth
+	}
+
+	player := ai.NewAIPlayer(ai.Hard, state.CurrentPlayer)
+	if options.Evaluator != nil {
+		player.SetEvaluator(options.Evaluator)
+	}
+	move, err := player.Think(ctx, state, limits.Depth, limits.MoveTime, func(info ai.SearchInfo) {
+		report(Info{
+			Depth: info.Depth,
+			Score: int(math.Round(info.Score)),
+			Nodes: info.Nodes,
+			Time:  info.Elapsed,
+			PV:    info.Line,
+		})
+	})
+	if err != nil {
+		move = ai.NewAIPlayer(ai.Easy, state.CurrentPlayer).GetBestMove(state)
+	}
+
+	return game.FormatMove(move)
+}
+
+// setOption reads the arguments of a setoption command
+func setOption(options *EngineOptions, args []string) error {
+	line := strings.Join(args, " ")
+	if !strings.HasPrefix(line, "name ") {
+		return fmt.Errorf("%w: setoption needs a name", ErrInvalidCommand)
+	}
+	name, value, found := strings.Cut(strings.TrimPrefix(line, "name "), " value ")
+	if !found {
+		return fmt.Errorf("%w: setoption needs a value", ErrInvalidCommand)
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%w: option %s must be a number", ErrInvalidCommand, name)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "depth":
+		if number < 1 || number > 20 {
+			return fmt.Errorf("%w: Depth must be between 1 and 20", ErrInvalidCommand)
+		}
+		options.Depth = number
+	case "movetime":
+		if number < 0 {
+			return fmt.Errorf("%w: MoveTime must not be negative", ErrInvalidCommand)
+		}
+		options.MoveTime = time.Duration(number) * time.Millisecond
+	default:
+		return fmt.Errorf("%w: unknown option %q", ErrInvalidCommand, name)
+	}
+	return nil
+}
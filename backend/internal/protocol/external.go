@@ -0,0 +1,276 @@
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"t-9/internal/game"
+	"time"
+)
+
+const (
+	// handshakeTimeout bounds the uti and isready exchanges
+	handshakeTimeout = 10 * time.Second
+
+	// searchGrace is added to the move time before an engine is considered
+	// unresponsive. Searches without a move time may take searchTimeout.
+	searchGrace   = 5 * time.Second
+	searchTimeout = 60 * time.Second
+
+	// stopGrace is how long a cancelled engine has to answer stop
+	stopGrace = time.Second
+
+	// quitGrace is how long an engine has to exit after quit, and killGrace
+	// how long its output is then waited for once it is killed
+	quitGrace = time.Second
+	killGrace = time.Second
+)
+
+var ErrEngineTimeout = errors.New("engine did not reply in time")
+
+// ExternalEngine runs an engine binary that speaks the protocol and plays
+// through it. It implements ai.Engine, so external engines can play AI games
+// and arena matches.
+type ExternalEngine struct {
+	Name   string
+	Limits Limits
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+
+	closing   chan struct{} // Closed by Close; output is no longer wanted
+	closeOnce sync.Once
+	eof       chan struct{} // Closed once all output has been read
+}
+
+// StartExternalEngine starts an engine process and completes the handshake.
+// command is the binary followed by its arguments, separated by spaces.
+func StartExternalEngine(command string, limits Limits) (*ExternalEngine, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty engine command")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start engine %s: %w", fields[0], err)
+	}
+
+	engine := &ExternalEngine{
+		Name:   filepath.Base(fields[0]),
+		Limits: limits,
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan string, 64),
+
+		closing: make(chan struct{}),
+		eof:     make(chan struct{}),
+	}
+
+	// Read the engine's output in the background so a stuck engine can be
+	// timed out. Once the engine is closing its output is read and dropped
+	// until it exits, so it never blocks writing.
+	go func() {
+		defer close(engine.eof)
+		defer close(engine.lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case engine.lines <- scanner.Text():
+			case <-engine.closing:
+			}
+		}
+	}()
+
+	if err := engine.handshake(); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	return engine, nil
+}
+
+// handshake identifies the engine and waits until it is ready
+func (e *ExternalEngine) handshake() error {
+	if err := e.send("uti"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(handshakeTimeout)
+	for {
+		fields, err := e.readLine(context.Background(), deadline)
+		if err != nil {
+			return fmt.Errorf("uti handshake: %w", err)
+		}
+		if len(fields) > 2 && fields[0] == "id" && fields[1] == "name" {
+			e.Name = strings.Join(fields[2:], " ")
+		}
+		if fields[0] == "utiok" {
+			break
+		}
+	}
+
+	return e.waitReady()
+}
+
+// waitReady sends isready and waits for readyok
+func (e *ExternalEngine) waitReady() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(handshakeTimeout)
+	for {
+		fields, err := e.readLine(context.Background(), deadline)
+		if err != nil {
+			return fmt.Errorf("isready: %w", err)
+		}
+		if fields[0] == "readyok" {
+			return nil
+		}
+	}
+}
+
+// GetBestMoveContext asks the engine for a move within its limits. It
+// implements ai.Engine.
+func (e *ExternalEngine) GetBestMoveContext(ctx context.Context, gameState *game.GameState) (game.Move, error) {
+	return e.Search(ctx, gameState, e.Limits, nil)
+}
+
+// Search sends the position and a go command, passing every info line to
+// report, and returns the engine's best move. When ctx is cancelled the
+// engine is told to stop and the context's error is returned.
+func (e *ExternalEngine) Search(ctx context.Context, gameState *game.GameState, limits Limits, report func(Info)) (game.Move, error) {
+	if gameState.GameOver {
+		return game.Move{}, fmt.Errorf("game is already over")
+	}
+
+	if err := e.send(FormatPosition(gameState)); err != nil {
+		return game.Move{}, err
+	}
+	if err := e.send(FormatGo(limits)); err != nil {
+		return game.Move{}, err
+	}
+
+	timeout := searchTimeout
+	if limits.MoveTime > 0 {
+		timeout = limits.MoveTime + searchGrace
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		fields, err := e.readLine(ctx, deadline)
+		if err != nil {
+			// The search's bestmove must not be taken as the answer to the
+			// next one, so an engine that cannot be stopped is killed
+			if !e.stop() {
+				e.cmd.Process.Kill()
+			}
+			return game.Move{}, err
+		}
+
+		switch fields[0] {
+		case "info":
+			if report == nil || (len(fields) > 1 && fields[1] == "string") {
+				continue
+			}
+			if info, err := ParseInfo(fields[1:], gameState.CurrentPlayer); err == nil {
+				report(info)
+			}
+		case "bestmove":
+			if len(fields) < 2 {
+				return game.Move{}, fmt.Errorf("%w: bestmove without a move", ErrInvalidCommand)
+			}
+			move, err := game.ParseMove(fields[1], gameState.CurrentPlayer)
+			if err != nil {
+				return game.Move{}, fmt.Errorf("engine played %q: %w", fields[1], err)
+			}
+			if err := gameState.IsValidMove(move); err != nil {
+				return game.Move{}, fmt.Errorf("engine played illegal move %s: %w", fields[1], err)
+			}
+			return move, nil
+		}
+	}
+}
+
+// stop interrupts a search and discards its result, so the engine is ready
+// for the next command. It reports whether the engine answered in time.
+func (e *ExternalEngine) stop() bool {
+	if e.send("stop") != nil {
+		return false
+	}
+	deadline := time.Now().Add(stopGrace)
+	for {
+		fields, err := e.readLine(context.Background(), deadline)
+		if err != nil {
+			return false
+		}
+		if fields[0] == "bestmove" {
+			return true
+		}
+	}
+}
+
+// Close asks the engine to quit and kills it if it does not. The process is
+// waited for once its output has all been read, as os/exec requires, or once
+// killGrace has passed since it was killed: a process the engine started,
+// such as from a wrapper script, may keep its output open after the engine
+// is gone. Waiting closes the output, which ends the reader.
+func (e *ExternalEngine) Close() error {
+	e.closeOnce.Do(func() { close(e.closing) })
+	e.send("quit")
+	e.stdin.Close()
+
+	select {
+	case <-e.eof:
+	case <-time.After(quitGrace):
+		e.cmd.Process.Kill()
+		select {
+		case <-e.eof:
+		case <-time.After(killGrace):
+		}
+	}
+	return e.cmd.Wait()
+}
+
+// send writes a command line to the engine
+func (e *ExternalEngine) send(command string) error {
+	if _, err := io.WriteString(e.stdin, command+"\n"); err != nil {
+		return fmt.Errorf("write to engine: %w", err)
+	}
+	return nil
+}
+
+// readLine returns the fields of the next non-empty line from the engine
+func (e *ExternalEngine) readLine(ctx context.Context, deadline time.Time) ([]string, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return nil, fmt.Errorf("engine exited")
+			}
+			if fields := strings.Fields(line); len(fields) > 0 {
+				return fields, nil
+			}
+		case <-timer.C:
+			return nil, ErrEngineTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package protocol implements UTI, a line-based text protocol for Ultimate
+// Tic-Tac-Toe engines modelled on chess UCI. The GUI (here, the server)
+// writes commands to the engine's stdin and reads replies from its stdout:
+//
+//	uti                                    -> id name ..., option ..., utiok
+//	isready                                -> readyok
+//	setoption name <id> value <x>
+//	newgame
+//	position startpos [moves 44 40 ...]
+//	position notation <board> <side> <active> [moves ...]
+//	go [depth N] [movetime MS] [infinite]  -> info ..., bestmove 40
+//	stop
+//	quit
+//
+// Moves use record notation: two digits, the big board then the small board.
+// Positions use game notation, see game.ParseNotation.
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"t-9/internal/game"
+	"time"
+)
+
+var ErrInvalidCommand = errors.New("invalid command")
+
+// maxInfiniteDepth bounds "go infinite" searches, which run until stop
+const maxInfiniteDepth = 64
+
+// Limits bound a search. Zero values mean no limit of that kind. Infinite
+// searches run until they are stopped.
+type Limits struct {
+	Depth    int
+	MoveTime time.Duration
+	Infinite bool
+}
+
+// Info is a progress report sent by an engine while it searches. Score is in
+// the engine's own units, from the point of view of the side to move.
+type Info struct {
+	Depth int
+	Score int
+	Nodes int
+	Time  time.Duration
+	PV    []game.Move
+}
+
+// FormatPosition writes a position command. When the game's move history
+// replays to the same position it is sent as a move list from the start,
+// otherwise as notation.
+func FormatPosition(state *game.GameState) string {
+	if len(state.MoveHistory) == 0 && state.Notation() == game.StartNotation {
+		return "position startpos"
+	}
+
+	record := &game.Record{Moves: state.MoveHistory}
+	if replayed, err := record.Replay(); err == nil && replayed.Notation() == state.Notation() {
+		return "position startpos moves " + formatMoves(state.MoveHistory)
+	}
+
+	return "position notation " + state.Notation()
+}
+
+// ParsePosition reads the arguments of a position command
+func ParsePosition(args []string) (*game.GameState, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: position needs startpos or notation", ErrInvalidCommand)
+	}
+
+	var state *game.GameState
+	var rest []string
+	switch args[0] {
+	case "startpos":
+		state = game.NewGame()
+		rest = args[1:]
+	case "notation":
+		if len(args) < 4 {
+			return nil, fmt.Errorf("%w: notation needs board, side and active board", ErrInvalidCommand)
+		}
+		var err error
+		state, err = game.ParseNotation(strings.Join(args[1:4], " "))
+		if err != nil {
+			return nil, err
+		}
+		state.MoveHistory = []game.Move{}
+		rest = args[4:]
+	default:
+		return nil, fmt.Errorf("%w: unknown position type %q", ErrInvalidCommand, args[0])
+	}
+
+	if len(rest) == 0 {
+		return state, nil
+	}
+	if rest[0] != "moves" {
+		return nil, fmt.Errorf("%w: expected moves, got %q", ErrInvalidCommand, rest[0])
+	}
+	for _, text := range rest[1:] {
+		move, err := game.ParseMove(text, state.CurrentPlayer)
+		if err != nil {
+			return nil, err
+		}
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("move %s: %w", text, err)
+		}
+	}
+
+	return state, nil
+}
+
+// FormatGo writes a go command
+func FormatGo(limits Limits) string {
+	command := "go"
+	if limits.Depth > 0 {
+		command += fmt.Sprintf(" depth %d", limits.Depth)
+	}
+	if limits.MoveTime > 0 {
+		command += fmt.Sprintf(" movetime %d", limits.MoveTime.Milliseconds())
+	}
+	if limits.Infinite {
+		command += " infinite"
+	}
+	return command
+}
+
+// ParseGo reads the arguments of a go command
+func ParseGo(args []string) (Limits, error) {
+	var limits Limits
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			limits.Infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return Limits{}, fmt.Errorf("%w: %s needs a value", ErrInvalidCommand, args[i])
+		}
+		value, err := strconv.Atoi(args[i+1])
+		if err != nil || value < 0 {
+			return Limits{}, fmt.Errorf("%w: %s must be a non-negative number", ErrInvalidCommand, args[i])
+		}
+		switch args[i] {
+		case "depth":
+			limits.Depth = value
+		case "movetime":
+			limits.MoveTime = time.Duration(value) * time.Millisecond
+		default:
+			return Limits{}, fmt.Errorf("%w: unknown go limit %q", ErrInvalidCommand, args[i])
+		}
+		i++
+	}
+	return limits, nil
+}
+
+// FormatInfo writes an info line
+func FormatInfo(info Info) string {
+	line := fmt.Sprintf("info depth %d score %d nodes %d time %d",
+		info.Depth, info.Score, info.Nodes, info.Time.Milliseconds())
+	if len(info.PV) > 0 {
+		line += " pv " + formatMoves(info.PV)
+	}
+	return line
+}
+
+// ParseInfo reads the arguments of an info line. Unknown fields are skipped
+// so engines can send extra information.
+func ParseInfo(args []string, player game.Player) (Info, error) {
+	var info Info
+	for i := 0; i < len(args); i++ {
+		if args[i] == "pv" {
+			state := player
+			for _, text := range args[i+1:] {
+				move, err := game.ParseMove(text, state)
+				if err != nil {
+					return Info{}, err
+				}
+				info.PV = append(info.PV, move)
+				state = opponent(state)
+			}
+			break
+		}
+
+		if i+1 >= len(args) {
+			break
+		}
+		value, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		switch args[i] {
+		case "depth":
+			info.Depth = value
+		case "score":
+			info.Score = value
+		case "nodes":
+			info.Nodes = value
+		case "time":
+			info.Time = time.Duration(value) * time.Millisecond
+		default:
+			continue
+		}
+		i++
+	}
+	return info, nil
+}
+
+// formatMoves writes moves in record notation
+func formatMoves(moves []game.Move) string {
+	parts := make([]string, len(moves))
+	for i, move := range moves {
+		parts[i] = game.FormatMove(move)
+	}
+	return strings.Join(parts, " ")
+}
+
+// opponent returns the other player
+func opponent(player game.Player) game.Player {
+	if player == game.X {
+		return game.O
+	}
+	return game.X
+}
@@ -0,0 +1,136 @@
+package puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+)
+
+// MineConfig controls which positions become puzzles
+type MineConfig struct {
+	MinMoves  int // Fewest solver moves in a puzzle
+	MaxMoves  int // Most solver moves in a puzzle
+	SkipPlies int // Opening plies of each game to ignore
+}
+
+// DefaultMineConfig finds wins in two or three moves
+func DefaultMineConfig() MineConfig {
+	return MineConfig{MinMoves: 2, MaxMoves: 3, SkipPlies: 10}
+}
+
+// Mine returns the puzzle at a position, or nil if it is not one. A position
+// is a puzzle when the side to move has exactly one fastest forced win and
+// every later solver move is unique too, except the last, where any winning
+// move counts. Replies are the defence that delays the loss longest.
+func Mine(ctx context.Context, position *game.GameState, source string, config MineConfig) (*Puzzle, error) {
+	if position.GameOver {
+		return nil, nil
+	}
+
+	moves, plies, err := ai.ShortestWin(ctx, position, 2*config.MaxMoves-1)
+	if errors.Is(err, ai.ErrSolveLimit) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(moves) != 1 || plies < 2*config.MinMoves-1 {
+		return nil, nil
+	}
+
+	var line []game.Move
+	state := position.Clone()
+	for {
+		line = append(line, moves[0])
+		state.MakeMove(moves[0])
+		if state.GameOver {
+			break
+		}
+
+		reply, next, nextPlies, err := bestDefence(ctx, state, plies-2)
+		if errors.Is(err, ai.ErrSolveLimit) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(next) == 0 || (nextPlies > 1 && len(next) != 1) {
+			return nil, nil
+		}
+
+		line = append(line, reply)
+		state.MakeMove(reply)
+		moves, plies = next, nextPlies
+	}
+
+	return New(position, line, source), nil
+}
+
+// bestDefence picks the reply after which the attacker's win takes longest,
+// preferring replies that leave the attacker a single winning move. It
+// returns the reply and the attacker's winning moves after it.
+func bestDefence(ctx context.Context, state *game.GameState, maxPlies int) (game.Move, []game.Move, int, error) {
+	var best game.Move
+	var bestWins []game.Move
+	bestPlies := -1
+
+	for _, reply := range legalMovesOf(state) {
+		after := state.Clone()
+		after.MakeMove(reply)
+
+		wins, plies, err := ai.ShortestWin(ctx, after, maxPlies)
+		if err != nil {
+			return game.Move{}, nil, 0, err
+		}
+		if len(wins) == 0 {
+			// The line is not forced after all
+			return game.Move{}, nil, 0, nil
+		}
+		if plies > bestPlies || (plies == bestPlies && len(wins) == 1 && len(bestWins) != 1) {
+			best, bestWins, bestPlies = reply, wins, plies
+		}
+	}
+
+	return best, bestWins, bestPlies, nil
+}
+
+// MineRecord returns the first puzzle found for each side in a game record
+func MineRecord(ctx context.Context, record *game.Record, source string, config MineConfig) ([]*Puzzle, error) {
+	var puzzles []*Puzzle
+	found := map[game.Player]bool{}
+
+	state := game.NewGame()
+	for i, move := range record.Moves {
+		if i >= config.SkipPlies && !found[state.CurrentPlayer] {
+			puzzle, err := Mine(ctx, state, source, config)
+			if err != nil {
+				return puzzles, err
+			}
+			if puzzle != nil {
+				puzzles = append(puzzles, puzzle)
+				found[state.CurrentPlayer] = true
+			}
+		}
+		if err := state.MakeMove(move); err != nil {
+			return puzzles, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+
+	return puzzles, nil
+}
+
+// legalMovesOf returns every legal move for the side to move
+func legalMovesOf(state *game.GameState) []game.Move {
+	var moves []game.Move
+	for big := 0; big < 9; big++ {
+		for small := 0; small < 9; small++ {
+			move := game.Move{BigBoardIndex: big, SmallBoardIndex: small, Player: state.CurrentPlayer}
+			if state.IsValidMove(move) == nil {
+				moves = append(moves, move)
+			}
+		}
+	}
+	return moves
+}
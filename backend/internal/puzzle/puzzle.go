@@ -0,0 +1,152 @@
+// Package puzzle mines tactical puzzles, positions with a unique forced win,
+// from games and tracks the puzzle ratings of the players who solve them.
+package puzzle
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"t-9/internal/game"
+)
+
+var (
+	ErrInvalidPuzzle = errors.New("invalid puzzle")
+	ErrWrongPly      = errors.New("not the solver's move at this ply")
+)
+
+// Puzzle is a position where the side to move has a forced win. The solution
+// alternates the solver's moves and the opponent's best replies.
+type Puzzle struct {
+	ID       string   `json:"id"`
+	Position string   `json:"position"` // Game notation
+	Solution []string `json:"solution"` // Record notation
+	Rating   int      `json:"rating"`
+	Plays    int      `json:"plays"`
+	Source   string   `json:"source,omitempty"`
+}
+
+// Attempt is the outcome of a move played against a puzzle
+type Attempt struct {
+	Correct bool       `json:"correct"`
+	Solved  bool       `json:"solved"`
+	Reply   *game.Move `json:"reply,omitempty"`   // The opponent's answer to a correct move
+	NextPly int        `json:"nextPly,omitempty"` // Ply of the solver's next move
+}
+
+// New creates a puzzle from a position and its solution line. The ID is
+// derived from the position, so the same position mined twice is one puzzle.
+func New(position *game.GameState, solution []game.Move, source string) *Puzzle {
+	notation := position.Notation()
+	hash := sha1.Sum([]byte(notation))
+
+	moves := make([]string, len(solution))
+	for i, move := range solution {
+		moves[i] = game.FormatMove(move)
+	}
+
+	return &Puzzle{
+		ID:       hex.EncodeToString(hash[:6]),
+		Position: notation,
+		Solution: moves,
+		Rating:   initialRating(position, solution),
+		Source:   source,
+	}
+}
+
+// State returns the puzzle's starting position
+func (p *Puzzle) State() (*game.GameState, error) {
+	state, err := game.ParseNotation(p.Position)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPuzzle, err)
+	}
+	state.MoveHistory = []game.Move{}
+	return state, nil
+}
+
+// Moves returns the solution line
+func (p *Puzzle) Moves() ([]game.Move, error) {
+	state, err := p.State()
+	if err != nil {
+		return nil, err
+	}
+
+	moves := make([]game.Move, len(p.Solution))
+	for i, text := range p.Solution {
+		move, err := game.ParseMove(text, state.CurrentPlayer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPuzzle, err)
+		}
+		if err := state.MakeMove(move); err != nil {
+			return nil, fmt.Errorf("%w: move %s: %v", ErrInvalidPuzzle, text, err)
+		}
+		moves[i] = move
+	}
+	return moves, nil
+}
+
+// SolverMoves returns how many moves the solver has to find
+func (p *Puzzle) SolverMoves() int {
+	return (len(p.Solution) + 1) / 2
+}
+
+// Check plays the solver's move at a ply of the solution, which must be even.
+// The move is correct if it is the solution's move or, on the last move, any
+// move that wins the game.
+func (p *Puzzle) Check(ply int, move game.Move) (Attempt, error) {
+	if ply < 0 || ply%2 != 0 || ply >= len(p.Solution) {
+		return Attempt{}, ErrWrongPly
+	}
+
+	moves, err := p.Moves()
+	if err != nil {
+		return Attempt{}, err
+	}
+	state, err := p.State()
+	if err != nil {
+		return Attempt{}, err
+	}
+	for _, played := range moves[:ply] {
+		state.MakeMove(played)
+	}
+
+	move.Player = state.CurrentPlayer
+	if err := state.IsValidMove(move); err != nil {
+		return Attempt{}, err
+	}
+
+	last := ply == len(moves)-1
+	if move != moves[ply] {
+		if !last {
+			return Attempt{}, nil
+		}
+		state.MakeMove(move)
+		return Attempt{Correct: state.GameWon == move.Player, Solved: state.GameWon == move.Player}, nil
+	}
+
+	if last {
+		return Attempt{Correct: true, Solved: true}, nil
+	}
+	reply := moves[ply+1]
+	return Attempt{Correct: true, Reply: &reply, NextPly: ply + 2}, nil
+}
+
+// initialRating estimates a new puzzle's difficulty from the length of the
+// solution, how open the position is and whether the first move is quiet
+func initialRating(position *game.GameState, solution []game.Move) int {
+	rating := 900 + 300*((len(solution)+1)/2-1)
+
+	if position.ActiveBoard == -1 {
+		rating += 150
+	}
+
+	if len(solution) > 0 {
+		after := position.Clone()
+		if after.MakeMove(solution[0]) == nil &&
+			after.BigBoardWins[solution[0].BigBoardIndex] == position.BigBoardWins[solution[0].BigBoardIndex] {
+			rating += 200
+		}
+	}
+
+	return rating
+}
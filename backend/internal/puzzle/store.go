@@ -0,0 +1,413 @@
+package puzzle
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRating is the puzzle rating of a new player
+	DefaultRating = 1200
+
+	// Elo K-factors. Players move faster than puzzles, which are rated by
+	// many players.
+	playerK = 32
+	puzzleK = 16
+
+	// AttemptTTL is how long an attempt at a puzzle is kept after its last
+	// correct move. Attempts are rarely finished once left.
+	AttemptTTL = time.Hour
+)
+
+var (
+	ErrPlayerTaken = errors.New("player ID is already registered")
+	ErrBadToken    = errors.New("token is not valid for this player")
+)
+
+// Player is a player's puzzle record. Only the holder of the token issued
+// when the player registered can play puzzles under it.
+type Player struct {
+	Rating    int             `json:"rating"`
+	Solved    int             `json:"solved"`
+	Failed    int             `json:"failed"`
+	Played    map[string]bool `json:"played,omitempty"`    // Puzzle IDs already rated
+	TokenHash string          `json:"tokenHash,omitempty"` // SHA-256 of the player's token
+}
+
+// Result is the rating change from finishing a puzzle. Only the first finish
+// of a puzzle is rated.
+type Result struct {
+	Rated        bool `json:"rated"`
+	PlayerRating int  `json:"playerRating"`
+	PlayerChange int  `json:"playerChange"`
+	PuzzleRating int  `json:"puzzleRating"`
+}
+
+// Store holds the puzzles and players' puzzle ratings, saved as one JSON file.
+// How far players are through the puzzles they are solving is only kept in
+// memory.
+type Store struct {
+	mu       sync.RWMutex
+	path     string
+	puzzles  map[string]*Puzzle
+	players  map[string]*Player
+	progress map[progressKey]progress
+	changed  bool // Changed since it was loaded or saved
+}
+
+// progressKey identifies a player's attempt at a puzzle
+type progressKey struct {
+	playerID string
+	puzzleID string
+}
+
+// progress is how far a player is through an attempt at a puzzle
+type progress struct {
+	ply     int // Last ply answered correctly
+	updated time.Time
+}
+
+// storeFile is the on-disk form of a store
+type storeFile struct {
+	Puzzles []*Puzzle          `json:"puzzles"`
+	Players map[string]*Player `json:"players"`
+}
+
+// NewStore creates an empty store that is not saved
+func NewStore() *Store {
+	return &Store{
+		puzzles:  map[string]*Puzzle{},
+		players:  map[string]*Player{},
+		progress: map[progressKey]progress{},
+	}
+}
+
+// LoadStore reads a store from a file. A missing file gives an empty store
+// that is saved to path.
+func LoadStore(path string) (*Store, error) {
+	store := NewStore()
+	store.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for _, puzzle := range file.Puzzles {
+		store.puzzles[puzzle.ID] = puzzle
+	}
+	for id, player := range file.Players {
+		// Players recorded before tokens were issued cannot be told apart
+		// from anyone claiming their ID, so their records are reset
+		if player.TokenHash == "" {
+			store.changed = true
+			continue
+		}
+		store.players[id] = player
+	}
+	return store, nil
+}
+
+// Save writes the store to its file, if it has one
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	file := storeFile{Puzzles: s.sortedPuzzles(), Players: s.players}
+	data, err := json.MarshalIndent(file, "", "  ")
+	s.changed = false
+	s.mu.Unlock()
+	if err == nil {
+		err = writeFile(s.path, data)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.changed = true
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// SaveIfChanged is Save for a store that has changed since it was loaded or
+// last saved. It is called periodically, so requests do not wait for the
+// file to be written.
+func (s *Store) SaveIfChanged() error {
+	s.mu.RLock()
+	changed := s.changed
+	s.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return s.Save()
+}
+
+// writeFile replaces a file. A temporary file is written first so a crash
+// cannot leave half a store.
+func writeFile(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), ".puzzles-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Add stores new puzzles and returns how many were not already stored
+func (s *Store) Add(puzzles ...*Puzzle) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, puzzle := range puzzles {
+		if _, exists := s.puzzles[puzzle.ID]; !exists {
+			s.puzzles[puzzle.ID] = puzzle
+			added++
+		}
+	}
+	return added
+}
+
+// Len returns the number of stored puzzles
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.puzzles)
+}
+
+// Get returns a copy of a puzzle
+func (s *Store) Get(id string) (Puzzle, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	puzzle, exists := s.puzzles[id]
+	if !exists {
+		return Puzzle{}, false
+	}
+	return *puzzle, true
+}
+
+// Next returns the puzzle a player has not finished whose rating is closest
+// to theirs
+func (s *Store) Next(playerID string) (Puzzle, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	player := s.player(playerID)
+	var best *Puzzle
+	for _, puzzle := range s.sortedPuzzles() {
+		if player.Played[puzzle.ID] {
+			continue
+		}
+		if best == nil || abs(puzzle.Rating-player.Rating) < abs(best.Rating-player.Rating) {
+			best = puzzle
+		}
+	}
+
+	if best == nil {
+		return Puzzle{}, false
+	}
+	return *best, true
+}
+
+// Player returns a copy of a player's puzzle record
+func (s *Store) Player(playerID string) Player {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	player := *s.player(playerID)
+	player.Played = nil
+	player.TokenHash = ""
+	return player
+}
+
+// Register issues the token for a new player ID. A player ID can be
+// registered once.
+func (s *Store) Register(playerID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.players[playerID]; exists {
+		return "", ErrPlayerTaken
+	}
+	token := newToken()
+	s.players[playerID] = &Player{Rating: DefaultRating, TokenHash: hashToken(token)}
+	s.changed = true
+	return token, nil
+}
+
+// Authenticate checks that a token is the one issued to a player
+func (s *Store) Authenticate(playerID, token string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	player, exists := s.players[playerID]
+	if !exists || player.TokenHash == "" || token == "" ||
+		subtle.ConstantTimeCompare([]byte(player.TokenHash), []byte(hashToken(token))) != 1 {
+		return ErrBadToken
+	}
+	return nil
+}
+
+// Answered records that a player answered a ply of a puzzle correctly
+func (s *Store) Answered(playerID, puzzleID string, ply int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress[progressKey{playerID, puzzleID}] = progress{ply: ply, updated: time.Now()}
+}
+
+// Progress returns the last ply of a puzzle a player answered correctly in
+// the attempt under way, if any. An attempt left for AttemptTTL is over.
+func (s *Store) Progress(playerID, puzzleID string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	attempt, exists := s.progress[progressKey{playerID, puzzleID}]
+	if !exists || time.Since(attempt.updated) > AttemptTTL {
+		return 0, false
+	}
+	return attempt.ply, true
+}
+
+// ExpireAttempts forgets attempts left for AttemptTTL as of now and returns
+// how many there were
+func (s *Store) ExpireAttempts(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := 0
+	for key, attempt := range s.progress {
+		if now.Sub(attempt.updated) > AttemptTTL {
+			delete(s.progress, key)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Finish records a solved or failed puzzle and updates the ratings of the
+// player and the puzzle
+func (s *Store) Finish(playerID, puzzleID string, solved bool) (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	puzzle, exists := s.puzzles[puzzleID]
+	if !exists {
+		return Result{}, false
+	}
+	delete(s.progress, progressKey{playerID, puzzleID})
+
+	player, exists := s.players[playerID]
+	if !exists {
+		player = &Player{Rating: DefaultRating}
+		s.players[playerID] = player
+	}
+	if player.Played[puzzleID] {
+		return Result{PlayerRating: player.Rating, PuzzleRating: puzzle.Rating}, true
+	}
+
+	score := 0.0
+	if solved {
+		score = 1
+		player.Solved++
+	} else {
+		player.Failed++
+	}
+
+	expected := expectedScore(player.Rating, puzzle.Rating)
+	change := int(math.Round(playerK * (score - expected)))
+	player.Rating += change
+	puzzle.Rating -= int(math.Round(puzzleK * (score - expected)))
+	puzzle.Plays++
+
+	if player.Played == nil {
+		player.Played = map[string]bool{}
+	}
+	player.Played[puzzleID] = true
+	s.changed = true
+
+	return Result{
+		Rated:        true,
+		PlayerRating: player.Rating,
+		PlayerChange: change,
+		PuzzleRating: puzzle.Rating,
+	}, true
+}
+
+// player returns a player's record, or a new record if there is none. The
+// caller must hold the lock.
+func (s *Store) player(playerID string) *Player {
+	if player, exists := s.players[playerID]; exists {
+		return player
+	}
+	return &Player{Rating: DefaultRating}
+}
+
+// sortedPuzzles returns the puzzles by rating, then ID, so choices and saved
+// files are stable. The caller must hold the lock.
+func (s *Store) sortedPuzzles() []*Puzzle {
+	puzzles := make([]*Puzzle, 0, len(s.puzzles))
+	for _, puzzle := range s.puzzles {
+		puzzles = append(puzzles, puzzle)
+	}
+	sort.Slice(puzzles, func(i, j int) bool {
+		if puzzles[i].Rating != puzzles[j].Rating {
+			return puzzles[i].Rating < puzzles[j].Rating
+		}
+		return puzzles[i].ID < puzzles[j].ID
+	})
+	return puzzles
+}
+
+// newToken creates a random secret token
+func newToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		// Fallback to timestamp if crypto/rand fails
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// hashToken is how tokens are stored, so the store file holds no secrets
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// expectedScore is the Elo expected score of a player against a puzzle
+func expectedScore(playerRating, puzzleRating int) float64 {
+	return 1 / (1 + math.Pow(10, float64(puzzleRating-playerRating)/400))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,249 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+
+	"t-9/internal/game"
+)
+
+const (
+	DefaultDelay      = 800 * time.Millisecond
+	DefaultFinalDelay = 3 * time.Second
+	MinDelay          = 50 * time.Millisecond
+	MaxDelay          = 30 * time.Second
+	MaxReplaySize     = 1024       // Replays hold a frame per move, so are kept smaller than boards
+	MaxReplayPixels   = 20_000_000 // Pixels drawn over all frames, which bounds the time a replay takes
+	MaxBannerLength   = 40
+)
+
+// ReplayOptions controls how a game is animated
+type ReplayOptions struct {
+	Options
+	Delay      time.Duration // Time each move is shown, DefaultDelay unless given
+	FinalDelay time.Duration // Time the final position is shown before the replay loops, DefaultFinalDelay unless given
+	NoBanner   bool          // Leave the final position without a banner
+	BannerText string        // Text of the final banner, the result unless given
+}
+
+// check fills in defaults and rejects options out of range
+func (o ReplayOptions) check() (ReplayOptions, Theme, error) {
+	options, theme, err := o.Options.check()
+	if err != nil {
+		return o, theme, err
+	}
+	o.Options = options
+	if o.Size > MaxReplaySize {
+		return o, theme, fmt.Errorf("size must be between %d and %d", MinSize, MaxReplaySize)
+	}
+	if o.Delay == 0 {
+		o.Delay = DefaultDelay
+	}
+	if o.FinalDelay == 0 {
+		o.FinalDelay = DefaultFinalDelay
+	}
+	for _, delay := range []time.Duration{o.Delay, o.FinalDelay} {
+		if delay < MinDelay || delay > MaxDelay {
+			return o, theme, fmt.Errorf("delays must be between %v and %v", MinDelay, MaxDelay)
+		}
+	}
+	if len([]rune(o.BannerText)) > MaxBannerLength {
+		return o, theme, fmt.Errorf("banner text must be at most %d characters", MaxBannerLength)
+	}
+	return o, theme, nil
+}
+
+// Check reports whether the options can be animated with
+func (o ReplayOptions) Check() error {
+	_, _, err := o.check()
+	return err
+}
+
+// MaxReplaySizeFor returns the largest size a game with the given number of
+// moves can be animated at. Each move adds a frame, so long games are drawn
+// smaller to stay within MaxReplayPixels.
+func MaxReplaySizeFor(moves int) int {
+	size := int(math.Sqrt(float64(MaxReplayPixels) / float64(moves+1)))
+	return min(size, MaxReplaySize)
+}
+
+// CheckFor reports whether the options can animate a game with the given
+// number of moves within MaxReplayPixels
+func (o ReplayOptions) CheckFor(moves int) error {
+	options, _, err := o.check()
+	if err != nil {
+		return err
+	}
+	if limit := MaxReplaySizeFor(moves); options.Size > limit {
+		return fmt.Errorf("size must be at most %d for a game of %d moves", limit, moves)
+	}
+	return nil
+}
+
+// GIF writes an animated replay of a game: the empty board, then a frame
+// per move. The final frame is held longer and, unless turned off, carries
+// a banner with the result.
+func GIF(w io.Writer, state *game.GameState, options ReplayOptions) error {
+	options, theme, err := options.check()
+	if err != nil {
+		return err
+	}
+
+	palette := replayPalette(theme)
+	colors := map[color.RGBA]uint8{}
+	size := options.Size
+	bounds := image.Rect(0, 0, size, size)
+	animation := &gif.GIF{Config: image.Config{ColorModel: palette, Width: size, Height: size}}
+
+	var previous *image.Paletted
+	position := game.NewGame()
+	for ply := 0; ply <= len(state.MoveHistory); ply++ {
+		if ply > 0 {
+			if err := position.MakeMove(state.MoveHistory[ply-1]); err != nil {
+				return fmt.Errorf("move %d: %w", ply, err)
+			}
+		}
+		final := ply == len(state.MoveHistory)
+		if final {
+			// The game as it ended, keeping a resignation
+			position = state
+		}
+
+		shapes := scene(position, size, theme)
+		delay := options.Delay
+		if final {
+			delay = options.FinalDelay
+			if line := bannerLine(state, options); line != "" {
+				shapes = append(shapes, banner(line, size, theme)...)
+			}
+		}
+
+		frame := quantize(raster(shapes, size), palette, colors)
+		// Only the part that changed is stored; the rest of the previous
+		// frame is left in place
+		changed := bounds
+		if previous != nil {
+			changed = difference(previous, frame)
+			if changed.Empty() {
+				changed = image.Rect(0, 0, 1, 1)
+			}
+		}
+		animation.Image = append(animation.Image, frame.SubImage(changed).(*image.Paletted))
+		animation.Delay = append(animation.Delay, int(delay/(10*time.Millisecond)))
+		animation.Disposal = append(animation.Disposal, gif.DisposalNone)
+		previous = frame
+	}
+	return gif.EncodeAll(w, animation)
+}
+
+// bannerLine returns the text of the final banner, or "" for none
+func bannerLine(state *game.GameState, options ReplayOptions) string {
+	switch {
+	case options.NoBanner:
+		return ""
+	case options.BannerText != "":
+		return options.BannerText
+	case !state.GameOver:
+		return ""
+	case state.GameWon == game.Empty:
+		return "Draw"
+	case state.WinningLine() == nil:
+		return fmt.Sprintf("%s wins by resignation", state.GameWon)
+	default:
+		return fmt.Sprintf("%s wins", state.GameWon)
+	}
+}
+
+// banner lays out a band across the middle of the board with a line of text
+func banner(line string, size int, theme Theme) []shape {
+	s := float64(size)
+	height := math.Round(s * 0.16)
+	top := math.Round((s - height) / 2)
+	shapes := []shape{{kind: rectShape, y1: top, x2: s, y2: top + height, color: theme.Banner}}
+
+	scale := textScale(line, s*0.9, height*0.55/glyphHeight)
+	if scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	return append(shapes, text(line, s/2, top+height/2, scale, theme.BannerText)...)
+}
+
+// replayPalette returns the colors a replay is drawn with: the theme's
+// colors, and blends of each mark and line over each fill for antialiased
+// edges
+func replayPalette(theme Theme) color.Palette {
+	opaque := func(c color.NRGBA) color.RGBA {
+		return color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+	}
+	mix := func(fg, bg color.RGBA, t float64) color.RGBA {
+		at := func(a, b uint8) uint8 { return uint8(float64(b)*(1-t) + float64(a)*t + 0.5) }
+		return color.RGBA{R: at(fg.R, bg.R), G: at(fg.G, bg.G), B: at(fg.B, bg.B), A: 0xff}
+	}
+
+	bannerFill := mix(opaque(theme.Banner), opaque(theme.Background), float64(theme.Banner.A)/0xff)
+	fills := []color.RGBA{opaque(theme.Background), opaque(theme.Active), opaque(theme.LastMove), opaque(theme.WonBoard), bannerFill}
+	strokes := []color.RGBA{opaque(theme.Grid), opaque(theme.BoardGrid), opaque(theme.X), opaque(theme.O), opaque(theme.WinLine), opaque(theme.BannerText)}
+
+	var palette color.Palette
+	seen := map[color.RGBA]bool{}
+	add := func(c color.RGBA) {
+		if !seen[c] && len(palette) < 256 {
+			seen[c] = true
+			palette = append(palette, c)
+		}
+	}
+	for _, c := range append(fills, strokes...) {
+		add(c)
+	}
+	for _, fg := range strokes {
+		for _, bg := range fills {
+			for step := 1; step < 8; step++ {
+				add(mix(fg, bg, float64(step)/8))
+			}
+		}
+	}
+	return palette
+}
+
+// quantize maps an image onto the nearest colors of palette. Boards reuse
+// few colors, so the nearest color of each is remembered in colors.
+func quantize(img *image.RGBA, palette color.Palette, colors map[color.RGBA]uint8) *image.Paletted {
+	out := image.NewPaletted(img.Bounds(), palette)
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: 0xff}
+		index, ok := colors[c]
+		if !ok {
+			index = uint8(palette.Index(c))
+			colors[c] = index
+		}
+		out.Pix[i/4] = index
+	}
+	return out
+}
+
+// difference returns the smallest rectangle holding every pixel that
+// differs between two frames of the same size
+func difference(a, b *image.Paletted) image.Rectangle {
+	bounds := a.Bounds()
+	minX, minY, maxX, maxY := bounds.Max.X, bounds.Max.Y, bounds.Min.X-1, bounds.Min.Y-1
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := a.PixOffset(bounds.Min.X, y)
+		for x := 0; x < bounds.Dx(); x++ {
+			if a.Pix[row+x] != b.Pix[row+x] {
+				minX = min(minX, bounds.Min.X+x)
+				maxX = max(maxX, bounds.Min.X+x)
+				minY = min(minY, y)
+				maxY = y
+			}
+		}
+	}
+	if maxY < minY {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
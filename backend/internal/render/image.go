@@ -0,0 +1,108 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"t-9/internal/game"
+)
+
+// Image draws a position as a raster image
+func Image(state *game.GameState, options Options) (*image.RGBA, error) {
+	options, theme, err := options.check()
+	if err != nil {
+		return nil, err
+	}
+	return raster(scene(state, options.Size, theme), options.Size), nil
+}
+
+// raster draws a scene onto a new square image
+func raster(shapes []shape, size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for _, s := range shapes {
+		drawShape(img, s)
+	}
+	return img
+}
+
+// PNG writes a position as a PNG image
+func PNG(w io.Writer, state *game.GameState, options Options) error {
+	img, err := Image(state, options)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// drawShape blends a shape onto img. Edges are antialiased by how much of
+// each pixel the shape covers.
+func drawShape(img *image.RGBA, s shape) {
+	margin := s.width/2 + 1
+	var minX, minY, maxX, maxY float64
+	switch s.kind {
+	case rectShape:
+		minX, minY, maxX, maxY = s.x1, s.y1, s.x2, s.y2
+	case lineShape:
+		minX, maxX = math.Min(s.x1, s.x2)-margin, math.Max(s.x1, s.x2)+margin
+		minY, maxY = math.Min(s.y1, s.y2)-margin, math.Max(s.y1, s.y2)+margin
+	case circleShape:
+		minX, minY = s.x1-s.r-margin, s.y1-s.r-margin
+		maxX, maxY = s.x1+s.r+margin, s.y1+s.r+margin
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(img.Bounds())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Pixel centres
+			px, py := float64(x)+0.5, float64(y)+0.5
+			var coverage float64
+			switch s.kind {
+			case rectShape:
+				coverage = overlap(float64(x), float64(x+1), s.x1, s.x2) * overlap(float64(y), float64(y+1), s.y1, s.y2)
+			case lineShape:
+				coverage = edge(s.width/2 - segmentDistance(px, py, s.x1, s.y1, s.x2, s.y2))
+			case circleShape:
+				coverage = edge(s.width/2 - math.Abs(math.Hypot(px-s.x1, py-s.y1)-s.r))
+			}
+			if coverage > 0 {
+				blend(img, x, y, s.color, coverage)
+			}
+		}
+	}
+}
+
+// overlap returns how much of [a1, a2] lies in [b1, b2]
+func overlap(a1, a2, b1, b2 float64) float64 {
+	return math.Max(0, math.Min(a2, b2)-math.Max(a1, b1))
+}
+
+// edge turns the distance of a pixel centre inside a shape's edge into the
+// share of the pixel covered
+func edge(inside float64) float64 {
+	return math.Max(0, math.Min(1, inside+0.5))
+}
+
+// segmentDistance returns the distance from (px, py) to the segment from
+// (x1, y1) to (x2, y2)
+func segmentDistance(px, py, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((px-x1)*dx+(py-y1)*dy)/length))
+	}
+	return math.Hypot(px-(x1+t*dx), py-(y1+t*dy))
+}
+
+// blend draws c over the pixel at (x, y) with the given coverage
+func blend(img *image.RGBA, x, y int, c color.NRGBA, coverage float64) {
+	alpha := float64(c.A) / 0xff * coverage
+	i := img.PixOffset(x, y)
+	pix := img.Pix[i : i+4 : i+4]
+	pix[0] = uint8(float64(pix[0])*(1-alpha) + float64(c.R)*alpha + 0.5)
+	pix[1] = uint8(float64(pix[1])*(1-alpha) + float64(c.G)*alpha + 0.5)
+	pix[2] = uint8(float64(pix[2])*(1-alpha) + float64(c.B)*alpha + 0.5)
+	pix[3] = uint8(float64(pix[3])*(1-alpha) + 0xff*alpha + 0.5)
+}
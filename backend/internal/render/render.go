@@ -0,0 +1,237 @@
+// Package render draws game positions as SVG or raster images. Both formats
+// are drawn from the same scene of shapes, so they look alike.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	"t-9/internal/game"
+)
+
+const (
+	DefaultSize = 480
+	MinSize     = 120
+	MaxSize     = 2048
+)
+
+// Theme is the colors a board is drawn in
+type Theme struct {
+	Background color.NRGBA
+	Grid       color.NRGBA // Lines between cells
+	BoardGrid  color.NRGBA // Lines between small boards
+	X          color.NRGBA
+	O          color.NRGBA
+	Active     color.NRGBA // Small boards the next move may be played in
+	LastMove   color.NRGBA // Cell of the last move
+	WonBoard   color.NRGBA // Small boards already decided
+	WinLine    color.NRGBA // Line through the boards that won the game
+	Banner     color.NRGBA // Band behind the result of a replay
+	BannerText color.NRGBA
+}
+
+// themes are the themes a board can be drawn in, by name. The light theme
+// matches the web client.
+var themes = map[string]Theme{
+	"light": {
+		Background: rgb(0xf9f9f9),
+		Grid:       rgb(0xcccccc),
+		BoardGrid:  rgb(0x666666),
+		X:          rgb(0xd32f2f),
+		O:          rgb(0x1976d2),
+		Active:     rgb(0xd6ebfa),
+		LastMove:   rgb(0xfff3b0),
+		WonBoard:   rgb(0xe8e8e8),
+		WinLine:    rgb(0x2e7d32),
+		Banner:     color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xe6},
+		BannerText: rgb(0xffffff),
+	},
+	"dark": {
+		Background: rgb(0x1e1e1e),
+		Grid:       rgb(0x3c3c3c),
+		BoardGrid:  rgb(0x9e9e9e),
+		X:          rgb(0xef5350),
+		O:          rgb(0x42a5f5),
+		Active:     rgb(0x1d3a52),
+		LastMove:   rgb(0x5c5222),
+		WonBoard:   rgb(0x2c2c2c),
+		WinLine:    rgb(0x66bb6a),
+		Banner:     color.NRGBA{R: 0xee, G: 0xee, B: 0xee, A: 0xe6},
+		BannerText: rgb(0x1e1e1e),
+	},
+}
+
+func rgb(value uint32) color.NRGBA {
+	return color.NRGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}
+}
+
+// ThemeNames lists the themes, sorted
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options controls how a position is drawn
+type Options struct {
+	Size  int    // Width and height in pixels
+	Theme string // Theme name, light unless given
+}
+
+// check fills in defaults and rejects options out of range
+func (o Options) check() (Options, Theme, error) {
+	if o.Size == 0 {
+		o.Size = DefaultSize
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return o, Theme{}, fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Theme == "" {
+		o.Theme = "light"
+	}
+	theme, ok := themes[o.Theme]
+	if !ok {
+		return o, Theme{}, fmt.Errorf("unknown theme %q", o.Theme)
+	}
+	return o, theme, nil
+}
+
+// Check reports whether the options can be drawn with
+func (o Options) Check() error {
+	_, _, err := o.check()
+	return err
+}
+
+// shapeKind is the kind of a shape in a scene
+type shapeKind int
+
+const (
+	rectShape   shapeKind = iota // Filled rectangle from (x1, y1) to (x2, y2)
+	lineShape                    // Line from (x1, y1) to (x2, y2) with round caps
+	circleShape                  // Circle outline around (x1, y1) with radius r
+)
+
+// shape is one element of a scene, in pixels
+type shape struct {
+	kind           shapeKind
+	x1, y1, x2, y2 float64
+	r              float64
+	width          float64 // Stroke width of lines and circles
+	color          color.NRGBA
+}
+
+// scene lays out a position as shapes, drawn in order
+func scene(state *game.GameState, size int, theme Theme) []shape {
+	s := float64(size)
+	pad := s * 0.03
+	boardSize := (s - 2*pad) / 3
+	inset := boardSize * 0.06
+	cellSize := (boardSize - 2*inset) / 3
+
+	boardOrigin := func(board int) (float64, float64) {
+		return pad + float64(board%3)*boardSize, pad + float64(board/3)*boardSize
+	}
+	cellOrigin := func(board, cell int) (float64, float64) {
+		x, y := boardOrigin(board)
+		return x + inset + float64(cell%3)*cellSize, y + inset + float64(cell/3)*cellSize
+	}
+
+	shapes := []shape{{kind: rectShape, x2: s, y2: s, color: theme.Background}}
+
+	// Small boards: decided, or open for the next move
+	for board := 0; board < 9; board++ {
+		x, y := boardOrigin(board)
+		fill := color.NRGBA{}
+		switch {
+		case state.BigBoardWins[board] != game.Empty || boardFull(state, board):
+			fill = theme.WonBoard
+		case !state.GameOver && (state.ActiveBoard == -1 || state.ActiveBoard == board):
+			fill = theme.Active
+		}
+		if fill.A > 0 {
+			shapes = append(shapes, shape{kind: rectShape, x1: x + inset/2, y1: y + inset/2, x2: x + boardSize - inset/2, y2: y + boardSize - inset/2, color: fill})
+		}
+	}
+
+	if n := len(state.MoveHistory); n > 0 {
+		last := state.MoveHistory[n-1]
+		x, y := cellOrigin(last.BigBoardIndex, last.SmallBoardIndex)
+		shapes = append(shapes, shape{kind: rectShape, x1: x, y1: y, x2: x + cellSize, y2: y + cellSize, color: theme.LastMove})
+	}
+
+	// Grid lines
+	gridWidth := s * 0.004
+	for board := 0; board < 9; board++ {
+		x, y := boardOrigin(board)
+		for i := 1; i < 3; i++ {
+			offset := inset + float64(i)*cellSize
+			shapes = append(shapes,
+				shape{kind: lineShape, x1: x + offset, y1: y + inset, x2: x + offset, y2: y + boardSize - inset, width: gridWidth, color: theme.Grid},
+				shape{kind: lineShape, x1: x + inset, y1: y + offset, x2: x + boardSize - inset, y2: y + offset, width: gridWidth, color: theme.Grid},
+			)
+		}
+	}
+	boardGridWidth := s * 0.01
+	for i := 1; i < 3; i++ {
+		offset := pad + float64(i)*boardSize
+		shapes = append(shapes,
+			shape{kind: lineShape, x1: offset, y1: pad, x2: offset, y2: s - pad, width: boardGridWidth, color: theme.BoardGrid},
+			shape{kind: lineShape, x1: pad, y1: offset, x2: s - pad, y2: offset, width: boardGridWidth, color: theme.BoardGrid},
+		)
+	}
+
+	// Marks, and a large mark over each won board
+	for board := 0; board < 9; board++ {
+		for cell := 0; cell < 9; cell++ {
+			if player := state.BigBoard[board][cell]; player != game.Empty {
+				x, y := cellOrigin(board, cell)
+				shapes = append(shapes, mark(player, x, y, cellSize, theme)...)
+			}
+		}
+	}
+	for board := 0; board < 9; board++ {
+		if winner := state.BigBoardWins[board]; winner != game.Empty {
+			x, y := boardOrigin(board)
+			won := mark(winner, x+inset, y+inset, boardSize-2*inset, theme)
+			for i := range won {
+				won[i].color.A = 0xc0
+			}
+			shapes = append(shapes, won...)
+		}
+	}
+
+	if line := state.WinningLine(); line != nil {
+		x1, y1 := boardOrigin(line[0])
+		x2, y2 := boardOrigin(line[2])
+		half := boardSize / 2
+		shapes = append(shapes, shape{kind: lineShape, x1: x1 + half, y1: y1 + half, x2: x2 + half, y2: y2 + half, width: s * 0.025, color: theme.WinLine})
+	}
+	return shapes
+}
+
+// mark draws a player's mark in the square at (x, y)
+func mark(player game.Player, x, y, size float64, theme Theme) []shape {
+	margin := size * 0.22
+	width := size * 0.1
+	if player == game.X {
+		return []shape{
+			{kind: lineShape, x1: x + margin, y1: y + margin, x2: x + size - margin, y2: y + size - margin, width: width, color: theme.X},
+			{kind: lineShape, x1: x + size - margin, y1: y + margin, x2: x + margin, y2: y + size - margin, width: width, color: theme.X},
+		}
+	}
+	return []shape{{kind: circleShape, x1: x + size/2, y1: y + size/2, r: size/2 - margin, width: width, color: theme.O}}
+}
+
+// boardFull reports whether every cell of a small board is taken
+func boardFull(state *game.GameState, board int) bool {
+	for _, cell := range state.BigBoard[board] {
+		if cell == game.Empty {
+			return false
+		}
+	}
+	return true
+}
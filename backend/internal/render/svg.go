@@ -0,0 +1,49 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+
+	"t-9/internal/game"
+)
+
+// SVG writes a position as an SVG image
+func SVG(w io.Writer, state *game.GameState, options Options) error {
+	options, theme, err := options.check()
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		options.Size, options.Size, options.Size, options.Size)
+	for _, s := range scene(state, options.Size, theme) {
+		switch s.kind {
+		case rectShape:
+			fmt.Fprintf(out, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"%s/>`+"\n",
+				s.x1, s.y1, s.x2-s.x1, s.y2-s.y1, svgColor(s.color), svgOpacity("fill", s.color))
+		case lineShape:
+			fmt.Fprintf(out, `<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="%s" stroke-width="%.2f" stroke-linecap="round"%s/>`+"\n",
+				s.x1, s.y1, s.x2, s.y2, svgColor(s.color), s.width, svgOpacity("stroke", s.color))
+		case circleShape:
+			fmt.Fprintf(out, `<circle cx="%.2f" cy="%.2f" r="%.2f" fill="none" stroke="%s" stroke-width="%.2f"%s/>`+"\n",
+				s.x1, s.y1, s.r, svgColor(s.color), s.width, svgOpacity("stroke", s.color))
+		}
+	}
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
+
+func svgColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// svgOpacity returns the opacity attribute of a translucent color
+func svgOpacity(attribute string, c color.NRGBA) string {
+	if c.A == 0xff {
+		return ""
+	}
+	return fmt.Sprintf(` %s-opacity="%.2f"`, attribute, float64(c.A)/0xff)
+}
@@ -0,0 +1,414 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrQueueFull    = errors.New("AI queue is full")
+	ErrClientBusy   = errors.New("too many AI requests from this client")
+	ErrQueueTimeout = errors.New("timed out waiting for an AI worker")
+	ErrClosed       = errors.New("scheduler is closed")
+)
+
+// Config bounds the work a scheduler takes on
+type Config struct {
+	Workers    int           // Jobs run at once
+	QueueSize  int           // Jobs waiting for a worker, across all clients
+	MaxWait    time.Duration // Longest a job waits for a worker
+	ClientJobs int           // Jobs one client may have queued or running
+	Retention  time.Duration // How long finished jobs can still be looked up
+}
+
+// Func is the work of a job. It should return soon after ctx is cancelled.
+type Func func(ctx context.Context) (interface{}, error)
+
+// Scheduler runs jobs on a fixed pool of workers. Waiting jobs are queued per
+// client and clients take turns, so one client cannot starve the others.
+// Background jobs only use workers nobody else needs.
+type Scheduler struct {
+	config Config
+
+	mu      sync.Mutex
+	wake    *sync.Cond
+	jobs    map[string]*Job
+	queues  map[string][]*Job // Waiting jobs of each client, oldest first
+	turns   []string          // Clients with waiting jobs, next to run first
+	queued  int
+	active  map[string]int // Queued and running jobs of each client
+	running int
+	closed  bool
+
+	background map[*Job]struct{} // Background jobs queued or running
+}
+
+// Job is a unit of work submitted to a scheduler
+type Job struct {
+	scheduler  *Scheduler
+	id         string
+	client     string
+	run        Func
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	expiry     *time.Timer
+	background bool // Gives its worker up to other jobs
+
+	// Guarded by the scheduler's mutex
+	status   Status
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	result   interface{}
+	err      error
+}
+
+// JobView is a job as shown to clients
+type JobView struct {
+	ID         string      `json:"id"`
+	Status     Status      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	WaitMs     int64       `json:"waitMs"` // Time spent queued
+	RunMs      int64       `json:"runMs"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// New creates a scheduler and starts its workers
+func New(config Config) *Scheduler {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	s := &Scheduler{
+		config: config,
+		jobs:   map[string]*Job{},
+		queues: map[string][]*Job{},
+		active: map[string]int{},
+
+		background: map[*Job]struct{}{},
+	}
+	s.wake = sync.NewCond(&s.mu)
+	for i := 0; i < config.Workers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+// Submit queues a job for a client. It fails straight away with
+// ErrClientBusy or ErrQueueFull when the limits are reached.
+func (s *Scheduler) Submit(client string, run Func) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.config.ClientJobs > 0 && s.active[client] >= s.config.ClientJobs {
+		return nil, ErrClientBusy
+	}
+	// Jobs that can start at once do not count against the queue. Background
+	// jobs give way, so they do not count at all.
+	if s.running+s.queued-len(s.background) >= s.config.Workers+s.config.QueueSize {
+		return nil, ErrQueueFull
+	}
+
+	job := s.enqueue(client, run)
+	if s.config.MaxWait > 0 {
+		job.expiry = time.AfterFunc(s.config.MaxWait, func() { s.dequeue(job, ErrQueueTimeout) })
+	}
+	s.giveWay()
+	return job, nil
+}
+
+// SubmitBackground queues a job that only runs on a worker nobody else needs.
+// There are at most as many background jobs as workers, and they only queue
+// when no other job is waiting; otherwise it fails with ErrQueueFull. Queued
+// background jobs fail with context.Canceled when another job is submitted,
+// and running ones are cancelled when another job is waiting for their
+// worker.
+func (s *Scheduler) SubmitBackground(client string, run Func) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if len(s.background) >= s.config.Workers || s.queued > s.queuedBackground() {
+		return nil, ErrQueueFull
+	}
+	job := s.enqueue(client, run)
+	job.background = true
+	s.background[job] = struct{}{}
+	return job, nil
+}
+
+// queuedBackground counts the background jobs waiting for a worker. The
+// caller holds s.mu.
+func (s *Scheduler) queuedBackground() int {
+	n := 0
+	for job := range s.background {
+		if job.status == StatusQueued {
+			n++
+		}
+	}
+	return n
+}
+
+// enqueue adds a job to its client's queue. The caller holds s.mu.
+func (s *Scheduler) enqueue(client string, run Func) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		scheduler: s,
+		id:        newJobID(),
+		client:    client,
+		run:       run,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    StatusQueued,
+		created:   time.Now(),
+	}
+
+	s.jobs[job.id] = job
+	s.active[client]++
+	s.queued++
+	if len(s.queues[client]) == 0 {
+		s.turns = append(s.turns, client)
+	}
+	s.queues[client] = append(s.queues[client], job)
+	s.wake.Signal()
+	return job
+}
+
+// giveWay makes room for a job just submitted: background jobs still queued
+// are dropped, and a running one is cancelled if no worker is free. The
+// caller holds s.mu.
+func (s *Scheduler) giveWay() {
+	free := s.config.Workers - s.running - s.queued
+	for job := range s.background {
+		switch {
+		case job.status == StatusQueued:
+			job.cancel()
+			s.remove(job, context.Canceled)
+			free++
+		case free < 0 && job.ctx.Err() == nil:
+			job.cancel()
+			free++
+		}
+	}
+}
+
+// Job looks up a job by ID
+func (s *Scheduler) Job(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[id]
+	return job, exists
+}
+
+// Load returns the number of running and queued jobs
+func (s *Scheduler) Load() (running, queued int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running, s.queued
+}
+
+// Close stops the workers once the running jobs finish. Queued jobs fail
+// with ErrClosed.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	var waiting []*Job
+	for _, jobs := range s.queues {
+		waiting = append(waiting, jobs...)
+	}
+	s.wake.Broadcast()
+	s.mu.Unlock()
+
+	for _, job := range waiting {
+		s.dequeue(job, ErrClosed)
+	}
+}
+
+// worker runs jobs until the scheduler is closed
+func (s *Scheduler) worker() {
+	for {
+		s.mu.Lock()
+		for len(s.turns) == 0 && !s.closed {
+			s.wake.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		job := s.next()
+		job.status = StatusRunning
+		job.started = time.Now()
+		s.running++
+		s.mu.Unlock()
+
+		if job.expiry != nil {
+			job.expiry.Stop()
+		}
+		result, err := job.run(job.ctx)
+
+		s.mu.Lock()
+		s.running--
+		s.finish(job, result, err)
+		s.mu.Unlock()
+	}
+}
+
+// next takes the oldest job of the client whose turn it is. The caller holds
+// s.mu and there must be a waiting job.
+func (s *Scheduler) next() *Job {
+	client := s.turns[0]
+	s.turns = s.turns[1:]
+
+	jobs := s.queues[client]
+	job := jobs[0]
+	if len(jobs) > 1 {
+		s.queues[client] = jobs[1:]
+		s.turns = append(s.turns, client)
+	} else {
+		delete(s.queues, client)
+	}
+	s.queued--
+	return job
+}
+
+// dequeue fails a job that is still waiting for a worker
+func (s *Scheduler) dequeue(job *Job, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(job, err)
+}
+
+// remove is dequeue with the caller holding s.mu
+func (s *Scheduler) remove(job *Job, err error) {
+	if job.status != StatusQueued {
+		return
+	}
+
+	jobs := s.queues[job.client]
+	for i := range jobs {
+		if jobs[i] == job {
+			jobs = append(jobs[:i:i], jobs[i+1:]...)
+			break
+		}
+	}
+	if len(jobs) > 0 {
+		s.queues[job.client] = jobs
+	} else {
+		delete(s.queues, job.client)
+		for i, client := range s.turns {
+			if client == job.client {
+				s.turns = append(s.turns[:i:i], s.turns[i+1:]...)
+				break
+			}
+		}
+	}
+	s.queued--
+	s.finish(job, nil, err)
+}
+
+// finish records a job's outcome and forgets it after the retention time.
+// The caller holds s.mu.
+func (s *Scheduler) finish(job *Job, result interface{}, err error) {
+	job.result, job.err = result, err
+	job.status = StatusDone
+	if err != nil {
+		job.status = StatusFailed
+	}
+	job.finished = time.Now()
+	job.cancel()
+	close(job.done)
+	delete(s.background, job)
+
+	if s.active[job.client]--; s.active[job.client] <= 0 {
+		delete(s.active, job.client)
+	}
+	time.AfterFunc(s.config.Retention, func() {
+		s.mu.Lock()
+		delete(s.jobs, job.id)
+		s.mu.Unlock()
+	})
+}
+
+// ID returns the job's ID
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Done is closed when the job has finished, failed or been cancelled
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Result returns the job's result once it is done
+func (j *Job) Result() (interface{}, error) {
+	<-j.done
+	return j.result, j.err
+}
+
+// Cancel stops a job. A queued job fails with context.Canceled; a running
+// job is asked to stop through its context.
+func (j *Job) Cancel() {
+	j.cancel()
+	j.scheduler.dequeue(j, context.Canceled)
+}
+
+// View returns the job as shown to clients
+func (j *Job) View() JobView {
+	j.scheduler.mu.Lock()
+	defer j.scheduler.mu.Unlock()
+
+	view := JobView{
+		ID:        j.id,
+		Status:    j.status,
+		CreatedAt: j.created,
+	}
+	now := time.Now()
+	if !j.finished.IsZero() {
+		now = j.finished
+		view.FinishedAt = &j.finished
+		view.Result = j.result
+	}
+	if j.err != nil {
+		view.Error = j.err.Error()
+	}
+	if j.started.IsZero() {
+		view.WaitMs = now.Sub(j.created).Milliseconds()
+	} else {
+		view.WaitMs = j.started.Sub(j.created).Milliseconds()
+		view.RunMs = now.Sub(j.started).Milliseconds()
+	}
+	return view
+}
+
+// newJobID creates a random job ID
+func newJobID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		// Fallback to timestamp if crypto/rand fails
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(bytes)
+}
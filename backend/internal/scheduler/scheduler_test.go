@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// block submits a job that runs until release is closed, and waits for it
+// to start so the next jobs queue behind it
+func block(t *testing.T, s *Scheduler, client string, release <-chan struct{}) *Job {
+	t.Helper()
+	started := make(chan struct{})
+	job, err := s.Submit(client, func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	if err != nil {
+		t.Fatalf("submitting blocking job: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("blocking job did not start")
+	}
+	return job
+}
+
+// wait returns a job's outcome, failing the test if it does not finish
+func wait(t *testing.T, job *Job) (interface{}, error) {
+	t.Helper()
+	select {
+	case <-job.Done():
+		return job.Result()
+	case <-time.After(time.Second):
+		t.Fatalf("job %s did not finish", job.ID())
+		return nil, nil
+	}
+}
+
+func TestClientsTakeTurns(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients []string // Submitted in order while the worker is busy
+		want    []string // Order the jobs run in
+	}{
+		{"one client", []string{"a", "a", "a"}, []string{"a", "a", "a"}},
+		{"two clients", []string{"a", "a", "a", "b"}, []string{"a", "b", "a", "a"}},
+		{"three clients", []string{"a", "a", "a", "b", "b", "c"}, []string{"a", "b", "c", "a", "b", "a"}},
+		{"late client", []string{"a", "b", "a", "a", "c"}, []string{"a", "b", "c", "a", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Config{Workers: 1, QueueSize: 10, Retention: time.Minute})
+			defer s.Close()
+			release := make(chan struct{})
+			block(t, s, "blocker", release)
+
+			var mu sync.Mutex
+			var order []string
+			var jobs []*Job
+			for _, client := range tt.clients {
+				client := client
+				job, err := s.Submit(client, func(context.Context) (interface{}, error) {
+					mu.Lock()
+					order = append(order, client)
+					mu.Unlock()
+					return nil, nil
+				})
+				if err != nil {
+					t.Fatalf("submitting job for %s: %v", client, err)
+				}
+				jobs = append(jobs, job)
+			}
+
+			close(release)
+			for _, job := range jobs {
+				wait(t, job)
+			}
+			if !reflect.DeepEqual(order, tt.want) {
+				t.Errorf("jobs ran in order %v, want %v", order, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		clients []string // Submitted while the worker is busy
+		want    []error
+	}{
+		{"client limit", Config{Workers: 1, QueueSize: 10, ClientJobs: 2}, []string{"a", "a", "b"}, []error{nil, ErrClientBusy, nil}},
+		{"queue limit", Config{Workers: 1, QueueSize: 2}, []string{"a", "b", "c"}, []error{nil, nil, ErrQueueFull}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Retention = time.Minute
+			s := New(tt.config)
+			defer s.Close()
+			release := make(chan struct{})
+			defer close(release)
+			block(t, s, "a", release)
+
+			for i, client := range tt.clients {
+				_, err := s.Submit(client, func(context.Context) (interface{}, error) { return nil, nil })
+				if !errors.Is(err, tt.want[i]) {
+					t.Errorf("job %d for %s: error = %v, want %v", i, client, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name string
+		// stop ends the job in some way while the blocking job holds the
+		// worker, or while job itself is running if running is set
+		stop    func(s *Scheduler, job *Job)
+		config  Config
+		running bool
+		want    error
+	}{
+		{"cancel queued", func(s *Scheduler, job *Job) { job.Cancel() }, Config{}, false, context.Canceled},
+		{"cancel running", func(s *Scheduler, job *Job) { job.Cancel() }, Config{}, true, context.Canceled},
+		{"wait too long", func(s *Scheduler, job *Job) {}, Config{MaxWait: 20 * time.Millisecond}, false, ErrQueueTimeout},
+		{"close", func(s *Scheduler, job *Job) { s.Close() }, Config{}, false, ErrClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Workers, tt.config.QueueSize, tt.config.Retention = 1, 10, time.Minute
+			s := New(tt.config)
+			defer s.Close()
+			release := make(chan struct{})
+			defer close(release)
+			if !tt.running {
+				block(t, s, "blocker", release)
+			}
+
+			started := make(chan struct{})
+			ran := false
+			job, err := s.Submit("a", func(ctx context.Context) (interface{}, error) {
+				ran = true
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			if err != nil {
+				t.Fatalf("submitting job: %v", err)
+			}
+			if tt.running {
+				<-started
+			}
+
+			tt.stop(s, job)
+			if _, err := wait(t, job); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if ran != tt.running {
+				t.Errorf("job ran = %v, want %v", ran, tt.running)
+			}
+			if view := job.View(); view.Status != StatusFailed {
+				t.Errorf("status = %s, want %s", view.Status, StatusFailed)
+			}
+			if running, queued := s.Load(); tt.running && running+queued != 0 {
+				t.Errorf("load = %d running, %d queued after the job finished", running, queued)
+			}
+		})
+	}
+}
+
+func TestBackgroundJobsGiveWay(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool // Whether the background job has started when another job is submitted
+	}{
+		{"queued", false},
+		{"running", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Config{Workers: 1, QueueSize: 10, Retention: time.Minute})
+			defer s.Close()
+			release := make(chan struct{})
+			defer close(release)
+			var blocker *Job
+			if !tt.running {
+				blocker = block(t, s, "blocker", release)
+			}
+
+			started := make(chan struct{})
+			background, err := s.SubmitBackground("a", func(ctx context.Context) (interface{}, error) {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			if err != nil {
+				t.Fatalf("submitting background job: %v", err)
+			}
+			if tt.running {
+				<-started
+			}
+
+			job, err := s.Submit("b", func(context.Context) (interface{}, error) { return "done", nil })
+			if err != nil {
+				t.Fatalf("submitting job: %v", err)
+			}
+			if _, err := wait(t, background); !errors.Is(err, context.Canceled) {
+				t.Errorf("background job error = %v, want %v", err, context.Canceled)
+			}
+			if blocker != nil {
+				blocker.Cancel()
+			}
+			if result, err := wait(t, job); err != nil || result != "done" {
+				t.Errorf("job = %v, %v; want done", result, err)
+			}
+		})
+	}
+}
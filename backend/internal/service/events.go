@@ -0,0 +1,170 @@
+package service
+
+import (
+	"time"
+
+	"t-9/internal/game"
+)
+
+// EventType is the kind of change an event reports
+type EventType string
+
+const (
+	EventMove    EventType = "move"    // A move was played
+	EventResult  EventType = "result"  // The game finished, by a move or resignation
+	EventAbandon EventType = "abandon" // The game was abandoned without a result
+	EventJoin    EventType = "join"    // A WebSocket client joined
+	EventLeave   EventType = "leave"   // A WebSocket client left
+	EventClosed  EventType = "closed"  // The game was deleted or archived, no more events follow
+	EventAIError EventType = "aiError" // The AI playing a seat could not move, the error says why
+
+	// EventState is not published. It is a snapshot of the game given to a
+	// subscriber in place of events it missed.
+	EventState EventType = "state"
+)
+
+// eventHistory is how many of a game's latest events are kept for
+// subscribers resuming after a disconnect. Joins, leaves and AI errors have no
+// limit, so a game can outgrow it; its move and result events are also kept
+// for the whole game, which has at most 81 moves.
+const eventHistory = 128
+
+// Event is a change to a game. Events of a game are numbered from 1 in the
+// order they happened.
+type Event struct {
+	ID     int             `json:"id"`
+	Type   EventType       `json:"type"`
+	GameID string          `json:"gameId"`
+	Move   *game.Move      `json:"move,omitempty"`
+	Player game.Player     `json:"player,omitempty"` // Seat that moved, resigned, joined or left
+	Error  string          `json:"error,omitempty"`  // Why the AI could not move
+	Game   *game.GameState `json:"game"`             // The game after the change
+	Status string          `json:"status"`           // Lifecycle state after the change
+	Time   time.Time       `json:"time"`
+	Origin Origin          `json:"-"`
+}
+
+// Subscription receives the events of one game
+type Subscription struct {
+	service *GameService
+	session *session
+	events  chan Event
+	closed  bool // Guarded by the service's mutex
+}
+
+// Subscribe starts receiving the events of a game. The subscription's channel
+// is closed when the game is closed, the subscriber falls too far behind or
+// the subscription is closed.
+func (s *GameService) Subscribe(id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return s.subscribe(sess), nil
+}
+
+// SubscribeAfter is Subscribe for a subscriber that has seen the events of a
+// game up to the one numbered after. It also returns the events published
+// since. If they are no longer all kept, it returns the move and result
+// events published since followed by a snapshot of the game, so no move is
+// missed. A subscriber with no events seen passes a negative after to get the
+// snapshot alone.
+func (s *GameService) SubscribeAfter(id string, after int) (*Subscription, []Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		return nil, nil, ErrNotFound
+	}
+
+	var missed []Event
+	first := sess.lastEvent - len(sess.history) + 1
+	if after >= first-1 && after <= sess.lastEvent {
+		missed = append(missed, sess.history[after-first+1:]...)
+	} else {
+		if after >= 0 {
+			for _, event := range sess.record {
+				if event.ID > after {
+					missed = append(missed, event)
+				}
+			}
+		}
+		missed = append(missed, Event{
+			ID:     sess.lastEvent,
+			Type:   EventState,
+			GameID: sess.id,
+			Game:   sess.state.Clone(),
+			Status: sess.status(),
+			Time:   sess.updatedAt,
+		})
+	}
+	return s.subscribe(sess), missed, nil
+}
+
+// subscribe adds a subscriber to a game. The caller holds s.mu.
+func (s *GameService) subscribe(sess *session) *Subscription {
+	sub := &Subscription{
+		service: s,
+		session: sess,
+		events:  make(chan Event, subscriptionBuffer),
+	}
+	sess.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Events returns the channel events are delivered on
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close stops the subscription
+func (sub *Subscription) Close() {
+	sub.service.mu.Lock()
+	defer sub.service.mu.Unlock()
+	sub.close()
+}
+
+// close ends the subscription. The caller holds the service's mutex.
+func (sub *Subscription) close() {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(sub.session.subscribers, sub)
+	close(sub.events)
+}
+
+// publish numbers an event, keeps it in the game's history and delivers it to
+// the game's subscribers. Moves and results are also kept in the game's
+// record. A subscriber whose buffer is full is dropped. The caller holds s.mu.
+func (s *GameService) publish(sess *session, event Event, origin Origin) Event {
+	now := time.Now()
+	sess.lastEvent++
+	sess.updatedAt = now
+
+	event.ID = sess.lastEvent
+	event.GameID = sess.id
+	event.Game = sess.state.Clone()
+	event.Status = sess.status()
+	event.Time = now
+	event.Origin = origin
+
+	if len(sess.history) == eventHistory {
+		sess.history = append(sess.history[:0], sess.history[1:]...)
+	}
+	sess.history = append(sess.history, event)
+	if event.Type == EventMove || event.Type == EventResult {
+		sess.record = append(sess.record, event)
+	}
+
+	for sub := range sess.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			sub.close()
+		}
+	}
+	return event
+}
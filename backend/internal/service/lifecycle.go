@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"t-9/internal/game"
+	"t-9/internal/logging"
+)
+
+// Lifecycle states of a game. Archived games have been written to the archive
+// and are no longer held in memory.
+const (
+	StateCreated   = "created"
+	StateActive    = "active"
+	StateFinished  = "finished"
+	StateAbandoned = "abandoned"
+	StateArchived  = "archived"
+)
+
+// Summary describes a game without its board
+type Summary struct {
+	GameID    string    `json:"gameId"`
+	Mode      Mode      `json:"mode"`
+	Status    string    `json:"status"`           // Lifecycle state
+	Result    string    `json:"result,omitempty"` // x, o or draw once finished
+	Players   Players   `json:"players"`
+	MoveCount int       `json:"moveCount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"` // Last activity
+	Version   int       `json:"version"`   // Number of the latest event, changed by every change to the game
+}
+
+// Players names who plays each side of a game
+type Players struct {
+	X string `json:"x,omitempty"`
+	O string `json:"o,omitempty"`
+}
+
+// Has reports whether name plays either side
+func (p Players) Has(name string) bool {
+	return p.X == name || p.O == name
+}
+
+// status returns the lifecycle state of a game. The caller holds the
+// service's mutex.
+func (sess *session) status() string {
+	switch {
+	case sess.state.GameOver:
+		return StateFinished
+	case sess.abandoned:
+		return StateAbandoned
+	case len(sess.state.MoveHistory) == 0:
+		return StateCreated
+	default:
+		return StateActive
+	}
+}
+
+// summary summarises a game. The caller holds the service's mutex.
+func (sess *session) summary() Summary {
+	summary := Summary{
+		GameID:    sess.id,
+		Mode:      sess.mode,
+		Status:    sess.status(),
+		Players:   Players{X: sess.seats[game.X].Name, O: sess.seats[game.O].Name},
+		MoveCount: len(sess.state.MoveHistory),
+		CreatedAt: sess.createdAt,
+		UpdatedAt: sess.updatedAt,
+		Version:   sess.lastEvent,
+	}
+	if sess.state.GameOver {
+		switch sess.state.GameWon {
+		case game.X:
+			summary.Result = "x"
+		case game.O:
+			summary.Result = "o"
+		default:
+			summary.Result = "draw"
+		}
+	}
+	return summary
+}
+
+// ReaperConfig sets how long games are kept in each state
+type ReaperConfig struct {
+	Interval     time.Duration // Time between checks
+	CreatedTTL   time.Duration // Idle time before a game with no moves is abandoned
+	ActiveTTL    time.Duration // Idle time before a game in progress is abandoned
+	FinishedTTL  time.Duration // Time a finished game is kept before it is archived
+	AbandonedTTL time.Duration // Time an abandoned game is kept before it is archived
+	ArchiveFile  string        // Archived games are appended here, empty to drop them
+}
+
+// archivedGame is a game as written to the archive
+type archivedGame struct {
+	Summary
+	Game       *game.GameState `json:"game"`
+	ArchivedAt time.Time       `json:"archivedAt"`
+}
+
+// RunReaper abandons idle games and archives finished and abandoned ones once
+// they are past their TTL, until the process exits
+func (s *GameService) RunReaper(config ReaperConfig) {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.reap(config, now)
+	}
+}
+
+// reap runs one pass of the reaper
+func (s *GameService) reap(config ReaperConfig, now time.Time) {
+	ttls := map[string]time.Duration{
+		StateCreated:   config.CreatedTTL,
+		StateActive:    config.ActiveTTL,
+		StateFinished:  config.FinishedTTL,
+		StateAbandoned: config.AbandonedTTL,
+	}
+
+	s.mu.Lock()
+	var events []Event
+	var expired []archivedGame
+	for _, sess := range s.sessions {
+		state := sess.status()
+		if now.Sub(sess.updatedAt) <= ttls[state] {
+			continue
+		}
+		switch state {
+		case StateCreated, StateActive:
+			sess.abandoned = true
+			events = append(events, s.publish(sess, Event{Type: EventAbandon}, Origin{}))
+		case StateFinished, StateAbandoned:
+			record := archivedGame{
+				Summary:    sess.summary(),
+				Game:       sess.state.Clone(),
+				ArchivedAt: now,
+			}
+			record.Status = StateArchived
+			expired = append(expired, record)
+		}
+	}
+	abandoned := len(events)
+	s.mu.Unlock()
+	s.notify(events)
+
+	// Games that cannot be archived stay in memory for the next pass
+	if err := appendArchive(config.ArchiveFile, expired); err != nil {
+		logging.DefaultLogger.Error("Failed to archive games", err, map[string]interface{}{
+			"file":  config.ArchiveFile,
+			"games": len(expired),
+		})
+		expired = nil
+	}
+
+	s.mu.Lock()
+	events = nil
+	for _, record := range expired {
+		if sess, exists := s.sessions[record.GameID]; exists {
+			events = append(events, s.remove(sess, Origin{}))
+		}
+	}
+	remaining := len(s.sessions)
+	s.mu.Unlock()
+	s.notify(events)
+
+	if abandoned > 0 || len(expired) > 0 {
+		logging.DefaultLogger.Info("Reaped games", map[string]interface{}{
+			"abandoned": abandoned,
+			"archived":  len(expired),
+			"remaining": remaining,
+		})
+	}
+}
+
+// appendArchive appends games to the archive file, one JSON object per line.
+// Without a file the games are dropped.
+func appendArchive(path string, games []archivedGame) error {
+	if path == "" || len(games) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, record := range games {
+		if err := encoder.Encode(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
@@ -0,0 +1,473 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"t-9/internal/game"
+)
+
+// Mode is how a game is played
+type Mode string
+
+const (
+	ModeLocal       Mode = "local"       // Both sides played from one client
+	ModeAI          Mode = "ai"          // The server's AI plays a seat
+	ModeMultiplayer Mode = "multiplayer" // Created by players joining over WebSocket
+)
+
+var (
+	ErrNotFound    = errors.New("game not found")
+	ErrInvalidID   = errors.New("game ID must be 1-64 characters")
+	ErrAbandoned   = errors.New("game has been abandoned")
+	ErrNotYourTurn = errors.New("not your turn")
+	ErrAISeat      = errors.New("seat is played by the AI")
+	ErrNoSeat      = errors.New("player must be X or O")
+	ErrStale       = errors.New("game has moved on")
+)
+
+// AnyPly and AnyVersion let MoveAtPly play a move whatever the number of
+// moves so far and whatever the game's version
+const (
+	AnyPly     = -1
+	AnyVersion = -1
+)
+
+// maxIDLength bounds the game IDs clients may choose when joining
+const maxIDLength = 64
+
+// subscriptionBuffer is how many events a subscriber may fall behind by
+// before it is dropped
+const subscriptionBuffer = 256
+
+// Seat is one side of a game
+type Seat struct {
+	Name   string // Who plays the seat, if known
+	AI     bool   // The seat is played by the server's AI
+	Client string // WebSocket client sitting in the seat, if any
+
+	token     string // Issued to whoever claimed the seat
+	transient bool   // Claimed by a WebSocket client and given up when it leaves
+}
+
+// Origin says who made a change to a game
+type Origin struct {
+	Client string // Client ID or address
+	AI     bool   // The change was made by an AI
+	Name   string // Name of the AI
+	Waits  bool   // The client is answered with the AI opponent's reply to its move
+}
+
+// GameService owns every game, whether it is played over REST or WebSocket.
+// It validates changes to games and publishes them as events.
+type GameService struct {
+	mu        sync.RWMutex
+	sessions  map[string]*session
+	listeners []func(Event)
+}
+
+// session is one game held by the service
+type session struct {
+	id          string
+	mode        Mode
+	state       *game.GameState
+	seats       [3]Seat // Indexed by game.Player
+	createdAt   time.Time
+	updatedAt   time.Time // Last event
+	abandoned   bool
+	lastEvent   int
+	history     []Event // Latest events, oldest first
+	record      []Event // Every move and result event, oldest first
+	subscribers map[*Subscription]struct{}
+
+	observerToken string
+}
+
+// New creates an empty game service
+func New() *GameService {
+	return &GameService{sessions: map[string]*session{}}
+}
+
+// OnEvent registers a listener called with every event of every game. It is
+// called in order after the change, from the goroutine that made it.
+func (s *GameService) OnEvent(listener func(Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// CreateOptions describes a new game
+type CreateOptions struct {
+	Mode  Mode
+	X, O  Seat
+	Claim []game.Player // Seats the creator takes, AI seats aside
+}
+
+// Create starts a new game under a fresh ID. It returns the tokens of the
+// seats the creator claimed.
+func (s *GameService) Create(options CreateOptions) (string, *game.GameState, Tokens) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := generateID()
+	for s.sessions[id] != nil {
+		id = generateID()
+	}
+	sess := s.newSession(id, options.Mode)
+	sess.seats[game.X] = options.X
+	sess.seats[game.O] = options.O
+
+	tokens := Tokens{Observer: sess.observerToken}
+	for _, player := range options.Claim {
+		if player != game.X && player != game.O {
+			continue
+		}
+		seat := &sess.seats[player]
+		if seat.AI || seat.token != "" {
+			continue
+		}
+		seat.token = newToken()
+		if player == game.X {
+			tokens.X = seat.token
+		} else {
+			tokens.O = seat.token
+		}
+	}
+	return id, sess.state.Clone(), tokens
+}
+
+// Open returns the game with an ID chosen by a client, creating it if there
+// is none. It reports whether the game was created.
+func (s *GameService) Open(id string, mode Mode) (bool, error) {
+	if id == "" || len(id) > maxIDLength {
+		return false, ErrInvalidID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[id] != nil {
+		return false, nil
+	}
+	s.newSession(id, mode)
+	return true, nil
+}
+
+// newSession adds a game. The caller holds s.mu.
+func (s *GameService) newSession(id string, mode Mode) *session {
+	now := time.Now()
+	sess := &session{
+		id:          id,
+		mode:        mode,
+		state:       game.NewGame(),
+		createdAt:   now,
+		updatedAt:   now,
+		subscribers: map[*Subscription]struct{}{},
+
+		observerToken: newToken(),
+	}
+	s.sessions[id] = sess
+	return sess
+}
+
+// Get returns a copy of a game and its summary
+func (s *GameService) Get(id string) (*game.GameState, Summary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		return nil, Summary{}, ErrNotFound
+	}
+	return sess.state.Clone(), sess.summary(), nil
+}
+
+// List returns a summary of every game
+func (s *GameService) List() []Summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summaries := make([]Summary, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		summaries = append(summaries, sess.summary())
+	}
+	return summaries
+}
+
+// Move plays a move and returns the game after it. Only an AI may move for
+// a seat the AI plays.
+func (s *GameService) Move(id string, move game.Move, origin Origin) (*game.GameState, error) {
+	state, _, err := s.MoveAtPly(id, AnyPly, AnyVersion, move, origin)
+	return state, err
+}
+
+// MoveAtPly plays a move like Move, but only if the game has had ply moves so
+// far and is at version, the version of its summary. Otherwise it returns
+// ErrStale, so a client moving on an old position can tell its move was not
+// played. It also returns the game's summary as of the move.
+func (s *GameService) MoveAtPly(id string, ply, version int, move game.Move, origin Origin) (*game.GameState, Summary, error) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, Summary{}, ErrNotFound
+	}
+	if ply != AnyPly && ply != len(sess.state.MoveHistory) || version != AnyVersion && version != sess.lastEvent {
+		s.mu.Unlock()
+		return nil, Summary{}, ErrStale
+	}
+	if err := sess.checkTurn(move.Player, origin); err != nil {
+		s.mu.Unlock()
+		return nil, Summary{}, err
+	}
+	if err := sess.state.MakeMove(move); err != nil {
+		s.mu.Unlock()
+		return nil, Summary{}, err
+	}
+	// An AI seat is listed under the engine that last played it. A player
+	// who asks the AI to move for them keeps their seat and name.
+	if origin.AI && sess.seats[move.Player].AI {
+		sess.mode = ModeAI
+		sess.seats[move.Player].Name = origin.Name
+	}
+
+	events := []Event{s.publish(sess, Event{Type: EventMove, Move: &move, Player: move.Player}, origin)}
+	if sess.state.GameOver {
+		events = append(events, s.publish(sess, Event{Type: EventResult}, origin))
+	}
+	state, summary := sess.state.Clone(), sess.summary()
+	s.mu.Unlock()
+
+	s.notify(events)
+	return state, summary, nil
+}
+
+// checkTurn checks that player may move now. The caller holds s.mu.
+func (sess *session) checkTurn(player game.Player, origin Origin) error {
+	switch {
+	case sess.abandoned:
+		return ErrAbandoned
+	case sess.state.GameOver:
+		return game.ErrGameOver
+	case player != game.X && player != game.O:
+		return ErrNoSeat
+	case sess.seats[player].AI && !origin.AI:
+		return ErrAISeat
+	case player != sess.state.CurrentPlayer:
+		return ErrNotYourTurn
+	}
+	return nil
+}
+
+// Resign ends a game with the resigning player's opponent as the winner
+func (s *GameService) Resign(id string, player game.Player, origin Origin) (*game.GameState, error) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if err := sess.checkSeat(player, origin); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if err := sess.state.Resign(player); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	event := s.publish(sess, Event{Type: EventResult, Player: player}, origin)
+	state := sess.state.Clone()
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return state, nil
+}
+
+// checkSeat checks that a game is still being played and player is a seat a
+// human may act for. The caller holds s.mu.
+func (sess *session) checkSeat(player game.Player, origin Origin) error {
+	switch {
+	case sess.abandoned:
+		return ErrAbandoned
+	case player != game.X && player != game.O:
+		return ErrNoSeat
+	case sess.seats[player].AI && !origin.AI:
+		return ErrAISeat
+	}
+	return nil
+}
+
+// Abandon stops a game without a result
+func (s *GameService) Abandon(id string, origin Origin) (*game.GameState, error) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if sess.state.GameOver || sess.abandoned {
+		s.mu.Unlock()
+		return nil, game.ErrGameOver
+	}
+	sess.abandoned = true
+	event := s.publish(sess, Event{Type: EventAbandon}, origin)
+	state := sess.state.Clone()
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return state, nil
+}
+
+// ReportAIError tells a game's subscribers that the AI playing player could
+// not move, so clients waiting for its move are not left waiting
+func (s *GameService) ReportAIError(id string, player game.Player, reason string, origin Origin) error {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	event := s.publish(sess, Event{Type: EventAIError, Player: player, Error: reason}, origin)
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return nil
+}
+
+// Delete removes a game at once, without archiving it
+func (s *GameService) Delete(id string, origin Origin) error {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	event := s.remove(sess, origin)
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return nil
+}
+
+// Discard removes a multiplayer game nothing has happened in yet: no move
+// has been played and no seat has been claimed except by WebSocket clients
+// for as long as they stay. It is called when the last client leaves, so
+// games opened under made-up IDs do not pile up until the reaper. It reports
+// whether the game was removed.
+func (s *GameService) Discard(id string, origin Origin) bool {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists || sess.mode != ModeMultiplayer || len(sess.state.MoveHistory) > 0 {
+		s.mu.Unlock()
+		return false
+	}
+	for _, seat := range sess.seats {
+		if seat.token != "" && !seat.transient {
+			s.mu.Unlock()
+			return false
+		}
+	}
+	event := s.remove(sess, origin)
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return true
+}
+
+// remove forgets a game, ending its subscriptions. The caller holds s.mu.
+func (s *GameService) remove(sess *session, origin Origin) Event {
+	event := s.publish(sess, Event{Type: EventClosed}, origin)
+	for sub := range sess.subscribers {
+		sub.close()
+	}
+	delete(s.sessions, sess.id)
+	return event
+}
+
+// Join seats a WebSocket client in a game. With a token the client takes
+// the token's seat, or watches the game with the observer token. Without one
+// it claims the first free seat until it leaves, or watches if there is
+// none. The seat is returned with its token.
+func (s *GameService) Join(id, client, token string) (game.Player, string, error) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return game.Empty, "", ErrNotFound
+	}
+
+	var player game.Player
+	if token != "" {
+		var err error
+		if player, err = sess.authorize(token); err != nil {
+			s.mu.Unlock()
+			return game.Empty, "", err
+		}
+	} else {
+		player, token = sess.claim("")
+		if player != game.Empty {
+			sess.seats[player].transient = true
+		}
+	}
+	if player != game.Empty {
+		seat := &sess.seats[player]
+		seat.Client = client
+		if seat.Name == "" {
+			seat.Name = client
+		}
+	}
+	event := s.publish(sess, Event{Type: EventJoin, Player: player}, Origin{Client: client})
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return player, token, nil
+}
+
+// Leave takes a WebSocket client out of its seat. A seat it claimed without
+// a token is freed for others.
+func (s *GameService) Leave(id, client string) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return
+	}
+	player := game.Empty
+	for _, p := range []game.Player{game.X, game.O} {
+		seat := &sess.seats[p]
+		if seat.Client != client {
+			continue
+		}
+		seat.Client = ""
+		if seat.transient {
+			seat.token, seat.transient = "", false
+		}
+		player = p
+	}
+	event := s.publish(sess, Event{Type: EventLeave, Player: player}, Origin{Client: client})
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+}
+
+// notify calls the listeners with events already published to subscribers
+func (s *GameService) notify(events []Event) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// generateID creates a random game ID
+func generateID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		// Fallback to timestamp if crypto/rand fails
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"t-9/internal/game"
+)
+
+var (
+	ErrNoToken  = errors.New("a seat token is required")
+	ErrBadToken = errors.New("token is not valid for this game")
+)
+
+// Tokens are the secrets handed out for a game. A seat token lets its holder
+// play that seat; the observer token is read-only and acts for no seat.
+// Games are public to read over REST. The observer token passes where a
+// token is checked, so a WebSocket client can join with it to watch without
+// taking a free seat, but it is refused for moves.
+type Tokens struct {
+	X        string `json:"x,omitempty"`
+	O        string `json:"o,omitempty"`
+	Observer string `json:"observer"`
+}
+
+// Authorize returns the seat a token is for, or Empty for the observer token
+func (s *GameService) Authorize(id, token string) (game.Player, error) {
+	if token == "" {
+		return game.Empty, ErrNoToken
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		return game.Empty, ErrNotFound
+	}
+	return sess.authorize(token)
+}
+
+// authorize looks a token up. The caller holds the service's mutex.
+func (sess *session) authorize(token string) (game.Player, error) {
+	for _, player := range []game.Player{game.X, game.O} {
+		if tokensMatch(sess.seats[player].token, token) {
+			return player, nil
+		}
+	}
+	if tokensMatch(sess.observerToken, token) {
+		return game.Empty, nil
+	}
+	return game.Empty, ErrBadToken
+}
+
+// Claim gives the first free seat of a game to a player joining over REST
+// and returns it with its token. Someone who finds no free seat gets the
+// observer token and Empty.
+func (s *GameService) Claim(id, name string, origin Origin) (game.Player, string, error) {
+	s.mu.Lock()
+	sess, exists := s.sessions[id]
+	if !exists {
+		s.mu.Unlock()
+		return game.Empty, "", ErrNotFound
+	}
+	player, token := sess.claim(name)
+	event := s.publish(sess, Event{Type: EventJoin, Player: player}, origin)
+	s.mu.Unlock()
+
+	s.notify([]Event{event})
+	return player, token, nil
+}
+
+// claim takes the first free seat, X first. A seat is free when no AI plays
+// it and no token has been issued for it. The caller holds the service's
+// mutex.
+func (sess *session) claim(name string) (game.Player, string) {
+	for _, player := range []game.Player{game.X, game.O} {
+		seat := &sess.seats[player]
+		if seat.AI || seat.token != "" {
+			continue
+		}
+		seat.token = newToken()
+		if name != "" {
+			seat.Name = name
+		}
+		return player, seat.token
+	}
+	return game.Empty, sess.observerToken
+}
+
+// tokensMatch compares a stored token with one presented by a client
+func tokensMatch(stored, presented string) bool {
+	return stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(presented)) == 1
+}
+
+// newToken creates a random secret token
+func newToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		// Fallback to timestamp if crypto/rand fails
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(bytes)
+}
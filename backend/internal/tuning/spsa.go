@@ -0,0 +1,129 @@
+package tuning
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"t-9/internal/ai"
+	"t-9/internal/arena"
+	"t-9/internal/game"
+)
+
+// SPSAConfig controls an SPSA tuning run. Weights are perturbed in their own
+// units, by default their starting values, so A and C are fractions of each
+// weight's unit.
+type SPSAConfig struct {
+	Iterations  int
+	Games       int     // Games per iteration, played in colour-swapped pairs
+	A           float64 // Step size of the first iteration
+	C           float64 // Perturbation size of the first iteration
+	Strength    ai.Strength
+	Concurrency int
+	Seed        int64
+
+	// OnIteration is called after every iteration with the score of the
+	// positive perturbation against the negative one
+	OnIteration func(iteration int, score float64, weights ai.Weights)
+}
+
+// Standard SPSA gain sequence exponents
+const (
+	spsaAlpha = 0.602
+	spsaGamma = 0.101
+)
+
+// SPSA tunes weights by simultaneous perturbation stochastic approximation.
+// Every iteration perturbs all weights at once in random directions, plays
+// the two perturbed sets against each other and moves the weights towards
+// the one that scored better.
+func SPSA(ctx context.Context, weights ai.Weights, config SPSAConfig) (ai.Weights, error) {
+	rng := rand.New(rand.NewSource(config.Seed))
+	origin := weights.Vector()
+	ranges := weights.Ranges()
+
+	// Position relative to the starting weights in units of each weight, 0
+	// meaning unchanged
+	theta := make([]float64, len(origin))
+
+	// Offset of the step size sequence, a tenth of the run as usual
+	offset := float64(config.Iterations) / 10
+
+	for k := 0; k < config.Iterations; k++ {
+		a := config.A / math.Pow(float64(k+1)+offset, spsaAlpha)
+		c := config.C / math.Pow(float64(k+1), spsaGamma)
+
+		delta := make([]float64, len(theta))
+		plus := make([]float64, len(theta))
+		minus := make([]float64, len(theta))
+		for i := range theta {
+			delta[i] = 1
+			if rng.Intn(2) == 0 {
+				delta[i] = -1
+			}
+			plus[i] = ranges[i].Clamp(origin[i] + ranges[i].Unit*(theta[i]+c*delta[i]))
+			minus[i] = ranges[i].Clamp(origin[i] + ranges[i].Unit*(theta[i]-c*delta[i]))
+		}
+
+		plusWeights, minusWeights := weights, weights
+		plusWeights.SetVector(plus)
+		minusWeights.SetVector(minus)
+
+		result, err := arena.Run(ctx, arena.Config{
+			Engine1:     weightedEngine("plus", config.Strength, plusWeights),
+			Engine2:     weightedEngine("minus", config.Strength, minusWeights),
+			Games:       config.Games,
+			Openings:    arena.DefaultOpenings(),
+			Concurrency: config.Concurrency,
+			Seed:        config.Seed + int64(k)*int64(config.Games)*2,
+		})
+		if err != nil {
+			return weights, err
+		}
+
+		// Score of plus against minus, from -1 to 1
+		score := 2*result.Score() - 1
+		for i := range theta {
+			theta[i] = clampPosition(theta[i]+a*score/(2*c*delta[i]), origin[i], ranges[i])
+		}
+
+		current := weights
+		current.SetVector(positionWeights(origin, ranges, theta))
+		if config.OnIteration != nil {
+			config.OnIteration(k+1, score, current)
+		}
+	}
+
+	weights.SetVector(positionWeights(origin, ranges, theta))
+	return weights, nil
+}
+
+// weightedEngine plays at a strength level with the given weights
+func weightedEngine(name string, strength ai.Strength, weights ai.Weights) arena.EngineSpec {
+	return arena.EngineSpec{
+		Name: fmt.Sprintf("%s level %d", name, strength.Level),
+		New: func(player game.Player, seed int64) (ai.Engine, error) {
+			aiPlayer := ai.NewSeededLeveledAIPlayer(strength, player, seed)
+			aiPlayer.SetWeights(weights)
+			return aiPlayer, nil
+		},
+	}
+}
+
+// positionWeights converts positions in units back into weights
+func positionWeights(origin []float64, ranges []ai.WeightRange, theta []float64) []float64 {
+	values := make([]float64, len(origin))
+	for i := range origin {
+		values[i] = ranges[i].Clamp(origin[i] + ranges[i].Unit*theta[i])
+	}
+	return values
+}
+
+// clampPosition limits a position in units to where its weight stays in
+// range, so a weight held at a bound by the range does not drift past it
+func clampPosition(position, origin float64, r ai.WeightRange) float64 {
+	if r.Unit == 0 {
+		return 0
+	}
+	return math.Max((r.Min-origin)/r.Unit, math.Min((r.Max-origin)/r.Unit, position))
+}
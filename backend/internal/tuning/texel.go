@@ -0,0 +1,206 @@
+// Package tuning fits the handwritten evaluation weights to game results,
+// either with Texel's method on recorded positions or with SPSA on games
+// played between perturbed weight sets.
+package tuning
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"t-9/internal/ai"
+	"t-9/internal/game"
+)
+
+// Position is a recorded position with the final result of its game from
+// X's point of view: 1 for a win, 0.5 for a draw and 0 for a loss
+type Position struct {
+	State  *game.GameState
+	Result float64
+}
+
+// TexelConfig controls a Texel tuning run
+type TexelConfig struct {
+	Iterations int     // Passes over every weight
+	Step       float64 // Change tried for each weight relative to its unit, e.g. 0.1
+	MinStep    float64 // The run stops once the step has halved below this
+
+	// OnIteration is called after every pass with the error reached
+	OnIteration func(iteration int, err float64, weights ai.Weights)
+}
+
+// PositionsFromRecords collects the unfinished positions of finished games,
+// skipping the first skipPlies of each game since openings say little about
+// the result
+func PositionsFromRecords(records []*game.Record, skipPlies int) ([]Position, error) {
+	var positions []Position
+	for _, record := range records {
+		final, err := record.Replay()
+		if err != nil {
+			return nil, err
+		}
+		if !final.GameOver {
+			continue
+		}
+
+		result := 0.5
+		switch final.GameWon {
+		case game.X:
+			result = 1
+		case game.O:
+			result = 0
+		}
+
+		state := game.NewGame()
+		for ply, move := range record.Moves {
+			if ply >= skipPlies {
+				positions = append(positions, Position{State: state.Clone(), Result: result})
+			}
+			if err := state.MakeMove(move); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return positions, nil
+}
+
+// MeanSquaredError is the Texel error: the mean squared difference between
+// game results and the win probability predicted from the evaluation
+func MeanSquaredError(positions []Position, weights ai.Weights, scale float64) float64 {
+	if len(positions) == 0 {
+		return 0
+	}
+
+	workers := runtime.NumCPU()
+	chunk := (len(positions) + workers - 1) / workers
+	sums := make([]float64, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunk
+		end := start + chunk
+		if end > len(positions) {
+			end = len(positions)
+		}
+		if start >= end {
+			break
+		}
+
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for _, position := range positions[start:end] {
+				score := ai.EvaluateWithWeights(position.State, game.X, weights)
+				diff := position.Result - sigmoid(score, scale)
+				sums[w] += diff * diff
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	total := 0.0
+	for _, sum := range sums {
+		total += sum
+	}
+	return total / float64(len(positions))
+}
+
+// FitScale finds the score scale that best maps evaluations of positions to
+// results, so the weights are tuned against a fixed probability curve
+func FitScale(positions []Position, weights ai.Weights) float64 {
+	// Golden section search over the logarithm of the scale
+	low, high := math.Log(10), math.Log(100000)
+	ratio := (math.Sqrt(5) - 1) / 2
+	errorAt := func(logScale float64) float64 {
+		return MeanSquaredError(positions, weights, math.Exp(logScale))
+	}
+
+	a := high - ratio*(high-low)
+	b := low + ratio*(high-low)
+	errA, errB := errorAt(a), errorAt(b)
+	for i := 0; i < 40; i++ {
+		if errA < errB {
+			high, b, errB = b, a, errA
+			a = high - ratio*(high-low)
+			errA = errorAt(a)
+		} else {
+			low, a, errA = a, b, errB
+			b = low + ratio*(high-low)
+			errB = errorAt(b)
+		}
+	}
+	return math.Exp((low + high) / 2)
+}
+
+// Texel tunes weights with Texel's local search: every weight is nudged up
+// and down in turn and the change is kept when it lowers the error. When a
+// whole pass finds nothing the step is halved. Weights stay within their
+// ranges; those with no unit, or that do not affect the evaluation of any
+// position, are left alone.
+func Texel(positions []Position, weights ai.Weights, scale float64, config TexelConfig) (ai.Weights, float64) {
+	values := weights.Vector()
+	ranges := weights.Ranges()
+	errorOf := func(values []float64) float64 {
+		candidate := weights
+		candidate.SetVector(values)
+		return MeanSquaredError(positions, candidate, scale)
+	}
+
+	best := errorOf(values)
+	active := make([]bool, len(values))
+	for i := range active {
+		active[i] = ranges[i].Unit > 0
+	}
+
+	step := config.Step
+	for iteration := 1; iteration <= config.Iterations && step >= config.MinStep; iteration++ {
+		improved := false
+		for i := range values {
+			if !active[i] {
+				continue
+			}
+
+			original := values[i]
+			delta := ranges[i].Unit * step
+
+			unchanged := true
+			for _, candidate := range []float64{original + delta, original - delta} {
+				if candidate != ranges[i].Clamp(candidate) {
+					continue
+				}
+				values[i] = candidate
+				err := errorOf(values)
+				if err != best {
+					unchanged = false
+				}
+				if err < best {
+					best = err
+					original = candidate
+					improved = true
+					break
+				}
+			}
+			values[i] = original
+
+			if unchanged && iteration == 1 {
+				active[i] = false
+			}
+		}
+
+		if !improved {
+			step /= 2
+		}
+
+		weights.SetVector(values)
+		if config.OnIteration != nil {
+			config.OnIteration(iteration, best, weights)
+		}
+	}
+
+	weights.SetVector(values)
+	return weights, best
+}
+
+// sigmoid converts a score into an expected result
+func sigmoid(score, scale float64) float64 {
+	return 1 / (1 + math.Exp(-score/scale))
+}
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"t-9/internal/config"
 	"t-9/internal/game"
+	"t-9/internal/scheduler"
+	"t-9/internal/service"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +20,7 @@ import (
 
 const (
 	maxRooms         = 100               // Maximum concurrent game rooms
-	roomTimeout      = 30 * time.Minute  // Delete inactive rooms after 30 minutes
+	roomTimeout      = 30 * time.Minute  // Disconnect inactive rooms after 30 minutes
 	cleanupInterval  = 5 * time.Minute   // Run cleanup every 5 minutes
 )
 
@@ -86,16 +88,19 @@ func getEnv(key, defaultValue string) string {
 
 // Hub manages all game rooms and WebSocket connections
 type Hub struct {
+	games      *service.GameService
 	rooms      map[string]*GameRoom
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan []byte
+	jobs       *scheduler.Scheduler
 	mu         sync.RWMutex
 }
 
-// NewHub creates a new WebSocket hub
-func NewHub() *Hub {
+// NewHub creates a new WebSocket hub for the games of a game service
+func NewHub(games *service.GameService) *Hub {
 	return &Hub{
+		games:      games,
 		rooms:      make(map[string]*GameRoom),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
@@ -103,6 +108,11 @@ func NewHub() *Hub {
 	}
 }
 
+// SetJobs lets clients watch jobs of the AI scheduler
+func (h *Hub) SetJobs(jobs *scheduler.Scheduler) {
+	h.jobs = jobs
+}
+
 // Run starts the hub's main loop
 func (h *Hub) Run() {
 	// Start cleanup goroutine
@@ -159,36 +169,38 @@ func (h *Hub) handleUnregister(client *Client) {
 		if room, exists := h.rooms[client.GameID]; exists {
 			h.removeClientFromRoom(room, client)
 		}
+		h.games.Leave(client.GameID, client.ID)
 	}
 
-	close(client.Send)
+	if !client.closed {
+		close(client.Send)
+		client.closed = true
+	}
 	client.Conn.Close()
 	log.Printf("Client %s disconnected", client.ID)
 }
 
-// removeClientFromRoom removes a client from a game room
+// removeClientFromRoom removes a client from a game room. The other clients
+// hear of it through the game's leave event.
 func (h *Hub) removeClientFromRoom(room *GameRoom, client *Client) {
 	delete(room.Clients, client.ID)
 
-	if room.PlayerX == client {
-		room.PlayerX = nil
-	}
-	if room.PlayerO == client {
-		room.PlayerO = nil
+	// Remove the room if empty. A game that was played stays with the game
+	// service until it is archived.
+	if len(room.Clients) == 0 {
+		h.closeRoom(room)
+		log.Printf("Room %s closed (empty)", room.ID)
 	}
+}
 
-	// Notify other clients
-	msg := Message{
-		Type:    MsgTypePlayerLeave,
-		Player:  client.Player,
-		Message: "Player disconnected",
-	}
-	h.broadcastToRoom(room, msg)
-
-	// Remove room if empty
-	if len(room.Clients) == 0 {
-		delete(h.rooms, room.ID)
-		log.Printf("Room %s deleted (empty)", room.ID)
+// closeRoom stops forwarding a room's events and forgets it, along with its
+// game if nothing happened in it. Otherwise rooms opened and left under new
+// IDs would leave games behind without bound. The caller holds h.mu.
+func (h *Hub) closeRoom(room *GameRoom) {
+	room.events.Close()
+	delete(h.rooms, room.ID)
+	if h.games.Discard(room.ID, service.Origin{}) {
+		log.Printf("Game %s discarded (never played)", room.ID)
 	}
 }
 
@@ -233,19 +245,67 @@ func (h *Hub) writePump(client *Client) {
 func (h *Hub) handleMessage(client *Client, msg Message) {
 	switch msg.Type {
 	case MsgTypeJoinGame:
-		h.handleJoinGame(client, msg.GameID)
+		h.handleJoinGame(client, msg.GameID, msg.Token)
 	case MsgTypeMove:
 		if msg.Move != nil {
 			h.handleMove(client, *msg.Move)
 		}
+	case MsgTypeWatchJob:
+		h.handleWatchJob(client, msg.JobID)
 	}
 }
 
-// handleJoinGame handles a client joining a game
-func (h *Hub) handleJoinGame(client *Client, gameID string) {
+// handleWatchJob sends a client the result of an AI job once it is done
+func (h *Hub) handleWatchJob(client *Client, jobID string) {
+	if h.jobs == nil {
+		h.sendError(client, "Jobs are not available")
+		return
+	}
+	job, exists := h.jobs.Job(jobID)
+	if !exists {
+		h.sendError(client, "Job not found")
+		return
+	}
+
+	go func() {
+		<-job.Done()
+		data, err := json.Marshal(Message{
+			Type:  MsgTypeJobResult,
+			JobID: job.ID(),
+			Job:   job.View(),
+		})
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			return
+		}
+
+		// The client may have gone while the job ran
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if client.closed {
+			return
+		}
+		select {
+		case client.Send <- data:
+		default:
+			log.Printf("Dropped job result for slow client %s", client.ID)
+		}
+	}()
+}
+
+// handleJoinGame handles a client joining a game. A game that does not
+// exist yet is created. A client with a seat token takes its seat and one
+// with the observer token watches; others take a free seat, or watch the game
+// if there is none.
+func (h *Hub) handleJoinGame(client *Client, gameID, token string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if client.GameID != "" {
+		h.sendError(client, "Already in a game")
+		return
+	}
+
 	room, exists := h.rooms[gameID]
 	if !exists {
 		// Check if we've hit max rooms limit
@@ -255,85 +315,132 @@ func (h *Hub) handleJoinGame(client *Client, gameID string) {
 			return
 		}
 
+		if _, err := h.games.Open(gameID, service.ModeMultiplayer); err != nil {
+			h.sendError(client, err.Error())
+			return
+		}
+		events, err := h.games.Subscribe(gameID)
+		if err != nil {
+			h.sendError(client, err.Error())
+			return
+		}
+
 		// Create new room
 		room = &GameRoom{
 			ID:           gameID,
-			Game:         game.NewGame(),
 			Clients:      make(map[string]*Client),
+			events:       events,
 			LastActivity: time.Now(),
 		}
 		h.rooms[gameID] = room
+		go h.forwardEvents(room)
 	}
 
 	// Update activity timestamp
 	room.LastActivity = time.Now()
 
-	// Check if room is full
-	if len(room.Clients) >= 2 {
-		h.sendError(client, "Game room is full")
+	// Assign player. The other players hear of it through the join event.
+	player, token, err := h.games.Join(gameID, client.ID, token)
+	if err != nil {
+		h.sendError(client, err.Error())
+		if len(room.Clients) == 0 {
+			h.closeRoom(room)
+		}
 		return
 	}
-
-	// Assign player
-	if room.PlayerX == nil {
-		client.Player = game.X
-		room.PlayerX = client
-	} else if room.PlayerO == nil {
-		client.Player = game.O
-		room.PlayerO = client
-	}
-
+	client.Player = player
 	client.GameID = gameID
 	room.Clients[client.ID] = client
 
-	// Send game state to joining player
-	h.sendGameState(client, room.Game)
-
-	// Notify other players
-	msg := Message{
-		Type:    MsgTypePlayerJoin,
-		Player:  client.Player,
-		Message: "Player joined",
+	// Send game state to joining player, with the seat and its token
+	gameState, _, err := h.games.Get(gameID)
+	if err != nil {
+		h.sendError(client, err.Error())
+		return
 	}
-	h.broadcastToRoom(room, msg)
+	h.sendToClient(client, Message{
+		Type:   MsgTypeGameState,
+		Game:   gameState,
+		Player: player,
+		Token:  token,
+	})
 
 	log.Printf("Client %s joined game %s as player %v", client.ID, gameID, client.Player)
 }
 
 // handleMove handles a game move
 func (h *Hub) handleMove(client *Client, move game.Move) {
-	h.mu.RLock()
-	room, exists := h.rooms[client.GameID]
-	h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	if !exists {
+	if client.GameID == "" {
 		h.sendError(client, "Game not found")
 		return
 	}
-
-	// Validate it's the player's turn
-	if room.Game.CurrentPlayer != client.Player {
-		h.sendError(client, "Not your turn")
+	if client.Player == game.Empty {
+		h.sendError(client, "You are watching this game")
 		return
 	}
 
-	// Make the move
-	if err := room.Game.MakeMove(move); err != nil {
+	// Make the move. Players can only move for their own seat, and the
+	// updated game reaches the room through the move event.
+	move.Player = client.Player
+	if _, err := h.games.Move(client.GameID, move, service.Origin{Client: client.ID}); err != nil {
 		h.sendError(client, err.Error())
-		return
 	}
+}
 
-	// Update room activity
-	h.mu.Lock()
-	room.LastActivity = time.Now()
-	h.mu.Unlock()
+// forwardEvents sends the events of a room's game to its clients until the
+// room is closed
+func (h *Hub) forwardEvents(room *GameRoom) {
+	for event := range room.events.Events() {
+		h.mu.Lock()
+		room.LastActivity = time.Now()
+
+		switch event.Type {
+		case service.EventMove, service.EventResult, service.EventAbandon:
+			h.broadcastToRoom(room, Message{
+				Type: MsgTypeGameState,
+				Game: event.Game,
+			})
+		case service.EventJoin:
+			h.broadcastToRoom(room, Message{
+				Type:    MsgTypePlayerJoin,
+				Player:  event.Player,
+				Message: "Player joined",
+			})
+		case service.EventLeave:
+			h.broadcastToRoom(room, Message{
+				Type:    MsgTypePlayerLeave,
+				Player:  event.Player,
+				Message: "Player disconnected",
+			})
+		case service.EventAIError:
+			h.broadcastToRoom(room, Message{
+				Type:   MsgTypeError,
+				Player: event.Player,
+				Error:  event.Error,
+			})
+		case service.EventClosed:
+			for _, client := range room.Clients {
+				h.sendError(client, "Game closed")
+			}
+		}
+		h.mu.Unlock()
+	}
 
-	// Broadcast updated game state to all players in room
-	msg := Message{
-		Type: MsgTypeGameState,
-		Game: room.Game,
+	// The subscription ends when the room is closed, the game is closed or
+	// the room fell too far behind. Clients of a room that is still open
+	// are disconnected so they can rejoin afresh.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room.ID] != room {
+		return
 	}
-	h.broadcastToRoom(room, msg)
+	for _, client := range room.Clients {
+		client.Conn.Close()
+	}
+	delete(h.rooms, room.ID)
 }
 
 // sendGameState sends current game state to a client
@@ -366,7 +473,10 @@ func (h *Hub) sendToClient(client *Client, msg Message) {
 	case client.Send <- data:
 	default:
 		close(client.Send)
-		delete(h.rooms[client.GameID].Clients, client.ID)
+		client.closed = true
+		if room, exists := h.rooms[client.GameID]; exists {
+			delete(room.Clients, client.ID)
+		}
 	}
 }
 
@@ -383,6 +493,7 @@ func (h *Hub) broadcastToRoom(room *GameRoom, msg Message) {
 		case client.Send <- data:
 		default:
 			close(client.Send)
+			client.closed = true
 			delete(room.Clients, client.ID)
 		}
 	}
@@ -426,7 +537,7 @@ func (h *Hub) cleanupInactiveRooms() {
 				client.Conn.Close()
 			}
 
-			delete(h.rooms, gameID)
+			h.closeRoom(room)
 			deletedCount++
 			log.Printf("Cleaned up inactive room: %s (inactive for %v)", gameID, now.Sub(room.LastActivity))
 		}
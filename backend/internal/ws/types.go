@@ -2,6 +2,7 @@ package ws
 
 import (
 	"t-9/internal/game"
+	"t-9/internal/service"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,15 +15,15 @@ type Client struct {
 	GameID   string
 	Player   game.Player
 	Send     chan []byte
+	closed   bool // Send has been closed, guarded by the hub's mutex
 }
 
-// GameRoom manages a multiplayer game session
+// GameRoom is the set of clients connected to one game. The game itself is
+// held by the game service; the room forwards its events to the clients.
 type GameRoom struct {
 	ID           string
-	Game         *game.GameState
 	Clients      map[string]*Client
-	PlayerX      *Client
-	PlayerO      *Client
+	events       *service.Subscription
 	LastActivity time.Time
 }
 
@@ -36,6 +37,8 @@ const (
 	MsgTypePlayerJoin  MessageType = "player_join"
 	MsgTypePlayerLeave MessageType = "player_leave"
 	MsgTypeError       MessageType = "error"
+	MsgTypeWatchJob    MessageType = "watch_job"
+	MsgTypeJobResult   MessageType = "job_result"
 )
 
 // WebSocket message structure
@@ -47,6 +50,9 @@ type Message struct {
 	Player  game.Player `json:"player,omitempty"`
 	Error   string      `json:"error,omitempty"`
 	Message string      `json:"message,omitempty"`
+	Token   string      `json:"token,omitempty"` // Seat token, sent to a joining client and given to rejoin a seat
+	JobID   string      `json:"jobId,omitempty"`
+	Job     interface{} `json:"job,omitempty"`
 }
 
 // JoinGameRequest represents a request to join a game
@@ -1,4 +1,5 @@
-import type { GameState, Move, GameResponse } from './types';
+import { Player } from './types';
+import type { GameState, Move, GameResponse, SeatTokens } from './types';
 import { safeFetch, APIError, ErrorFactory, safeAsync } from './error';
 
 const API_BASE = import.meta.env.VITE_API_BASE || 'http://localhost:8080/api/v1';
@@ -8,6 +9,16 @@ export interface AIResponse {
   move: Move;
 }
 
+// Seat tokens of the games this client created, by game ID
+const seatTokens = new Map<string, SeatTokens>();
+
+// seatHeaders returns the Authorization header for acting as player in a game
+function seatHeaders(gameId: string, player: Player): Record<string, string> {
+  const tokens = seatTokens.get(gameId);
+  const token = player === Player.O ? tokens?.o : tokens?.x;
+  return token ? { Authorization: `Bearer ${token}` } : {};
+}
+
 export class GameAPI {
   static async createGame(): Promise<GameResponse> {
     const result = await safeAsync(async () => {
@@ -25,6 +36,10 @@ export class GameAPI {
       throw ErrorFactory.network('Failed to create game');
     }
 
+    if (result.tokens) {
+      seatTokens.set(result.gameId, result.tokens);
+    }
+
     return result;
   }
 
@@ -51,6 +66,7 @@ export class GameAPI {
           method: 'POST',
           headers: {
             'Content-Type': 'application/json',
+            ...seatHeaders(gameId, move.player),
           },
           body: JSON.stringify(move),
           signal: controller.signal,
@@ -74,12 +90,14 @@ export class GameAPI {
     return result;
   }
 
-  static async makeAIMove(gameId: string, difficulty: 'easy' | 'medium' | 'hard'): Promise<AIResponse> {
+  // player is the side the AI moves for, whose seat token is sent
+  static async makeAIMove(gameId: string, difficulty: 'easy' | 'medium' | 'hard', player: Player = Player.X): Promise<AIResponse> {
     const result = await safeAsync(async () => {
       const response = await safeFetch(`${API_BASE}/games/${gameId}/ai-move`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          ...seatHeaders(gameId, player),
         },
         body: JSON.stringify({ difficulty }),
       });
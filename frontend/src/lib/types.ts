@@ -15,6 +15,7 @@ export interface GameState {
   currentPlayer: Player;
   gameWon: Player;
   gameOver: boolean;
+  moveHistory?: Move[];
 }
 
 export interface Move {
@@ -23,7 +24,16 @@ export interface Move {
   player: Player;
 }
 
+// Seat tokens issued when a game is created, sent as a Bearer token to act
+// for a seat. The observer token may only read the game.
+export interface SeatTokens {
+  x?: string;
+  o?: string;
+  observer: string;
+}
+
 export interface GameResponse {
   gameId: string;
   game: GameState;
+  tokens: SeatTokens;
 }
\ No newline at end of file